@@ -17,6 +17,8 @@ func main() {
 		Commands: []*urfavecli.Command{
 			cli.InstallCommand,
 			cli.UninstallCommand,
+			cli.UpgradeCommand,
+			cli.PreflightCommand,
 			cli.RegistryCommand,
 			cli.VersionCommand,
 			cli.ConfigCommand,
@@ -24,6 +26,7 @@ func main() {
 			cli.ExportWorkerArtifactsCommand,
 			cli.ExportJoinConfigCommand,
 			cli.ShowFlavorCommand,
+			cli.RenderCommand,
 		},
 		Flags: []urfavecli.Flag{
 			&urfavecli.StringFlag{
@@ -47,6 +50,12 @@ func main() {
 				Usage:   "Show what would be done without making changes",
 				EnvVars: []string{"K0RDENTD_DRY_RUN"},
 			},
+			&urfavecli.BoolFlag{
+				Name:    "lenient",
+				Value:   false,
+				Usage:   "Ignore unknown fields in the configuration file instead of failing",
+				EnvVars: []string{"K0RDENTD_LENIENT"},
+			},
 		},
 	}
 
@@ -3,8 +3,9 @@ package containerd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+
+	"github.com/belgaied2/k0rdentd/pkg/system"
 )
 
 const (
@@ -18,6 +19,23 @@ const (
 	CRIRegistryConfigPath = "/etc/k0s/containerd.d/cri-registry.toml"
 )
 
+// MirroredRegistries are the upstream registries served by the local registry
+var MirroredRegistries = []string{"registry.k8s.io", "quay.io"}
+
+// HostsConfigPath returns the path of the hosts.toml of a given registry
+func HostsConfigPath(registry string) string {
+	return filepath.Join(CertsDir, registry, "hosts.toml")
+}
+
+// MirrorConfigPaths returns the files written by SetupContainerdMirror
+func MirrorConfigPaths() []string {
+	paths := []string{CRIRegistryConfigPath}
+	for _, registry := range MirroredRegistries {
+		paths = append(paths, HostsConfigPath(registry))
+	}
+	return paths
+}
+
 // CRIRegistryConfig returns the content of the CRI registry config
 func CRIRegistryConfig() string {
 	return `version = 2
@@ -39,28 +57,26 @@ func HostsConfig(registry, mirrorAddr string) string {
 }
 
 // SetupContainerdMirror configures containerd to use the local registry as a mirror
-func SetupContainerdMirror(mirrorAddr string) error {
+func SetupContainerdMirror(host system.Host, mirrorAddr string) error {
 	// Create directories
-	if err := os.MkdirAll(CertsDir, 0755); err != nil {
+	if err := host.MkdirAll(CertsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create certs.d directory: %w", err)
 	}
 
 	// Write CRI registry config
-	if err := os.WriteFile(CRIRegistryConfigPath, []byte(CRIRegistryConfig()), 0644); err != nil {
+	if err := host.WriteFile(CRIRegistryConfigPath, []byte(CRIRegistryConfig()), 0644); err != nil {
 		return fmt.Errorf("failed to write CRI registry config: %w", err)
 	}
 
 	// Configure mirrors for known registries
-	registries := []string{"registry.k8s.io", "quay.io"}
-	for _, registry := range registries {
-		regDir := filepath.Join(CertsDir, registry)
-		if err := os.MkdirAll(regDir, 0755); err != nil {
+	for _, registry := range MirroredRegistries {
+		hostsPath := HostsConfigPath(registry)
+		if err := host.MkdirAll(filepath.Dir(hostsPath), 0755); err != nil {
 			return fmt.Errorf("failed to create registry dir for %s: %w", registry, err)
 		}
 
-		hostsPath := filepath.Join(regDir, "hosts.toml")
 		hostsContent := HostsConfig(registry, mirrorAddr)
-		if err := os.WriteFile(hostsPath, []byte(hostsContent), 0644); err != nil {
+		if err := host.WriteFile(hostsPath, []byte(hostsContent), 0644); err != nil {
 			return fmt.Errorf("failed to write hosts.toml for %s: %w", registry, err)
 		}
 	}
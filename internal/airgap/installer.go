@@ -2,10 +2,11 @@ package airgap
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/fs"
-	"os"
 	"path/filepath"
 
 	"github.com/belgaied2/k0rdentd/internal/airgap/assets"
@@ -13,6 +14,7 @@ import (
 	"github.com/belgaied2/k0rdentd/internal/airgap/containerd"
 	"github.com/belgaied2/k0rdentd/pkg/config"
 	"github.com/belgaied2/k0rdentd/pkg/generator"
+	"github.com/belgaied2/k0rdentd/pkg/system"
 	"github.com/belgaied2/k0rdentd/pkg/utils"
 )
 
@@ -20,6 +22,7 @@ import (
 type Installer struct {
 	config *config.K0rdentdConfig
 	debug  bool
+	host   system.Host
 }
 
 // NewInstaller creates a new airgap installer
@@ -27,23 +30,42 @@ func NewInstaller(cfg *config.K0rdentdConfig, debug bool) *Installer {
 	return &Installer{
 		config: cfg,
 		debug:  debug,
+		host:   system.NewHost(debug),
 	}
 }
 
-// ExtractK0sBinary extracts the embedded k0s binary to /usr/local/bin/k0s
-func (i *Installer) ExtractK0sBinary() error {
+// SetHost sets the host the installer changes, e.g. a dry-run plan
+func (i *Installer) SetHost(host system.Host) {
+	i.host = host
+}
+
+// logChange logs that a change of the host succeeded. In dry-run mode the
+// change is only planned, the plan reports it instead.
+func (i *Installer) logChange(format string, args ...interface{}) {
+	if system.IsDryRun(i.host) {
+		return
+	}
+	utils.GetLogger().Infof(format, args...)
+}
+
+// K0sBinaryURLPath is the URL path the registry daemon serves the embedded
+// k0s binary under, for k0s autopilot upgrades of the other nodes
+const K0sBinaryURLPath = "/k0s/"
+
+// OpenK0sBinary opens the embedded k0s binary, returning its file name
+func OpenK0sBinary() (string, fs.File, error) {
 	if !IsAirGap() {
-		return fmt.Errorf("not an airgap build, cannot extract embedded k0s binary")
+		return "", nil, fmt.Errorf("not an airgap build, cannot extract embedded k0s binary")
 	}
 
 	// Read the k0s directory from embedded FS
 	entries, err := fs.ReadDir(assets.K0sBinary, "k0s")
 	if err != nil {
-		return fmt.Errorf("failed to read embedded k0s directory: %w", err)
+		return "", nil, fmt.Errorf("failed to read embedded k0s directory: %w", err)
 	}
 
 	if len(entries) == 0 {
-		return fmt.Errorf("no k0s binary found in embedded assets")
+		return "", nil, fmt.Errorf("no k0s binary found in embedded assets")
 	}
 
 	// Find the k0s binary (should be only one file)
@@ -56,33 +78,50 @@ func (i *Installer) ExtractK0sBinary() error {
 	}
 
 	if k0sBinaryName == "" {
-		return fmt.Errorf("no k0s binary file found in embedded assets")
+		return "", nil, fmt.Errorf("no k0s binary file found in embedded assets")
 	}
 
 	// Open the embedded k0s binary
-	srcPath := filepath.Join("k0s", k0sBinaryName)
-	srcFile, err := assets.K0sBinary.Open(srcPath)
+	srcFile, err := assets.K0sBinary.Open(filepath.Join("k0s", k0sBinaryName))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open embedded k0s binary: %w", err)
+	}
+	return k0sBinaryName, srcFile, nil
+}
+
+// K0sBinarySHA256 returns the file name and the sha256 checksum of the
+// embedded k0s binary
+func K0sBinarySHA256() (string, string, error) {
+	name, file, err := OpenK0sBinary()
 	if err != nil {
-		return fmt.Errorf("failed to open embedded k0s binary: %w", err)
+		return "", "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", "", fmt.Errorf("failed to read embedded k0s binary: %w", err)
+	}
+	return name, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ExtractK0sBinary extracts the embedded k0s binary to /usr/local/bin/k0s
+func (i *Installer) ExtractK0sBinary() error {
+	k0sBinaryName, srcFile, err := OpenK0sBinary()
+	if err != nil {
+		return err
 	}
 	defer srcFile.Close()
 
 	// Ensure /usr/local/bin exists
-	if err := os.MkdirAll("/usr/local/bin", 0755); err != nil {
+	if err := i.host.MkdirAll("/usr/local/bin", 0755); err != nil {
 		return fmt.Errorf("failed to create /usr/local/bin directory: %w", err)
 	}
 
-	// Create destination file at /usr/local/bin/k0s
+	// Copy the binary to /usr/local/bin/k0s
 	dstPath := "/usr/local/bin/k0s"
-	dstFile, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create k0s binary at %s: %w", dstPath, err)
-	}
-	defer dstFile.Close()
-
-	// Copy the binary
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		return fmt.Errorf("failed to copy k0s binary: %w", err)
+	if err := i.host.CopyFile(dstPath, srcFile, 0755); err != nil {
+		return fmt.Errorf("failed to copy k0s binary to %s: %w", dstPath, err)
 	}
 
 	if i.debug {
@@ -111,7 +150,8 @@ func (i *Installer) IsRegistryInsecure() bool {
 	// Default to true for localhost registries
 	if i.config.Airgap.Registry.Address == "" ||
 		i.config.Airgap.Registry.Address == "localhost:5000" ||
-		i.config.Airgap.Registry.Address == "127.0.0.1:5000" {
+		i.config.Airgap.Registry.Address == "127.0.0.1:5000" ||
+		i.config.Airgap.Registry.Address == "[::1]:5000" {
 		return true
 	}
 	return i.config.Airgap.Registry.Insecure
@@ -132,15 +172,15 @@ func (i *Installer) PrepareAirgap() (string, error) {
 	if err := i.ExtractK0sBinary(); err != nil {
 		return "", fmt.Errorf("failed to extract k0s binary: %w", err)
 	}
-	logger.Info("✅ K0s binary extracted to /usr/local/bin/k0s")
+	i.logChange("✅ K0s binary extracted to /usr/local/bin/k0s")
 
 	// Configure containerd mirrors
 	registryAddr := i.GetRegistryAddress()
 	logger.Info("Configuring containerd registry mirrors...")
-	if err := containerd.SetupContainerdMirror(registryAddr); err != nil {
+	if err := containerd.SetupContainerdMirror(i.host, registryAddr); err != nil {
 		return "", fmt.Errorf("failed to configure containerd mirrors: %w", err)
 	}
-	logger.Infof("✅ Containerd registry mirrors configured (registry: %s)", registryAddr)
+	i.logChange("✅ Containerd registry mirrors configured (registry: %s)", registryAddr)
 
 	return registryAddr, nil
 }
@@ -176,7 +216,7 @@ func (i *Installer) Install(ctx context.Context) error {
 	if err := i.ExtractK0sBinary(); err != nil {
 		return fmt.Errorf("failed to extract k0s binary: %w", err)
 	}
-	logger.Info("✅ K0s binary extracted to /usr/local/bin/k0s")
+	i.logChange("✅ K0s binary extracted to /usr/local/bin/k0s")
 
 	// Step 3: Generate k0s configuration for airgap mode
 	logger.Info("Generating k0s configuration for airgap mode...")
@@ -189,25 +229,29 @@ func (i *Installer) Install(ctx context.Context) error {
 	}
 
 	if i.debug {
-		logger.Debugf("Generated k0s config:\n%s", string(k0sConfigBytes))
+		redacted, err := generator.RedactK0sConfig(k0sConfigBytes)
+		if err != nil {
+			return fmt.Errorf("failed to redact airgap k0s config: %w", err)
+		}
+		logger.Debugf("Generated k0s config:\n%s", string(redacted))
 	}
 	logger.Infof("✅ K0s configuration generated (registry: %s, insecure: %t)", registryAddr, insecure)
 
 	// Step 4: Configure containerd registry mirror
 	logger.Info("Configuring containerd registry mirror...")
-	if err := containerd.SetupContainerdMirror(registryAddr); err != nil {
+	if err := containerd.SetupContainerdMirror(i.host, registryAddr); err != nil {
 		return fmt.Errorf("failed to configure containerd mirror: %w", err)
 	}
-	logger.Infof("✅ Containerd registry mirror configured (local registry: %s)", registryAddr)
+	i.logChange("✅ Containerd registry mirror configured (local registry: %s)", registryAddr)
 
 	// Step 5: Write k0s configuration
 	logger.Info("Writing k0s configuration to /etc/k0s/k0s.yaml...")
 	if err := i.WriteK0sConfig(k0sConfigBytes); err != nil {
 		return fmt.Errorf("failed to write k0s config: %w", err)
 	}
-	logger.Info("✅ K0s configuration written")
+	i.logChange("✅ K0s configuration written")
 
-	logger.Info("✅ Airgap installation preparation complete")
+	i.logChange("✅ Airgap installation preparation complete")
 
 	return nil
 }
@@ -217,12 +261,12 @@ func (i *Installer) WriteK0sConfig(config []byte) error {
 	configPath := "/etc/k0s/k0s.yaml"
 
 	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+	if err := i.host.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	// Write configuration file
-	if err := os.WriteFile(configPath, config, 0600); err != nil {
+	if err := i.host.WriteFile(configPath, config, 0600, system.RedactWith(generator.RedactK0sConfigFile)); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -20,6 +20,13 @@ import (
 	"github.com/google/go-containerregistry/pkg/registry"
 )
 
+const (
+	// DefaultStorageDir is the default storage directory of the registry
+	DefaultStorageDir = "/var/lib/k0rdentd/registry"
+	// SkopeoBinaryPath is where the embedded skopeo binary is extracted
+	SkopeoBinaryPath = "/usr/bin/skopeo"
+)
+
 // RegistryDaemon runs a local OCI registry for airgap installations
 type RegistryDaemon struct {
 	port       string
@@ -87,9 +94,13 @@ func (r *RegistryDaemon) Start(ctx context.Context) error {
 	reg := registry.New(registry.WithBlobHandler(blobHandler))
 
 	// Step 5: Start HTTP server
+	handler, err := r.handler(reg)
+	if err != nil {
+		return err
+	}
 	r.server = &http.Server{
-		Addr:         r.host + ":" + r.port,
-		Handler:      reg,
+		Addr:         r.Addr(),
+		Handler:      handler,
 		ReadTimeout:  5 * time.Minute,
 		WriteTimeout: 10 * time.Minute,
 	}
@@ -126,6 +137,23 @@ func (r *RegistryDaemon) Start(ctx context.Context) error {
 	}
 }
 
+// handler serves the registry and, in airgap builds, the embedded k0s binary
+// for the k0s autopilot upgrades of the other nodes
+func (r *RegistryDaemon) handler(reg http.Handler) (http.Handler, error) {
+	if !airgap.IsAirGap() {
+		return reg, nil
+	}
+
+	k0sFiles, err := fs.Sub(assets.K0sBinary, "k0s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded k0s directory: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", reg)
+	mux.Handle(airgap.K0sBinaryURLPath, http.StripPrefix(airgap.K0sBinaryURLPath, http.FileServer(http.FS(k0sFiles))))
+	return mux, nil
+}
+
 // verifyBundle verifies the bundle signature
 func (r *RegistryDaemon) verifyBundle() error {
 	// If cosignKey is a URL, download it first
@@ -153,13 +181,13 @@ func (r *RegistryDaemon) verifyBundle() error {
 
 // pushImagesToRegistry pushes images from the bundle to the local registry
 func (r *RegistryDaemon) pushImagesToRegistry(ctx context.Context) error {
-	registryAddr := "localhost:" + r.port
+	registryAddr := net.JoinHostPort("localhost", r.port)
 	return PushImages(r.bundlePath, registryAddr)
 }
 
-// Addr returns the registry address
+// Addr returns the registry address, IPv6 hosts being bracketed
 func (r *RegistryDaemon) Addr() string {
-	return r.host + ":" + r.port
+	return net.JoinHostPort(r.host, r.port)
 }
 
 // WaitForSignal waits for shutdown signals
@@ -177,7 +205,7 @@ func (r *RegistryDaemon) WaitForSignal(ctx context.Context) {
 
 // IsRunning checks if the registry port is in use
 func (r *RegistryDaemon) IsRunning() bool {
-	ln, err := net.Listen("tcp", r.host+":"+r.port)
+	ln, err := net.Listen("tcp", r.Addr())
 	if err != nil {
 		return true // Port is in use
 	}
@@ -260,7 +288,7 @@ func ExtractSkopeoBinary() error {
 	}
 
 	// Create destination file at /usr/bin/skopeo
-	dstPath := "/usr/bin/skopeo"
+	dstPath := SkopeoBinaryPath
 	dstFile, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		return fmt.Errorf("failed to create skopeo binary at %s: %w", dstPath, err)
@@ -2,8 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/network"
 	"github.com/belgaied2/k0rdentd/pkg/utils"
 	"github.com/urfave/cli/v2"
 )
@@ -23,6 +27,16 @@ var ConfigCommand = &cli.Command{
 			Name:   "show",
 			Usage:  "Show current configuration",
 			Action: showConfigAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "sources",
+					Usage: "Show which file each effective value comes from",
+				},
+				&cli.BoolFlag{
+					Name:  "show-secrets",
+					Usage: "Show credentials and tokens instead of redacting them",
+				},
+			},
 		},
 		{
 			Name:   "init",
@@ -35,34 +49,95 @@ var ConfigCommand = &cli.Command{
 					Value:   "/etc/k0rdentd/k0rdentd.yaml",
 					Usage:   "Output file path",
 				},
+				&cli.BoolFlag{
+					Name:    "interactive",
+					Aliases: []string{"i"},
+					Usage:   "Prompt for the main settings",
+				},
+				&cli.StringFlag{
+					Name:    "profile",
+					Aliases: []string{"p"},
+					Usage:   "Start from a commented configuration for a topology: " + strings.Join(config.Profiles, ", "),
+				},
+			},
+		},
+		{
+			Name:   "schema",
+			Usage:  "Print the JSON Schema of the configuration file",
+			Action: schemaConfigAction,
+		},
+		{
+			Name:      "migrate",
+			Usage:     "Convert configuration file to the current schema version",
+			UsageText: "k0rdentd config migrate [options]",
+			Action:    migrateConfigAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "output",
+					Aliases: []string{"o"},
+					Usage:   "Write the migrated configuration to this path instead of updating the file in place",
+				},
 			},
 		},
 	},
 }
 
-func validateConfigAction(c *cli.Context) error {
-	_, err := config.LoadConfigWithFallback(
+// loadConfig loads the configuration selected by the global flags,
+// merged with its drop-in files
+func loadConfig(c *cli.Context) (*config.K0rdentdConfig, error) {
+	cfg, _, err := loadConfigWithSources(c)
+	return cfg, err
+}
+
+// loadConfigWithSources works like loadConfig and also returns which file
+// each effective value comes from
+func loadConfigWithSources(c *cli.Context) (*config.K0rdentdConfig, []config.ValueSource, error) {
+	return config.LoadConfigWithSources(
 		c.String("config-file"),
 		"/etc/k0rdentd/k0rdentd.yaml",
 		c.IsSet("config-file"),
+		config.LoadOptions{
+			Lenient: c.Bool("lenient"),
+		},
 	)
+}
+
+func validateConfigAction(c *cli.Context) error {
+	cfg, err := loadConfig(c)
 	if err != nil {
 		return fmt.Errorf("configuration is invalid: %w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("configuration is invalid: %w", err)
+	}
 	utils.GetLogger().Info("✅ Configuration is valid!")
 	return nil
 }
 
 func showConfigAction(c *cli.Context) error {
-	cfg, err := config.LoadConfigWithFallback(
-		c.String("config-file"),
-		"/etc/k0rdentd/k0rdentd.yaml",
-		c.IsSet("config-file"),
-	)
+	cfg, sources, err := loadConfigWithSources(c)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if c.Bool("sources") {
+		var sb strings.Builder
+		w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tSOURCE")
+		for _, source := range sources {
+			fmt.Fprintf(w, "%s\t%s\n", source.Path, source)
+		}
+		w.Flush()
+		utils.GetLogger().Info(sb.String())
+		return nil
+	}
+
+	if !c.Bool("show-secrets") {
+		if cfg, err = cfg.Redacted(); err != nil {
+			return fmt.Errorf("failed to redact config: %w", err)
+		}
+	}
+
 	configData, err := config.MarshalConfig(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
@@ -73,10 +148,35 @@ func showConfigAction(c *cli.Context) error {
 }
 
 func initConfigAction(c *cli.Context) error {
-	cfg := config.DefaultConfig()
-	configData, err := config.MarshalConfig(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	logger := utils.GetLogger()
+	profile := c.String("profile")
+
+	var configData []byte
+	if c.Bool("interactive") || profile != "" {
+		internalIP, err := network.GetInternalIP(network.FamilyAny)
+		if err != nil {
+			logger.Warnf("⚠️  Could not detect internal IP address: %v", err)
+		}
+
+		var cfg *config.K0rdentdConfig
+		if c.Bool("interactive") {
+			cfg, profile, err = runConfigWizard(newPrompter(c.App.Reader, c.App.Writer), profile, internalIP)
+		} else {
+			cfg, err = config.ProfileConfig(profile, internalIP)
+		}
+		if err != nil {
+			return err
+		}
+
+		if configData, err = config.MarshalCommentedConfig(cfg, profile); err != nil {
+			return err
+		}
+	} else {
+		cfg := config.DefaultConfig()
+		var err error
+		if configData, err = config.MarshalConfig(cfg); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
 	}
 
 	outputPath := c.String("output")
@@ -84,6 +184,63 @@ func initConfigAction(c *cli.Context) error {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
-	utils.GetLogger().Infof("✅ Default configuration written to %s", outputPath)
+	logger.Infof("✅ Configuration written to %s", outputPath)
+	return nil
+}
+
+func schemaConfigAction(c *cli.Context) error {
+	schema, err := config.JSONSchema()
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	// Printed on stdout so it can be redirected to a file
+	fmt.Println(string(schema))
+	return nil
+}
+
+func migrateConfigAction(c *cli.Context) error {
+	logger := utils.GetLogger()
+	inputPath := c.String("config-file")
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	migrated, fromVersion, err := config.MigrateConfig(data)
+	if err != nil {
+		return fmt.Errorf("failed to migrate config: %w", err)
+	}
+	if fromVersion == config.CurrentAPIVersion {
+		logger.Infof("✅ %s is already at %s", inputPath, config.CurrentAPIVersion)
+		return nil
+	}
+	if fromVersion == "" {
+		fromVersion = "unversioned"
+	}
+
+	if c.Bool("dry-run") {
+		logger.Infof("🔍 Dry run: %s would be migrated from %s to %s", inputPath, fromVersion, config.CurrentAPIVersion)
+		fmt.Print(string(migrated))
+		return nil
+	}
+
+	outputPath := c.String("output")
+	if outputPath == "" {
+		// Keep the original file around when updating in place
+		outputPath = inputPath
+		backupPath := inputPath + ".bak"
+		if err := config.WriteConfigFile(backupPath, data); err != nil {
+			return fmt.Errorf("failed to write backup file: %w", err)
+		}
+		logger.Infof("💾 Original configuration saved to %s", backupPath)
+	}
+
+	if err := config.WriteConfigFile(outputPath, migrated); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	logger.Infof("✅ Configuration migrated from %s to %s and written to %s", fromVersion, config.CurrentAPIVersion, outputPath)
 	return nil
 }
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/events"
+	"github.com/belgaied2/k0rdentd/pkg/ui"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// installOutputFlag works like planOutputFlag, json also reports the
+	// progress of an installation as events on stdout
+	installOutputFlag = &cli.StringFlag{
+		Name:  "output",
+		Value: "text",
+		Usage: "Format of the --dry-run plan, or of the installation progress: text or json (one JSON event per line on stdout)",
+	}
+	eventsFileFlag = &cli.StringFlag{
+		Name:  "events-file",
+		Usage: "Write the installation progress to a file, one JSON event per line",
+	}
+)
+
+// progressEvents returns the recorder of the events selected by --output
+// json and --events-file, nil if there are none, and a function closing it
+func progressEvents(c *cli.Context) (*events.Recorder, func(), error) {
+	if c.Bool("dry-run") {
+		if c.IsSet("events-file") {
+			return nil, nil, fmt.Errorf("--events-file cannot be used with --dry-run")
+		}
+		return nil, func() {}, nil
+	}
+
+	var writers []io.Writer
+	closeFn := func() {}
+	if c.String("output") == "json" {
+		// Commands print their output to stdout in debug mode
+		if c.Bool("debug") {
+			return nil, nil, fmt.Errorf("--output json cannot be used with --debug, use --events-file instead")
+		}
+		// Stdout only carries the events
+		utils.DisableSpinner()
+		utils.GetLogger().SetOutput(os.Stderr)
+		writers = append(writers, os.Stdout)
+	}
+	if path := c.String("events-file"); path != "" {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open events file: %w", err)
+		}
+		writers = append(writers, file)
+		closeFn = func() { file.Close() }
+	}
+
+	if len(writers) == 0 {
+		return nil, closeFn, nil
+	}
+	return events.NewRecorder(io.MultiWriter(writers...)), closeFn, nil
+}
+
+// installSummary tells how to reach k0rdent once installed, access is nil
+// when the UI wasn't exposed
+func installSummary(cfg *config.K0rdentdConfig, access *ui.Access) *events.Summary {
+	summary := &events.Summary{}
+	if access != nil {
+		summary.UIURL = access.URL
+		summary.UINodePortURL = access.NodePortURL
+		summary.UIUsername = access.Username
+		summary.UIPasswordFrom = ui.PasswordSource
+	}
+
+	summary.Credentials = cfg.K0rdent.Credentials.Names()
+	return summary
+}
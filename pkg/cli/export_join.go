@@ -54,22 +54,27 @@ func exportJoinConfigAction(c *cli.Context) error {
 	logger := utils.GetLogger()
 
 	// Load current config to get k0s version and airgap settings
-	cfg, err := config.LoadConfigWithFallback(
-		c.String("config-file"),
-		"/etc/k0rdentd/k0rdentd.yaml",
-		c.IsSet("config-file"),
-	)
+	cfg, err := loadConfig(c)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Get controller IP
-	controllerIP, err := network.GetControllerIP(c.String("controller-ip"))
+	// Get controller IP, IPv6-only clusters are joined over IPv6
+	controllerIP, err := network.GetControllerIP(c.String("controller-ip"), network.FamilyOfCIDR(cfg.K0s.Network.PodCIDR))
 	if err != nil {
 		return fmt.Errorf("failed to get controller IP: %w", err)
 	}
 	logger.Infof("Using controller IP: %s", controllerIP)
 
+	// Joined nodes point at the external address or VIP of the controllers
+	// when there is one, so that they don't depend on this controller
+	server := cfg.K0s.StableAPIAddress()
+	if server != "" {
+		logger.Infof("Using API address: %s", server)
+	} else {
+		server = controllerIP
+	}
+
 	// Create token manager
 	tokenManager := token.NewManager("/usr/local/bin/k0s", c.Bool("debug"))
 
@@ -93,7 +98,7 @@ func exportJoinConfigAction(c *cli.Context) error {
 	}
 
 	// Generate controller join config
-	controllerConfig := generateJoinConfig(cfg, "controller", controllerIP, controllerToken, c.Int("registry-port"))
+	controllerConfig := generateJoinConfig(cfg, "controller", server, controllerIP, controllerToken, c.Int("registry-port"))
 	controllerPath := filepath.Join(outputDir, "controller-join.yaml")
 
 	if err := writeJoinConfig(controllerPath, controllerConfig, c.Bool("overwrite")); err != nil {
@@ -102,7 +107,7 @@ func exportJoinConfigAction(c *cli.Context) error {
 	logger.Infof("✅ Created: %s", controllerPath)
 
 	// Generate worker join config
-	workerConfig := generateJoinConfig(cfg, "worker", controllerIP, workerToken, c.Int("registry-port"))
+	workerConfig := generateJoinConfig(cfg, "worker", server, controllerIP, workerToken, c.Int("registry-port"))
 	workerPath := filepath.Join(outputDir, "worker-join.yaml")
 
 	if err := writeJoinConfig(workerPath, workerConfig, c.Bool("overwrite")); err != nil {
@@ -124,30 +129,47 @@ func exportJoinConfigAction(c *cli.Context) error {
 	return nil
 }
 
-// generateJoinConfig creates a join configuration file content
-func generateJoinConfig(baseCfg *config.K0rdentdConfig, mode, controllerIP, token string, registryPort int) *config.K0rdentdConfig {
+// generateJoinConfig creates a join configuration file content. Nodes join
+// server, the registry runs on the controller at controllerIP.
+func generateJoinConfig(baseCfg *config.K0rdentdConfig, mode, server, controllerIP, token string, registryPort int) *config.K0rdentdConfig {
 	version, err := checker.GetK0sVersion()
 	if err != nil {
 		version = baseCfg.K0s.Version
 	}
 	cfg := &config.K0rdentdConfig{
+		APIVersion: config.CurrentAPIVersion,
+		Kind:       config.Kind,
 		K0s: config.K0sConfig{
 			Version: version,
 		},
 		Join: config.JoinConfig{
 			Mode:   mode,
-			Server: controllerIP,
+			Server: server,
 			Token:  token,
 		},
 	}
 
+	// Controllers must share the API, load balancing and datastore
+	// settings, the bind and etcd peer addresses are specific to each
+	// controller
+	if mode == "controller" {
+		cfg.K0s.API = config.APIConfig{
+			Port:            baseCfg.K0s.API.Port,
+			ExternalAddress: baseCfg.K0s.API.ExternalAddress,
+			SANs:            baseCfg.K0s.API.SANs,
+		}
+		cfg.K0s.Network = baseCfg.K0s.Network
+		cfg.K0s.Storage = baseCfg.K0s.Storage
+		cfg.K0s.Storage.Etcd.PeerAddress = ""
+	}
+
 	// Always include airgap settings if running in airgap mode
 	// Joining nodes need the registry address to configure containerd mirrors
 	if airgap.IsAirGap() {
 		registryAddress := baseCfg.Airgap.Registry.Address
 		if registryAddress == "" {
 			// Generate registry address from controller IP if not specified
-			registryAddress = fmt.Sprintf("%s:%d", controllerIP, registryPort)
+			registryAddress = network.HostPort(controllerIP, registryPort)
 		}
 		cfg.Airgap = config.AirgapConfig{
 			Registry: config.RegistryConfig{
@@ -2,9 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/belgaied2/k0rdentd/internal/airgap"
 	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/events"
 	"github.com/belgaied2/k0rdentd/pkg/generator"
 	"github.com/belgaied2/k0rdentd/pkg/installer"
 	"github.com/belgaied2/k0rdentd/pkg/k0s"
@@ -44,18 +46,115 @@ var InstallCommand = &cli.Command{
 			Usage:   "Replace existing k0s binary without prompting (only if not running)",
 			EnvVars: []string{"K0RDENTD_REPLACE_K0S"},
 		},
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Skip the steps completed by a previous install with the same inputs",
+		},
+		&cli.StringFlag{
+			Name:  "from-step",
+			Usage: "Restart the installation at a step (check-version, write-config, prepare-airgap, install-k0s, wait-k0rdent, wait-providers, create-credentials)",
+		},
+		newSetFlag(),
+		newSetFileFlag(),
+		&cli.BoolFlag{
+			Name:  "rollback-on-failure",
+			Usage: "Undo the changes made to this node if the installation fails",
+		},
+		ignorePreflightFlag,
+		installOutputFlag,
+		eventsFileFlag,
+		timeoutFlag,
 	},
 }
 
+// newSetFlag returns the --set flag overriding values of the k0rdent helm
+// chart. Each command gets its own flag, the collected values are not shared.
+func newSetFlag() cli.Flag {
+	return &cli.GenericFlag{
+		Name:  "set",
+		Value: &helmValueFlag{},
+		Usage: "Set k0rdent helm chart values (can be repeated, e.g. --set controller.replicas=2,a.b=c)",
+	}
+}
+
+// newSetFileFlag returns the --set-file flag overriding values of the
+// k0rdent helm chart with the content of files
+func newSetFileFlag() cli.Flag {
+	return &cli.GenericFlag{
+		Name:  "set-file",
+		Value: &helmValueFlag{},
+		Usage: "Set k0rdent helm chart values from files (can be repeated, e.g. --set-file a.b=/path/to/file)",
+	}
+}
+
+// helmValueFlag collects the expressions of a repeated --set flag. Unlike
+// slice flags, it doesn't split them on commas, which separate assignments
+// and list items such as a={b,c}.
+type helmValueFlag []string
+
+// Set implements flag.Value
+func (v *helmValueFlag) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+// String implements flag.Value
+func (v *helmValueFlag) String() string {
+	return strings.Join(*v, " ")
+}
+
+// helmValueExprs returns the expressions given to a helmValueFlag
+func helmValueExprs(c *cli.Context, name string) []string {
+	if exprs, ok := c.Generic(name).(*helmValueFlag); ok {
+		return *exprs
+	}
+	return nil
+}
+
+// timeoutFlag overrides the timeouts section of the configuration
+var timeoutFlag = &cli.StringSliceFlag{
+	Name:  "timeout",
+	Usage: "Override the timeout of a phase (can be repeated, e.g. --timeout k0rdentReady=30m), phases: " + strings.Join(config.TimeoutPhases(), ", "),
+}
+
+// applyTimeoutFlags applies --timeout to the timeouts of the configuration
+func applyTimeoutFlags(c *cli.Context, cfg *config.K0rdentdConfig) error {
+	for _, expr := range c.StringSlice("timeout") {
+		if err := cfg.Timeouts.Set(expr); err != nil {
+			return fmt.Errorf("invalid --timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyHelmValueFlags applies --set-file then --set to the inline values of
+// the k0rdent helm chart, so they take precedence over the configuration
+func applyHelmValueFlags(c *cli.Context, cfg *config.K0rdentdConfig) error {
+	sets, setFiles := helmValueExprs(c, "set"), helmValueExprs(c, "set-file")
+	if len(sets) == 0 && len(setFiles) == 0 {
+		return nil
+	}
+	if cfg.K0rdent.Helm.Values == nil {
+		cfg.K0rdent.Helm.Values = make(map[string]interface{})
+	}
+	for _, expr := range setFiles {
+		if err := config.SetHelmFileValues(cfg.K0rdent.Helm.Values, expr); err != nil {
+			return fmt.Errorf("invalid --set-file %q: %w", expr, err)
+		}
+	}
+	for _, expr := range sets {
+		if err := config.SetHelmValues(cfg.K0rdent.Helm.Values, expr); err != nil {
+			return fmt.Errorf("invalid --set %q: %w", expr, err)
+		}
+	}
+	return nil
+}
+
 func installAction(c *cli.Context) error {
 	logger := utils.GetLogger()
 
 	// Load configuration with fallback logic
-	cfg, err := config.LoadConfigWithFallback(
-		c.String("config-file"),
-		"/etc/k0rdentd/k0rdentd.yaml",
-		c.IsSet("config-file"),
-	)
+	cfg, err := loadConfig(c)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -67,6 +166,17 @@ func installAction(c *cli.Context) error {
 	if c.IsSet("k0rdent-version") {
 		cfg.K0rdent.Version = c.String("k0rdent-version")
 	}
+	if err := applyHelmValueFlags(c, cfg); err != nil {
+		return err
+	}
+	if err := applyTimeoutFlags(c, cfg); err != nil {
+		return err
+	}
+
+	// Validate the effective configuration before touching the host
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
 
 	// Determine if we're joining a cluster
 	// Priority: CLI flags > config file
@@ -91,10 +201,87 @@ func installAction(c *cli.Context) error {
 		return fmt.Errorf("invalid mode '%s': must be 'controller' or 'worker'", joinMode)
 	}
 
+	// Install steps are only recorded when initializing a cluster
+	if joinMode != "" && (c.Bool("resume") || c.IsSet("from-step")) {
+		return fmt.Errorf("--resume and --from-step cannot be used when joining a cluster")
+	}
+	// A rolled back install starts over, it has nothing to resume
+	if c.Bool("rollback-on-failure") && (c.Bool("resume") || c.IsSet("from-step")) {
+		return fmt.Errorf("--rollback-on-failure cannot be used with --resume or --from-step")
+	}
+
+	if err := validatePlanOutput(c); err != nil {
+		return err
+	}
+	recorder, closeEvents, err := progressEvents(c)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+
+	// Check the host before anything changes
+	if err := runPreflight(cfg, joinMode, c.StringSlice("ignore-preflight"), c.Bool("dry-run")); err != nil {
+		recorder.Emit(events.Event{Type: events.InstallFailed, Error: err.Error()})
+		return err
+	}
+
+	// Create installer
+	dryRun := c.Bool("dry-run")
+	inst := installer.NewInstaller(
+		c.Bool("debug"),
+		dryRun,
+	)
+	inst.SetConfig(cfg)
+	inst.SetEvents(recorder)
+
+	// Set replace-k0s flag if specified
+	if c.IsSet("replace-k0s") {
+		inst.SetReplaceK0s(c.Bool("replace-k0s"))
+	}
+	inst.SetResume(c.Bool("resume"))
+	inst.SetFromStep(c.String("from-step"))
+
+	var access *ui.Access
+	install := func() error {
+		var err error
+		access, err = installNode(inst, cfg, joinMode)
+		return err
+	}
+	if c.Bool("rollback-on-failure") {
+		err = inst.WithRollback(install)
+	} else {
+		err = install()
+	}
+	if err != nil {
+		recorder.Emit(events.Event{Type: events.InstallFailed, Error: err.Error()})
+		return err
+	}
+	finished := events.Event{Type: events.InstallFinished}
+	if joinMode == "" {
+		finished.Summary = installSummary(cfg, access)
+	}
+	recorder.Emit(finished)
+
+	if dryRun {
+		return writePlan(c, inst.Plan())
+	}
+	if joinMode != "" {
+		logger.Info("✅ Successfully joined the cluster!")
+	} else {
+		logger.Info("✅ K0s and K0rdent installed successfully!")
+	}
+	return nil
+}
+
+// installNode installs k0s if missing, then initializes a new cluster or
+// joins an existing one. It returns how to reach the UI of a new cluster.
+func installNode(inst *installer.Installer, cfg *config.K0rdentdConfig, joinMode string) (*ui.Access, error) {
+	logger := utils.GetLogger()
+
 	// Check if k0s binary exists
 	k0sCheck, err := k0s.CheckK0s()
 	if err != nil {
-		return fmt.Errorf("failed to check k0s: %w", err)
+		return nil, fmt.Errorf("failed to check k0s: %w", err)
 	}
 
 	// If k0s is not installed, install it (for both init and join modes)
@@ -103,54 +290,46 @@ func installAction(c *cli.Context) error {
 		if cfg.K0s.Version != "" {
 			// Install specific version if configured
 			logger.Infof("k0s binary not found, installing version %s...", cfg.K0s.Version)
-			if err := k0s.InstallK0sVersion(cfg.K0s.Version); err != nil {
-				return fmt.Errorf("failed to install k0s version %s: %w", cfg.K0s.Version, err)
+			if err := inst.DownloadK0s(cfg.K0s.Version); err != nil {
+				return nil, fmt.Errorf("failed to install k0s version %s: %w", cfg.K0s.Version, err)
 			}
 		} else {
 			// Install latest version
 			logger.Info("k0s binary not found, installing latest version...")
-			if err := k0s.InstallK0s(); err != nil {
-				return fmt.Errorf("failed to install k0s: %w", err)
+			if err := inst.DownloadK0s(""); err != nil {
+				return nil, fmt.Errorf("failed to install k0s: %w", err)
 			}
 		}
 	}
 
-	// Create installer
-	inst := installer.NewInstaller(
-		c.Bool("debug"),
-		c.Bool("dry-run"),
-	)
-	inst.SetConfig(cfg)
-
-	// Set replace-k0s flag if specified
-	if c.IsSet("replace-k0s") {
-		inst.SetReplaceK0s(c.Bool("replace-k0s"))
-	}
-
 	// Execute installation based on mode
 	if joinMode != "" {
 		// Join existing cluster
 		if err := inst.InstallJoin(&cfg.Join); err != nil {
-			return fmt.Errorf("join installation failed: %w", err)
-		}
-		logger.Info("✅ Successfully joined the cluster!")
-	} else {
-		// Initialize new cluster (cluster-init is implicit)
-		k0sConfig, err := generator.GenerateK0sConfig(cfg)
-		if err != nil {
-			return fmt.Errorf("failed to generate K0s config: %w", err)
+			return nil, fmt.Errorf("join installation failed: %w", err)
 		}
+		return nil, nil
+	}
 
-		if err := inst.Install(k0sConfig, &cfg.K0rdent); err != nil {
-			return fmt.Errorf("installation failed: %w", err)
-		}
-		logger.Info("✅ K0s and K0rdent installed successfully!")
+	// Initialize new cluster (cluster-init is implicit)
+	k0sConfig, err := generator.GenerateK0sConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate K0s config: %w", err)
+	}
 
-		// Expose k0rdent UI (only for controller init mode)
-		if err := ui.ExposeUI(); err != nil {
-			logger.Warnf("Failed to expose k0rdent UI: %v", err)
-		}
+	if err := inst.Install(k0sConfig, &cfg.K0rdent); err != nil {
+		return nil, fmt.Errorf("installation failed: %w", err)
 	}
 
-	return nil
+	// Expose k0rdent UI (only for controller init mode)
+	var access *ui.Access
+	err = inst.Host().Do("Expose the k0rdent UI", func() error {
+		var err error
+		access, err = ui.Expose()
+		return err
+	})
+	if err != nil {
+		logger.Warnf("Failed to expose k0rdent UI: %v", err)
+	}
+	return access, nil
 }
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/urfave/cli/v2"
+)
+
+// helmValueFlags returns the --set and --set-file values of a command
+func helmValueFlags(command *cli.Command) []*helmValueFlag {
+	var values []*helmValueFlag
+	for _, flag := range command.Flags {
+		if generic, ok := flag.(*cli.GenericFlag); ok {
+			if value, ok := generic.Value.(*helmValueFlag); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func TestHelmValueFlagsPerCommand(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	install := helmValueFlags(InstallCommand)
+	render := helmValueFlags(RenderCommand)
+	g.Expect(install).To(gomega.HaveLen(2))
+	g.Expect(render).To(gomega.HaveLen(2))
+
+	g.Expect(install[0].Set("a.b=c")).To(gomega.Succeed())
+	defer func() { *install[0] = nil }()
+	for _, value := range append(install[1:], render...) {
+		g.Expect(*value).To(gomega.BeEmpty())
+	}
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/belgaied2/k0rdentd/pkg/system"
+	"github.com/urfave/cli/v2"
+)
+
+// planOutputFlag selects the format of the dry-run plan
+var planOutputFlag = &cli.StringFlag{
+	Name:  "output",
+	Value: "text",
+	Usage: "Format of the --dry-run plan: text or json",
+}
+
+// validatePlanOutput checks the --output flag before anything runs
+func validatePlanOutput(c *cli.Context) error {
+	switch c.String("output") {
+	case "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q: must be 'text' or 'json'", c.String("output"))
+	}
+}
+
+// writePlan prints the changes recorded by a dry run to stdout
+func writePlan(c *cli.Context, plan *system.Plan) error {
+	if c.String("output") == "json" {
+		return plan.WriteJSON(os.Stdout)
+	}
+	return plan.WriteText(os.Stdout)
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/preflight"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var PreflightCommand = &cli.Command{
+	Name:      "preflight",
+	Usage:     "Check that this host meets the requirements of K0s and K0rdent",
+	UsageText: "k0rdentd preflight [options]",
+	Action:    preflightAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "mode",
+			Usage: "Check the host for joining as controller or worker (default: join.mode from config)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "ignore",
+			Usage: fmt.Sprintf("Skip a check (can be repeated): %s", strings.Join(preflight.CheckNames, ", ")),
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Value: "text",
+			Usage: "Format of the results: text or json",
+		},
+	},
+}
+
+// ignorePreflightFlag skips preflight checks during the installation
+var ignorePreflightFlag = &cli.StringSliceFlag{
+	Name:  "ignore-preflight",
+	Usage: "Skip a preflight check (can be repeated), see k0rdentd preflight",
+}
+
+func preflightAction(c *cli.Context) error {
+	if err := validatePlanOutput(c); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mode := cfg.Join.Mode
+	if c.IsSet("mode") {
+		mode = c.String("mode")
+	}
+	if mode != "" && mode != "controller" && mode != "worker" {
+		return fmt.Errorf("invalid mode '%s': must be 'controller' or 'worker'", mode)
+	}
+
+	report, err := preflight.Run(cfg, preflightOptions(cfg, mode, c.StringSlice("ignore")))
+	if err != nil {
+		return err
+	}
+
+	if c.String("output") == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteTable(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write preflight results: %w", err)
+	}
+
+	if !report.Passed {
+		return fmt.Errorf("preflight checks failed: %s", strings.Join(report.Failed(), ", "))
+	}
+	return nil
+}
+
+// runPreflight runs the preflight checks before an installation, logging
+// the checks that did not pass. A dry-run installation doesn't need root.
+func runPreflight(cfg *config.K0rdentdConfig, mode string, ignore []string, dryRun bool) error {
+	logger := utils.GetLogger()
+	logger.Info("🔍 Running preflight checks...")
+
+	opts := preflightOptions(cfg, mode, ignore)
+	opts.DryRun = dryRun
+	report, err := preflight.Run(cfg, opts)
+	if err != nil {
+		return err
+	}
+	for _, result := range report.Results {
+		switch result.Status {
+		case preflight.StatusWarn:
+			logger.Warnf("⚠️  %s: %s", result.Name, result.Message)
+		case preflight.StatusFail:
+			logger.Errorf("❌ %s: %s", result.Name, result.Message)
+		default:
+			logger.Debugf("%s: %s", result.Name, result.Message)
+		}
+	}
+
+	if !report.Passed {
+		failed := report.Failed()
+		return fmt.Errorf("preflight checks failed: %s (fix them or skip them with --ignore-preflight %s)",
+			strings.Join(failed, ", "), failed[0])
+	}
+	logger.Info("✅ Preflight checks passed")
+	return nil
+}
+
+// preflightOptions describes this node for the preflight checks
+func preflightOptions(cfg *config.K0rdentdConfig, mode string, ignore []string) preflight.Options {
+	return preflight.Options{
+		Mode:            mode,
+		Airgap:          airgap.IsAirGap(),
+		RegistryAddress: airgap.NewInstaller(cfg, false).GetRegistryAddress(),
+		Ignore:          ignore,
+	}
+}
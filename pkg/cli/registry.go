@@ -30,13 +30,13 @@ var RegistryCommand = &cli.Command{
 			Name:    "host",
 			Aliases: []string{"H"},
 			Value:   "0.0.0.0",
-			Usage:   "Host address to bind to (default: 0.0.0.0 for all interfaces)",
+			Usage:   "Host address to bind to (default: 0.0.0.0 for all IPv4 interfaces, use :: for IPv6)",
 			EnvVars: []string{"K0RDENTD_REGISTRY_HOST"},
 		},
 		&cli.StringFlag{
 			Name:    "storage",
 			Aliases: []string{"s"},
-			Value:   "/var/lib/k0rdentd/registry",
+			Value:   registry.DefaultStorageDir,
 			Usage:   "Storage directory for registry data",
 			EnvVars: []string{"K0RDENTD_REGISTRY_STORAGE"},
 		},
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/pkg/render"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var RenderCommand = &cli.Command{
+	Name:      "render",
+	Usage:     "Write the files install would produce to a directory, without changing the host",
+	UsageText: "k0rdentd render [options]",
+	Action:    renderAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   "./rendered",
+			Usage:   "Output directory for the rendered files",
+		},
+		&cli.BoolFlag{
+			Name:  "show-secrets",
+			Usage: "Write sensitive values instead of redacting them",
+		},
+		newSetFlag(),
+		newSetFileFlag(),
+	},
+}
+
+func renderAction(c *cli.Context) error {
+	logger := utils.GetLogger()
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := applyHelmValueFlags(c, cfg); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	artifacts, err := render.Render(cfg, render.Options{
+		Airgap:      airgap.IsAirGap(),
+		ShowSecrets: c.Bool("show-secrets"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render: %w", err)
+	}
+
+	outputDir := c.String("output")
+	if err := render.WriteArtifacts(outputDir, artifacts); err != nil {
+		return err
+	}
+
+	for _, artifact := range artifacts {
+		logger.Infof("📄 %s", filepath.Join(outputDir, artifact.Path))
+	}
+	logger.Infof("✅ Rendered %d files to %s", len(artifacts), outputDir)
+	if !c.Bool("show-secrets") {
+		logger.Info("Sensitive values are redacted, use --show-secrets to include them")
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/belgaied2/k0rdentd/pkg/installer"
-	"github.com/belgaied2/k0rdentd/pkg/k0s"
 	"github.com/belgaied2/k0rdentd/pkg/utils"
 	"github.com/urfave/cli/v2"
 )
@@ -21,41 +20,145 @@ var UninstallCommand = &cli.Command{
 			Aliases: []string{"f"},
 			Usage:   "Force uninstall without confirmation",
 		},
+		&cli.BoolFlag{
+			Name:  "keep-config",
+			Usage: "Keep the k0rdentd configuration in /etc/k0rdentd",
+		},
+		&cli.BoolFlag{
+			Name:  "purge-registry",
+			Usage: "Remove the storage of the airgap registry",
+		},
+		&cli.BoolFlag{
+			Name:  "remove-binaries",
+			Usage: "Remove the k0s binary, and the extracted skopeo binary in airgap builds",
+		},
+		&cli.BoolFlag{
+			Name:  "k0rdent-only",
+			Usage: "Remove only k0rdent and its credentials from the running cluster, keeping k0s and its workloads",
+		},
+		timeoutFlag,
+		planOutputFlag,
 	},
 }
 
 func uninstallAction(c *cli.Context) error {
+	logger := utils.GetLogger()
+	if err := validatePlanOutput(c); err != nil {
+		return err
+	}
+	dryRun := c.Bool("dry-run")
 
-	// Check if k0s binary exists
-	k0sCheck, err := k0s.CheckK0s()
+	installer := installer.NewInstaller(
+		c.Bool("debug"),
+		dryRun,
+	)
+
+	if c.Bool("k0rdent-only") {
+		return uninstallK0rdent(c, installer)
+	}
+	// A full uninstall doesn't wait for the cluster
+	if c.IsSet("timeout") {
+		return fmt.Errorf("--timeout can only be used with --k0rdent-only")
+	}
+
+	inventory, err := installer.UninstallInventory(uninstallOptions(c))
 	if err != nil {
-		return fmt.Errorf("failed to check k0s: %w", err)
+		return err
+	}
+	if inventory.IsEmpty() {
+		logger.Info("Nothing to uninstall, k0s and k0rdent are not installed on this system.")
+		return nil
 	}
 
-	// If k0s is not installed, uninstall cannot proceed
-	if !k0sCheck.Installed {
-		return fmt.Errorf("k0s binary not found, cannot uninstall")
+	// Nothing is changed in dry-run mode, no need to confirm
+	if !dryRun {
+		logger.Info("🚨 This will remove from this system:")
+		for _, item := range inventory.Remove {
+			logger.Infof("   - %s: %s", item.Description, item.Path)
+		}
+		for _, item := range inventory.Keep {
+			logger.Infof("   Keeping %s: %s (use %s to remove it)", item.Description, item.Path, item.Flag)
+		}
+	}
+	if !c.Bool("force") && !dryRun && !confirmUninstall() {
+		return nil
 	}
 
-	if !c.Bool("force") {
-		utils.GetLogger().Info("🚨 This will uninstall K0s and K0rdent from this system.")
-		utils.GetLogger().Info("Are you sure you want to continue? (y/N): ")
-		var response string
-		if _, err := fmt.Scanln(&response); err != nil || (response != "y" && response != "Y") {
-			utils.GetLogger().Info("Cancelled.")
-			return nil
+	if err := installer.Uninstall(inventory); err != nil {
+		return fmt.Errorf("uninstallation failed: %w", err)
+	}
+	if dryRun {
+		return writePlan(c, installer.Plan())
+	}
+
+	logger.Info("✅ K0s and K0rdent uninstalled successfully!")
+	return nil
+}
+
+// uninstallOptions returns the uninstall options of the flags
+func uninstallOptions(c *cli.Context) installer.UninstallOptions {
+	return installer.UninstallOptions{
+		KeepConfig:     c.Bool("keep-config"),
+		PurgeRegistry:  c.Bool("purge-registry"),
+		RemoveBinaries: c.Bool("remove-binaries"),
+	}
+}
+
+// uninstallK0rdent removes k0rdent from the running cluster, keeping k0s
+func uninstallK0rdent(c *cli.Context, inst *installer.Installer) error {
+	logger := utils.GetLogger()
+	for _, flag := range []string{"keep-config", "purge-registry", "remove-binaries"} {
+		if c.Bool(flag) {
+			return fmt.Errorf("--%s cannot be used with --k0rdent-only", flag)
 		}
 	}
+	dryRun := c.Bool("dry-run")
 
-	installer := installer.NewInstaller(
-		c.Bool("debug"),
-		c.Bool("dry-run"),
-	)
+	// The credentials to delete are the configured ones
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := applyTimeoutFlags(c, cfg); err != nil {
+		return err
+	}
+	inst.SetConfig(cfg)
+	creds := &cfg.K0rdent.Credentials
 
-	if err := installer.Uninstall(); err != nil {
-		return fmt.Errorf("uninstallation failed: %w", err)
+	if !dryRun {
+		logger.Info("🚨 This will remove from the cluster:")
+		if creds.HasCredentials() {
+			logger.Info("   - the cloud provider credentials of the configuration")
+		}
+		logger.Info("   - the k0rdent Management and its providers")
+		logger.Info("   - the kcm chart, also from the k0s configuration")
+		logger.Info("   - the kcm-system namespace and the k0rdent CRDs")
+		logger.Info("   K0s and the other workloads keep running.")
+	}
+	if !c.Bool("force") && !dryRun && !confirmUninstall() {
+		return nil
 	}
 
-	utils.GetLogger().Info("✅ K0s and K0rdent uninstalled successfully!")
+	if err := inst.UninstallK0rdent(creds); err != nil {
+		return fmt.Errorf("k0rdent uninstallation failed: %w", err)
+	}
+	if dryRun {
+		return writePlan(c, inst.Plan())
+	}
+
+	logger.Info("✅ K0rdent uninstalled successfully, k0s is still running!")
+	logger.Info("   Run 'k0rdentd install' to install k0rdent again.")
 	return nil
 }
+
+// confirmUninstall asks the user to confirm, and reports a cancellation
+func confirmUninstall() bool {
+	logger := utils.GetLogger()
+	logger.Info("Are you sure you want to continue? (y/N): ")
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil || (response != "y" && response != "Y") {
+		logger.Info("Cancelled.")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/internal/airgap/bundle"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/installer"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var UpgradeCommand = &cli.Command{
+	Name:      "upgrade",
+	Usage:     "Upgrade K0s and K0rdent on this controller, or on the whole cluster",
+	UsageText: "k0rdentd upgrade [options]",
+	Action:    upgradeAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "k0s-version",
+			Aliases: []string{"k"},
+			Usage:   "Target K0s version (default: k0s.version from config, online flavor only)",
+		},
+		&cli.StringFlag{
+			Name:    "k0rdent-version",
+			Aliases: []string{"r"},
+			Usage:   "Target K0rdent version (default: k0rdent.version from config, online flavor only)",
+		},
+		&cli.StringFlag{
+			Name:  "bundle-path",
+			Usage: "New airgap bundle, its images are pushed to the local registry (default: airgap.bundlePath from config)",
+		},
+		&cli.StringFlag{
+			Name:  "backup-dir",
+			Value: installer.DefaultBackupDir,
+			Usage: "Directory of the backup made before upgrading",
+		},
+		&cli.BoolFlag{
+			Name:  "cluster",
+			Usage: "Upgrade k0s on every controller and worker with a k0s autopilot plan",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Upgrade even if the versions are not known to be compatible",
+		},
+		timeoutFlag,
+		planOutputFlag,
+	},
+}
+
+func upgradeAction(c *cli.Context) error {
+	if err := validatePlanOutput(c); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := applyTimeoutFlags(c, cfg); err != nil {
+		return err
+	}
+
+	target, err := upgradeTarget(c, cfg)
+	if err != nil {
+		return err
+	}
+
+	dryRun := c.Bool("dry-run")
+	inst := installer.NewInstaller(
+		c.Bool("debug"),
+		dryRun,
+	)
+	inst.SetConfig(cfg)
+	inst.SetBackupDir(c.String("backup-dir"))
+	inst.SetForceUpgrade(c.Bool("force"))
+	if airgap.IsAirGap() {
+		inst.SetBundlePath(upgradeBundlePath(c, cfg))
+	}
+
+	upgrade := inst.Upgrade
+	if c.Bool("cluster") {
+		upgrade = inst.UpgradeCluster
+	}
+	if err := upgrade(target); err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+	if dryRun {
+		return writePlan(c, inst.Plan())
+	}
+	return nil
+}
+
+// upgradeTarget returns the versions to upgrade to. The online flavor
+// upgrades to the flags or configured versions, the airgap flavor to the
+// embedded k0s and to the k0rdent version of the bundle.
+func upgradeTarget(c *cli.Context, cfg *config.K0rdentdConfig) (installer.Versions, error) {
+	if !airgap.IsAirGap() {
+		target := installer.Versions{K0s: cfg.K0s.Version, K0rdent: cfg.K0rdent.Version}
+		if c.IsSet("k0s-version") {
+			target.K0s = c.String("k0s-version")
+		}
+		if c.IsSet("k0rdent-version") {
+			target.K0rdent = c.String("k0rdent-version")
+		}
+		return target, nil
+	}
+
+	if c.IsSet("k0s-version") || c.IsSet("k0rdent-version") {
+		return installer.Versions{}, fmt.Errorf("--k0s-version and --k0rdent-version are not supported by the airgap flavor, use the k0rdentd binary of the new bundle")
+	}
+
+	metadata := airgap.GetBuildMetadata()
+	target := installer.Versions{K0s: metadata.K0sVersion, K0rdent: metadata.K0rdentVersion}
+	if bundlePath := upgradeBundlePath(c, cfg); bundlePath != "" {
+		version, err := bundle.ExtractK0rdentVersion(bundlePath)
+		if err != nil {
+			return target, fmt.Errorf("failed to extract k0rdent version from bundle: %w", err)
+		}
+		target.K0rdent = version
+	}
+	utils.GetLogger().Infof("Airgap mode (K0s: %s, K0rdent: %s)", target.K0s, target.K0rdent)
+	return target, nil
+}
+
+// upgradeBundlePath returns the airgap bundle of the upgrade
+func upgradeBundlePath(c *cli.Context, cfg *config.K0rdentdConfig) string {
+	if c.IsSet("bundle-path") {
+		return c.String("bundle-path")
+	}
+	return cfg.Airgap.BundlePath
+}
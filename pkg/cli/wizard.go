@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/k0s"
+)
+
+// prompter asks questions on an interactive terminal
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// newPrompter creates a prompter reading answers from in
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prompts for a value, returning def if the answer is empty.
+// The question is asked again until validate accepts the answer.
+func (p *prompter) ask(question, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+
+		line, err := p.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+
+		if validate != nil {
+			if err := validate(answer); err != nil {
+				fmt.Fprintf(p.out, "  ❌ %v\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes/no question
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	defAnswer := "y/N"
+	if def {
+		defAnswer = "Y/n"
+	}
+	answer, err := p.ask(fmt.Sprintf("%s (%s)", question, defAnswer), "", func(answer string) error {
+		switch strings.ToLower(answer) {
+		case "", "y", "yes", "n", "no":
+			return nil
+		}
+		return fmt.Errorf("please answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// choose asks for one of the given options
+func (p *prompter) choose(question string, options []string, def string) (string, error) {
+	return p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, ", ")), def, oneOf(options))
+}
+
+// oneOf validates that an answer is one of the given options
+func oneOf(options []string) func(string) error {
+	return func(answer string) error {
+		for _, option := range options {
+			if answer == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of: %s", strings.Join(options, ", "))
+	}
+}
+
+// optional makes a validation accept empty answers
+func optional(validate func(string) error) func(string) error {
+	return func(answer string) error {
+		if answer == "" {
+			return nil
+		}
+		return validate(answer)
+	}
+}
+
+// all combines validations, the first failing one rejects the answer
+func all(validations ...func(string) error) func(string) error {
+	return func(answer string) error {
+		for _, validate := range validations {
+			if err := validate(answer); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// required rejects empty answers
+func required(answer string) error {
+	if answer == "" {
+		return fmt.Errorf("a value is required")
+	}
+	return nil
+}
+
+// validIP validates an IP address
+func validIP(answer string) error {
+	if net.ParseIP(answer) == nil {
+		return fmt.Errorf("%q is not a valid IP address", answer)
+	}
+	return nil
+}
+
+// validCIDR validates a network in CIDR notation
+func validCIDR(answer string) error {
+	if _, _, err := net.ParseCIDR(answer); err != nil {
+		return fmt.Errorf("%q is not a valid CIDR", answer)
+	}
+	return nil
+}
+
+// validField validates an answer against the rest of the configuration:
+// set stores the answer in cfg and the answer is rejected if Validate
+// reports a problem with field, such as overlapping networks
+func validField(cfg *config.K0rdentdConfig, field string, set func(string)) func(string) error {
+	return func(answer string) error {
+		set(answer)
+		var verrs config.ValidationErrors
+		if !errors.As(cfg.Validate(), &verrs) {
+			return nil
+		}
+		for _, verr := range verrs {
+			if verr.Field == field {
+				return errors.New(verr.Message)
+			}
+		}
+		return nil
+	}
+}
+
+// unusedName rejects credential names that are already taken
+func unusedName(creds *config.CredentialsConfig) func(string) error {
+	return func(answer string) error {
+		if slices.Contains(creds.Names(), answer) {
+			return fmt.Errorf("a credential named %q already exists", answer)
+		}
+		return nil
+	}
+}
+
+// runConfigWizard prompts for the main settings of a configuration,
+// starting from the given profile. Each answer is validated when it is
+// entered and the question asked again if it is invalid.
+func runConfigWizard(p *prompter, profile, internalIP string) (*config.K0rdentdConfig, string, error) {
+	var err error
+	if profile == "" {
+		if profile, err = p.choose("Profile", config.Profiles, config.ProfileSingleNode); err != nil {
+			return nil, "", err
+		}
+	}
+
+	cfg, err := config.ProfileConfig(profile, internalIP)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if internalIP != "" {
+		fmt.Fprintf(p.out, "Detected internal IP address: %s\n", internalIP)
+	}
+
+	if cfg.K0s.Version, err = p.ask("k0s version (empty for the installed or latest version)", cfg.K0s.Version, optional(k0s.ValidateVersion)); err != nil {
+		return nil, "", err
+	}
+	if cfg.K0rdent.Version, err = p.ask("k0rdent version", cfg.K0rdent.Version, required); err != nil {
+		return nil, "", err
+	}
+
+	if profile == config.ProfileHAController {
+		if cfg.K0s.API.Address, err = p.ask("API address", cfg.K0s.API.Address, validIP); err != nil {
+			return nil, "", err
+		}
+		cfg.K0s.Storage.Etcd.PeerAddress = cfg.K0s.API.Address
+	}
+
+	if cfg.K0s.Network.PodCIDR, err = p.ask("Pod network CIDR", cfg.K0s.Network.PodCIDR, validCIDR); err != nil {
+		return nil, "", err
+	}
+	network := &cfg.K0s.Network
+	serviceCIDR := validField(cfg, "k0s.network.serviceCIDR", func(answer string) { network.ServiceCIDR = answer })
+	if network.ServiceCIDR, err = p.ask("Service network CIDR", network.ServiceCIDR, all(validCIDR, serviceCIDR)); err != nil {
+		return nil, "", err
+	}
+
+	if ip, _, err := net.ParseCIDR(cfg.K0s.Network.PodCIDR); err == nil && ip.To4() != nil {
+		dualStack := &cfg.K0s.Network.DualStack
+		if dualStack.Enabled, err = p.confirm("Enable dual-stack (IPv4 and IPv6)?", false); err != nil {
+			return nil, "", err
+		}
+		if dualStack.Enabled {
+			ipv6PodCIDR := validField(cfg, "k0s.network.dualStack.IPv6podCIDR", func(answer string) { dualStack.IPv6PodCIDR = answer })
+			if dualStack.IPv6PodCIDR, err = p.ask("IPv6 pod network CIDR", config.DefaultIPv6PodCIDR, all(validCIDR, ipv6PodCIDR)); err != nil {
+				return nil, "", err
+			}
+			ipv6ServiceCIDR := validField(cfg, "k0s.network.dualStack.IPv6serviceCIDR", func(answer string) { dualStack.IPv6ServiceCIDR = answer })
+			if dualStack.IPv6ServiceCIDR, err = p.ask("IPv6 service network CIDR", config.DefaultIPv6ServiceCIDR, all(validCIDR, ipv6ServiceCIDR)); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	if profile == config.ProfileAirgap {
+		if cfg.Airgap.BundlePath, err = p.ask("Airgap bundle path", cfg.Airgap.BundlePath, required); err != nil {
+			return nil, "", err
+		}
+		registryAddress := validField(cfg, "airgap.registry.address", func(answer string) { cfg.Airgap.Registry.Address = answer })
+		if cfg.Airgap.Registry.Address, err = p.ask("Registry address", cfg.Airgap.Registry.Address, all(required, registryAddress)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := askCredentials(p, &cfg.K0rdent.Credentials); err != nil {
+		return nil, "", err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, "", fmt.Errorf("configuration is invalid: %w", err)
+	}
+	return cfg, profile, nil
+}
+
+// askCredentials prompts for cloud credentials until the user is done.
+// Secrets default to environment references so they don't end up in the file.
+func askCredentials(p *prompter, creds *config.CredentialsConfig) error {
+	for {
+		more, err := p.confirm("Add cloud provider credentials?", false)
+		if err != nil || !more {
+			return err
+		}
+
+		provider, err := p.choose("Provider", []string{"aws", "azure", "openstack"}, "aws")
+		if err != nil {
+			return err
+		}
+		name, err := p.ask("Credential name", provider, all(required, unusedName(creds)))
+		if err != nil {
+			return err
+		}
+
+		switch provider {
+		case "aws":
+			cred := config.AWSCredential{Name: name}
+			if cred.Region, err = p.ask("Region", "us-east-1", required); err != nil {
+				return err
+			}
+			if cred.AccessKeyID, err = p.ask("Access key ID", "${AWS_ACCESS_KEY_ID}", required); err != nil {
+				return err
+			}
+			if cred.SecretAccessKey, err = p.ask("Secret access key", "${AWS_SECRET_ACCESS_KEY}", required); err != nil {
+				return err
+			}
+			creds.AWS = append(creds.AWS, cred)
+		case "azure":
+			cred := config.AzureCredential{Name: name}
+			if cred.SubscriptionID, err = p.ask("Subscription ID", "${AZURE_SUBSCRIPTION_ID}", required); err != nil {
+				return err
+			}
+			if cred.TenantID, err = p.ask("Tenant ID", "${AZURE_TENANT_ID}", required); err != nil {
+				return err
+			}
+			if cred.ClientID, err = p.ask("Client ID", "${AZURE_CLIENT_ID}", required); err != nil {
+				return err
+			}
+			if cred.ClientSecret, err = p.ask("Client secret", "${AZURE_CLIENT_SECRET}", required); err != nil {
+				return err
+			}
+			creds.Azure = append(creds.Azure, cred)
+		case "openstack":
+			cred := config.OpenStackCredential{Name: name}
+			if cred.AuthURL, err = p.ask("Auth URL", "${OS_AUTH_URL}", required); err != nil {
+				return err
+			}
+			if cred.Region, err = p.ask("Region", "RegionOne", required); err != nil {
+				return err
+			}
+			if cred.ApplicationCredentialID, err = p.ask("Application credential ID", "${OS_APPLICATION_CREDENTIAL_ID}", required); err != nil {
+				return err
+			}
+			if cred.ApplicationCredentialSecret, err = p.ask("Application credential secret", "${OS_APPLICATION_CREDENTIAL_SECRET}", required); err != nil {
+				return err
+			}
+			creds.OpenStack = append(creds.OpenStack, cred)
+		}
+	}
+}
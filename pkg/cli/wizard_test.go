@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestRunConfigWizard(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		internalIP string
+		answers    []string
+		check      func(g *gomega.WithT, cfg *config.K0rdentdConfig)
+		output     string
+		err        string
+	}{
+		{
+			name:    "defaults",
+			answers: []string{"", "", "", "", "", "", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.K0rdent.Version).To(gomega.Equal("1.2.2"))
+				g.Expect(cfg.K0s.Network.PodCIDR).To(gomega.Equal("10.244.0.0/16"))
+				g.Expect(cfg.K0s.Network.ServiceCIDR).To(gomega.Equal("10.96.0.0/12"))
+				g.Expect(cfg.K0s.Network.DualStack.Enabled).To(gomega.BeFalse())
+			},
+		},
+		{
+			name:    "unknown profile is asked again",
+			answers: []string{"multi-node", "single-node", "", "", "", "", "", ""},
+			output:  "must be one of: single-node, ha-controller, airgap",
+		},
+		{
+			name:    "invalid k0s version is asked again",
+			profile: config.ProfileSingleNode,
+			answers: []string{"1.32", "v1.32.4+k0s.0", "", "", "", "", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.K0s.Version).To(gomega.Equal("v1.32.4+k0s.0"))
+			},
+			output: "invalid k0s version format: 1.32",
+		},
+		{
+			name:    "invalid pod network is asked again",
+			profile: config.ProfileSingleNode,
+			answers: []string{"", "", "10.244.0.0", "10.200.0.0/16", "", "", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.K0s.Network.PodCIDR).To(gomega.Equal("10.200.0.0/16"))
+			},
+			output: `"10.244.0.0" is not a valid CIDR`,
+		},
+		{
+			name:    "service network overlapping the pod network is asked again",
+			profile: config.ProfileSingleNode,
+			answers: []string{"", "", "", "10.244.0.0/24", "10.100.0.0/16", "", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.K0s.Network.ServiceCIDR).To(gomega.Equal("10.100.0.0/16"))
+			},
+			output: "10.244.0.0/24 overlaps with podCIDR 10.244.0.0/16",
+		},
+		{
+			name:    "invalid dual-stack networks are asked again",
+			profile: config.ProfileSingleNode,
+			answers: []string{"", "", "", "", "maybe", "y", "10.1.0.0/16", "", "fd00::/120", "fd02::/108", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				dualStack := cfg.K0s.Network.DualStack
+				g.Expect(dualStack.Enabled).To(gomega.BeTrue())
+				g.Expect(dualStack.IPv6PodCIDR).To(gomega.Equal(config.DefaultIPv6PodCIDR))
+				g.Expect(dualStack.IPv6ServiceCIDR).To(gomega.Equal("fd02::/108"))
+			},
+			output: "fd00::/120 overlaps with IPv6podCIDR fd00::/108",
+		},
+		{
+			name:       "ha controller without an API address is asked again",
+			profile:    config.ProfileHAController,
+			internalIP: "",
+			answers:    []string{"", "", "", "192.168.1.10", "", "", "", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.K0s.API.Address).To(gomega.Equal("192.168.1.10"))
+				g.Expect(cfg.K0s.Storage.Etcd.PeerAddress).To(gomega.Equal("192.168.1.10"))
+			},
+			output: `"" is not a valid IP address`,
+		},
+		{
+			name:    "invalid registry address is asked again",
+			profile: config.ProfileAirgap,
+			answers: []string{"", "", "", "", "", "", "registry.local", "registry.local:5000", ""},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.Airgap.Registry.Address).To(gomega.Equal("registry.local:5000"))
+			},
+			output: `"registry.local" must be in the host:port form`,
+		},
+		{
+			name:    "duplicate credential name is asked again",
+			profile: config.ProfileSingleNode,
+			answers: []string{"", "", "", "", "",
+				"y", "aws", "", "", "", "",
+				"y", "aws", "", "aws-prod", "", "", "",
+				"n"},
+			check: func(g *gomega.WithT, cfg *config.K0rdentdConfig) {
+				g.Expect(cfg.K0rdent.Credentials.Names()).To(gomega.Equal([]string{"aws", "aws-prod"}))
+				g.Expect(cfg.K0rdent.Credentials.AWS[1].SecretAccessKey).To(gomega.Equal("${AWS_SECRET_ACCESS_KEY}"))
+			},
+			output: `a credential named "aws" already exists`,
+		},
+		{
+			name:    "missing answers",
+			profile: config.ProfileSingleNode,
+			answers: []string{"", "1.32"},
+			err:     "failed to read answer: EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			var out bytes.Buffer
+			input := strings.NewReader(strings.Join(tt.answers, "\n") + "\n")
+			cfg, profile, err := runConfigWizard(newPrompter(input, &out), tt.profile, tt.internalIP)
+			if tt.err != "" {
+				g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(tt.err)))
+				return
+			}
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(cfg.Validate()).To(gomega.Succeed())
+			if tt.profile != "" {
+				g.Expect(profile).To(gomega.Equal(tt.profile))
+			}
+			if tt.check != nil {
+				tt.check(g, cfg)
+			}
+			if tt.output != "" {
+				g.Expect(out.String()).To(gomega.ContainSubstring("❌ " + tt.output))
+			}
+		})
+	}
+}
+
+func TestPrompterConfirm(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		def      bool
+		expected bool
+	}{
+		{name: "empty answer returns the default", input: "\n", def: true, expected: true},
+		{name: "yes", input: "Yes\n", expected: true},
+		{name: "no", input: "n\n", def: true, expected: false},
+		{name: "invalid answer is asked again", input: "sure\ny\n", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			var out bytes.Buffer
+			answer, err := newPrompter(strings.NewReader(tt.input), &out).confirm("Continue?", tt.def)
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(answer).To(gomega.Equal(tt.expected))
+		})
+	}
+}
@@ -146,4 +146,29 @@ func TestJoinConfigIsValid(t *testing.T) {
 			g.Expect(tt.config.IsValid()).To(gomega.Equal(tt.expected))
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestK0sConfigStableAPIAddress(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	cplb := config.ControlPlaneLoadBalancingConfig{
+		Enabled: true,
+		Keepalived: config.KeepalivedConfig{
+			VRRPInstances: []config.VRRPInstance{{VirtualIPs: []string{"10.0.0.100/24"}, AuthPass: "secret"}},
+		},
+	}
+
+	g.Expect(config.K0sConfig{}.StableAPIAddress()).To(gomega.BeEmpty())
+	g.Expect(config.K0sConfig{
+		Network: config.NetworkConfig{ControlPlaneLoadBalancing: cplb},
+	}.StableAPIAddress()).To(gomega.Equal("10.0.0.100"))
+	g.Expect(config.K0sConfig{
+		API:     config.APIConfig{ExternalAddress: "api.example.com"},
+		Network: config.NetworkConfig{ControlPlaneLoadBalancing: cplb},
+	}.StableAPIAddress()).To(gomega.Equal("api.example.com"))
+
+	cplb.Enabled = false
+	g.Expect(config.K0sConfig{
+		Network: config.NetworkConfig{ControlPlaneLoadBalancing: cplb},
+	}.StableAPIAddress()).To(gomega.BeEmpty())
+}
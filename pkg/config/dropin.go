@@ -0,0 +1,280 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DropInDirName is the directory, next to the default configuration
+	// file, holding drop-in files merged over it (/etc/k0rdentd/config.d)
+	DropInDirName = "config.d"
+
+	// DefaultsSource is the source reported for built-in default values
+	DefaultsSource = "defaults"
+)
+
+// ValueSource tells which file an effective configuration value comes from
+type ValueSource struct {
+	// Path is the YAML path of the value (e.g. k0rdent.credentials.aws[0].region)
+	Path string
+	// File is the file the value was read from, or DefaultsSource
+	File string
+	// Line is the line of the value in File, 0 for default values
+	Line int
+}
+
+// String returns the location of the value as file:line
+func (s ValueSource) String() string {
+	if s.Line == 0 {
+		return s.File
+	}
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// dropInFiles returns the drop-in files of dir in lexical order
+func dropInFiles(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list drop-in files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadLayers loads the main configuration file (or the built-in defaults if
+// path is empty), merges the drop-in files of dropInDir over it and returns
+// the decoded configuration with the source of every value
+func loadLayers(path, dropInDir string, opts LoadOptions) (*K0rdentdConfig, []ValueSource, error) {
+	files, err := dropInFiles(dropInDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	origins := make(map[*yaml.Node]string)
+	var merged *yaml.Node
+	if path == "" {
+		if merged, err = defaultsLayer(); err != nil {
+			return nil, nil, err
+		}
+		markOrigin(merged, DefaultsSource, origins)
+	} else {
+		files = append([]string{path}, files...)
+	}
+
+	for idx, file := range files {
+		// Drop-ins are fragments of the main file, they may omit apiVersion
+		fragment := path == "" || idx > 0
+		layer, err := readLayer(file, fragment, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if layer == nil {
+			continue
+		}
+		markOrigin(layer, file, origins)
+		if merged == nil {
+			merged = layer
+			continue
+		}
+		utils.GetLogger().Debugf("Merging drop-in configuration %s", file)
+		mergeNodes(merged, layer, "")
+	}
+
+	// An empty file keeps the zero configuration
+	var cfg K0rdentdConfig
+	if merged != nil {
+		decode := decodeStrict
+		if opts.Lenient {
+			decode = func(root *yaml.Node, cfg *K0rdentdConfig) error { return root.Decode(cfg) }
+		}
+		if err := decode(merged, &cfg); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+	}
+
+	// Replace secret references with their actual values
+	if err := resolveSecretRefs(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve secret references: %w", err)
+	}
+
+	// Set defaults if not provided
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+
+	var sources []ValueSource
+	if merged != nil {
+		collectSources(merged, "", origins, &sources)
+	}
+	return &cfg, sources, nil
+}
+
+// readLayer reads a configuration file, converts it to CurrentAPIVersion and
+// checks its keys. It returns the top-level mapping, or nil for an empty file.
+// Fragments without apiVersion are assumed to be at CurrentAPIVersion.
+func readLayer(path string, fragment bool, opts LoadOptions) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+	}
+	if root.Kind == 0 {
+		return nil, nil
+	}
+
+	doc := documentMapping(&root)
+	if doc == nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: configuration must be a mapping", path)
+	}
+
+	// Older files are converted in memory, the file itself is left untouched
+	if !fragment || mappingValue(doc, "apiVersion") != nil {
+		fromVersion, err := migrateNode(&root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s: %w", path, err)
+		}
+		if fromVersion != CurrentAPIVersion {
+			utils.GetLogger().Warnf("⚠️  %s uses an outdated configuration schema, run 'k0rdentd config migrate' to update it to %s", path, CurrentAPIVersion)
+		}
+	}
+
+	// Files are checked one by one so positions refer to the right file
+	if !opts.Lenient {
+		if unknown := findUnknownFields(doc, reflect.TypeOf(K0rdentdConfig{})); len(unknown) > 0 {
+			return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, unknown)
+		}
+	}
+	return doc, nil
+}
+
+// defaultsLayer returns the built-in default configuration as a YAML mapping
+func defaultsLayer() (*yaml.Node, error) {
+	var root yaml.Node
+	if err := root.Encode(DefaultConfig()); err != nil {
+		return nil, fmt.Errorf("failed to encode default config: %w", err)
+	}
+	return &root, nil
+}
+
+// markOrigin records file as the origin of node and all its children
+func markOrigin(node *yaml.Node, file string, origins map[*yaml.Node]string) {
+	origins[node] = file
+	for _, child := range node.Content {
+		markOrigin(child, file, origins)
+	}
+}
+
+// namedLists are the lists merged by name, the credentials of each provider
+var namedLists = map[string]bool{
+	"k0rdent.credentials.aws":       true,
+	"k0rdent.credentials.azure":     true,
+	"k0rdent.credentials.openstack": true,
+}
+
+// mergeNodes deep-merges the src mapping, found at path, into dst:
+//   - mappings are merged key by key
+//   - the credentials lists are merged by name, items with a new name are
+//     appended
+//   - any other value of src, including other lists, replaces the value of dst
+func mergeNodes(dst, src *yaml.Node, path string) {
+	for idx := 0; idx+1 < len(src.Content); idx += 2 {
+		key, value := src.Content[idx], src.Content[idx+1]
+		keyPath := joinPath(path, key.Value)
+
+		existingIdx := mappingIndex(dst, key.Value)
+		if existingIdx < 0 {
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+
+		existing := dst.Content[existingIdx+1]
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value, keyPath)
+		case namedLists[keyPath] && isNamedList(existing) && isNamedList(value):
+			mergeNamedLists(existing, value, keyPath)
+		default:
+			dst.Content[existingIdx+1] = value
+		}
+	}
+}
+
+// mergeNamedLists merges the items of src, found at path, into dst by name
+func mergeNamedLists(dst, src *yaml.Node, path string) {
+	for _, item := range src.Content {
+		name := mappingValue(item, "name").Value
+		merged := false
+		for _, existing := range dst.Content {
+			if mappingValue(existing, "name").Value == name {
+				mergeNodes(existing, item, path)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			dst.Content = append(dst.Content, item)
+		}
+	}
+}
+
+// isNamedList returns true if node is a list whose items are all mappings
+// with a scalar name key
+func isNamedList(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+		name := mappingValue(item, "name")
+		if name == nil || name.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+// mappingIndex returns the index of key in a mapping node, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for idx := 0; idx+1 < len(mapping.Content); idx += 2 {
+		if mapping.Content[idx].Value == key {
+			return idx
+		}
+	}
+	return -1
+}
+
+// collectSources appends the source of every value below node, in document order
+func collectSources(node *yaml.Node, path string, origins map[*yaml.Node]string, sources *[]ValueSource) {
+	switch {
+	case node.Kind == yaml.MappingNode && len(node.Content) > 0:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			collectSources(node.Content[idx+1], joinPath(path, node.Content[idx].Value), origins, sources)
+		}
+	case node.Kind == yaml.SequenceNode && len(node.Content) > 0:
+		for idx, item := range node.Content {
+			collectSources(item, fmt.Sprintf("%s[%d]", path, idx), origins, sources)
+		}
+	default:
+		source := ValueSource{Path: path, File: origins[node]}
+		if source.File != DefaultsSource {
+			source.Line = node.Line
+		}
+		*sources = append(*sources, source)
+	}
+}
@@ -0,0 +1,196 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+// writeDropIns writes the main config file and its drop-in files to a
+// temporary directory and returns the main config file path
+func writeDropIns(t *testing.T, main string, dropIns map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "k0rdentd.yaml")
+	if err := os.WriteFile(path, []byte(main), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, config.DropInDirName), 0755); err != nil {
+		t.Fatalf("failed to create drop-in directory: %v", err)
+	}
+	for name, content := range dropIns {
+		if err := os.WriteFile(filepath.Join(dir, config.DropInDirName, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write drop-in file: %v", err)
+		}
+	}
+	return path
+}
+
+func TestLoadConfigDropIns(t *testing.T) {
+	main := `apiVersion: k0rdentd.io/v1alpha1
+kind: K0rdentdConfig
+k0s:
+  version: v1.32.4+k0s.0
+k0rdent:
+  helm:
+    values:
+      replicaCount: 1
+      k0rdent-ui:
+        enabled: true
+  credentials:
+    aws:
+      - name: prod
+        region: us-east-1
+        accessKeyID: AKIA
+        secretAccessKey: secret
+`
+
+	t.Run("drop-ins are deep-merged in lexical order", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeDropIns(t, main, map[string]string{
+			"20-credentials.yaml": `k0rdent:
+  credentials:
+    aws:
+      - name: prod
+        region: eu-west-1
+      - name: dev
+        region: us-east-2
+        accessKeyID: AKIB
+        secretAccessKey: secret
+`,
+			"10-version.yaml": `k0s:
+  version: v1.32.5+k0s.0
+k0rdent:
+  helm:
+    values:
+      replicaCount: 3
+`,
+			"30-version.yaml": `k0s:
+  version: v1.32.6+k0s.0
+`,
+			"ignored.yml": `k0s:
+  version: v1.0.0+k0s.0
+`,
+		})
+
+		cfg, err := config.LoadConfigWithFallback(path, path, true, config.LoadOptions{})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.Version).To(gomega.Equal("v1.32.6+k0s.0"))
+		g.Expect(cfg.K0rdent.Helm.Values).To(gomega.HaveKeyWithValue("replicaCount", 3))
+		g.Expect(cfg.K0rdent.Helm.Values).To(gomega.HaveKey("k0rdent-ui"))
+		g.Expect(cfg.K0rdent.Credentials.AWS).To(gomega.Equal([]config.AWSCredential{
+			{Name: "prod", Region: "eu-west-1", AccessKeyID: "AKIA", SecretAccessKey: "secret"},
+			{Name: "dev", Region: "us-east-2", AccessKeyID: "AKIB", SecretAccessKey: "secret"},
+		}))
+	})
+
+	t.Run("only the credentials lists are merged by name", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeDropIns(t, main, map[string]string{
+			"10-env.yaml": `k0rdent:
+  helm:
+    values:
+      env:
+        - name: PROXY
+          value: http://proxy:3128
+        - name: DEBUG
+          value: "false"
+`,
+			"20-env.yaml": `k0rdent:
+  helm:
+    values:
+      env:
+        - name: DEBUG
+          value: "true"
+`,
+		})
+
+		cfg, err := config.LoadConfigWithFallback(path, path, true, config.LoadOptions{})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0rdent.Helm.Values).To(gomega.HaveKeyWithValue("env", []interface{}{
+			map[string]interface{}{"name": "DEBUG", "value": "true"},
+		}))
+	})
+
+	t.Run("sources tell where each value comes from", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeDropIns(t, main, map[string]string{
+			"10-aws.yaml": `k0rdent:
+  credentials:
+    aws:
+      - name: prod
+        region: eu-west-1
+`,
+		})
+		dropIn := filepath.Join(filepath.Dir(path), config.DropInDirName, "10-aws.yaml")
+
+		_, sources, err := config.LoadConfigWithSources(path, path, true, config.LoadOptions{})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(sources).To(gomega.ContainElements(
+			config.ValueSource{Path: "k0s.version", File: path, Line: 4},
+			config.ValueSource{Path: "k0rdent.credentials.aws[0].region", File: dropIn, Line: 5},
+			config.ValueSource{Path: "k0rdent.credentials.aws[0].accessKeyID", File: path, Line: 15},
+		))
+	})
+
+	t.Run("unknown fields in drop-ins are reported with their file", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeDropIns(t, main, map[string]string{
+			"10-typo.yaml": "k0s:\n  verison: v1.32.5+k0s.0\n",
+		})
+
+		_, err := config.LoadConfigWithFallback(path, path, true, config.LoadOptions{})
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("10-typo.yaml")))
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown field "k0s.verison"`)))
+	})
+
+	t.Run("drop-ins next to an explicit file elsewhere are ignored", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeDropIns(t, main, map[string]string{
+			"10-version.yaml": "k0s:\n  version: v1.32.5+k0s.0\n",
+		})
+		defaultPath := writeDropIns(t, "", map[string]string{
+			"10-version.yaml": "k0s:\n  version: v1.32.6+k0s.0\n",
+		})
+
+		cfg, err := config.LoadConfigWithFallback(path, defaultPath, true, config.LoadOptions{})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.Version).To(gomega.Equal("v1.32.6+k0s.0"))
+	})
+
+	t.Run("drop-ins apply over defaults without main file", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeDropIns(t, "", map[string]string{
+			"10-version.yaml": "k0s:\n  version: v1.32.5+k0s.0\n",
+		})
+		g.Expect(os.Remove(path)).To(gomega.Succeed())
+
+		cfg, sources, err := config.LoadConfigWithSources("", path, false, config.LoadOptions{})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.Version).To(gomega.Equal("v1.32.5+k0s.0"))
+		g.Expect(cfg.K0rdent.Helm.Namespace).To(gomega.Equal(config.DefaultConfig().K0rdent.Helm.Namespace))
+		g.Expect(sources).To(gomega.ContainElement(
+			config.ValueSource{Path: "k0rdent.helm.namespace", File: config.DefaultsSource},
+		))
+	})
+}
+
+func TestLoadConfigWithFallbackDefaults(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	cfg, err := config.LoadConfigWithFallback("", filepath.Join(t.TempDir(), "k0rdentd.yaml"), false, config.LoadOptions{})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	expected := config.DefaultConfig()
+	expected.LogLevel = "info"
+	g.Expect(cfg).To(gomega.Equal(expected))
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxHelmListIndex bounds list indexes of --set expressions, like Helm does
+const maxHelmListIndex = 65536
+
+// helmKeySegment matches a key segment with optional list indexes, e.g. hosts[0]
+var helmKeySegment = regexp.MustCompile(`^(.*?)((?:\[\d+\])*)$`)
+
+// helmPathStep is a step of a --set key: a map key or a list index
+type helmPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// SetHelmValues applies a Helm --set style expression, such as
+// "a.b=1,list[0].name=x,tags={a,b}", to values. Values are typed like Helm
+// does: integers, booleans and null are converted, anything else is a string.
+func SetHelmValues(values map[string]interface{}, expr string) error {
+	return setHelmValues(values, expr, func(value string) (interface{}, error) {
+		return typedHelmValue(value), nil
+	})
+}
+
+// SetHelmFileValues applies a Helm --set-file style expression, such as
+// "a.b=/path/to/file", setting each key to the content of its file
+func SetHelmFileValues(values map[string]interface{}, expr string) error {
+	return setHelmValues(values, expr, func(value string) (interface{}, error) {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", value, err)
+		}
+		return string(data), nil
+	})
+}
+
+// setHelmValues parses the comma-separated assignments of expr and sets
+// each value, converted by convert, in values
+func setHelmValues(values map[string]interface{}, expr string, convert func(string) (interface{}, error)) error {
+	for _, assignment := range splitUnescaped(expr, ',', true) {
+		parts := splitUnescaped(assignment, '=', false)
+		if len(parts) < 2 {
+			return fmt.Errorf("invalid value %q: expected key=value", assignment)
+		}
+		rawValue := strings.Join(parts[1:], "=")
+
+		steps, err := parseHelmKey(parts[0])
+		if err != nil {
+			return err
+		}
+
+		var value interface{}
+		if strings.HasPrefix(rawValue, "{") && strings.HasSuffix(rawValue, "}") {
+			var list []interface{}
+			for _, item := range splitUnescaped(rawValue[1:len(rawValue)-1], ',', false) {
+				converted, err := convert(unescapeHelm(item))
+				if err != nil {
+					return err
+				}
+				list = append(list, converted)
+			}
+			value = list
+		} else if value, err = convert(unescapeHelm(rawValue)); err != nil {
+			return err
+		}
+
+		if _, err := setHelmPath(values, steps, value); err != nil {
+			return fmt.Errorf("invalid key %q: %w", parts[0], err)
+		}
+	}
+	return nil
+}
+
+// parseHelmKey parses a dotted key with optional list indexes
+func parseHelmKey(key string) ([]helmPathStep, error) {
+	var steps []helmPathStep
+	for _, segment := range splitUnescaped(key, '.', false) {
+		match := helmKeySegment.FindStringSubmatch(segment)
+		name := unescapeHelm(match[1])
+		if name == "" {
+			return nil, fmt.Errorf("invalid key %q: empty key segment", key)
+		}
+		steps = append(steps, helmPathStep{key: name})
+
+		for _, index := range strings.FieldsFunc(match[2], func(r rune) bool { return r == '[' || r == ']' }) {
+			idx, err := strconv.Atoi(index)
+			if err != nil || idx > maxHelmListIndex {
+				return nil, fmt.Errorf("invalid key %q: index %s out of range", key, index)
+			}
+			steps = append(steps, helmPathStep{index: idx, isIndex: true})
+		}
+	}
+	return steps, nil
+}
+
+// setHelmPath sets value at the path below node, creating maps and
+// lists as needed, and returns the updated node
+func setHelmPath(node interface{}, steps []helmPathStep, value interface{}) (interface{}, error) {
+	if len(steps) == 0 {
+		return value, nil
+	}
+	step := steps[0]
+
+	if step.isIndex {
+		list, ok := node.([]interface{})
+		if !ok && node != nil {
+			return nil, fmt.Errorf("cannot index a %T", node)
+		}
+		for len(list) <= step.index {
+			list = append(list, nil)
+		}
+		item, err := setHelmPath(list[step.index], steps[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		list[step.index] = item
+		return list, nil
+	}
+
+	values, ok := node.(map[string]interface{})
+	if !ok {
+		values = make(map[string]interface{})
+	}
+	item, err := setHelmPath(values[step.key], steps[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	values[step.key] = item
+	return values, nil
+}
+
+// typedHelmValue converts a --set value to an integer, boolean or null
+// when it looks like one
+func typedHelmValue(value string) interface{} {
+	switch strings.ToLower(value) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	// Keep values with leading zeros, such as "0755", as strings
+	if value == "0" {
+		return int64(0)
+	}
+	if !strings.HasPrefix(value, "0") {
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	}
+	return value
+}
+
+// splitUnescaped splits s on sep, except for backslash-escaped separators
+// and, when braces is set, separators inside braces. Escapes are kept.
+func splitUnescaped(s string, sep byte, braces bool) []string {
+	var parts []string
+	depth, start := 0, 0
+	for idx := 0; idx < len(s); idx++ {
+		switch {
+		case s[idx] == '\\':
+			idx++
+		case braces && s[idx] == '{':
+			depth++
+		case braces && s[idx] == '}' && depth > 0:
+			depth--
+		case s[idx] == sep && depth == 0:
+			parts = append(parts, s[start:idx])
+			start = idx + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeHelm removes the backslashes escaping characters
+func unescapeHelm(s string) string {
+	var sb strings.Builder
+	for idx := 0; idx < len(s); idx++ {
+		if s[idx] == '\\' && idx+1 < len(s) {
+			idx++
+		}
+		sb.WriteByte(s[idx])
+	}
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestSetHelmValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  map[string]interface{}
+		expr     string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "nested keys are typed like helm",
+			expr:     "a.b=1,a.c=true,a.d=null,a.e=0755,a.f=text",
+			expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1), "c": true, "d": nil, "e": "0755", "f": "text"}},
+		},
+		{
+			name:     "existing values are deep-merged",
+			initial:  map[string]interface{}{"a": map[string]interface{}{"keep": "yes", "b": 1}},
+			expr:     "a.b=2",
+			expected: map[string]interface{}{"a": map[string]interface{}{"keep": "yes", "b": int64(2)}},
+		},
+		{
+			name: "list indexes and list values",
+			expr: "hosts[1].name=example.com,tags={a,b}",
+			expected: map[string]interface{}{
+				"hosts": []interface{}{nil, map[string]interface{}{"name": "example.com"}},
+				"tags":  []interface{}{"a", "b"},
+			},
+		},
+		{
+			name:     "escaped separators",
+			expr:     `annotations.kubernetes\.io/role=a\,b,url=http://x?a=b`,
+			expected: map[string]interface{}{"annotations": map[string]interface{}{"kubernetes.io/role": "a,b"}, "url": "http://x?a=b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			values := tt.initial
+			if values == nil {
+				values = make(map[string]interface{})
+			}
+			g.Expect(config.SetHelmValues(values, tt.expr)).To(gomega.Succeed())
+			g.Expect(values).To(gomega.Equal(tt.expected))
+		})
+	}
+
+	t.Run("invalid expressions are rejected", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		values := make(map[string]interface{})
+		g.Expect(config.SetHelmValues(values, "novalue")).ToNot(gomega.Succeed())
+		g.Expect(config.SetHelmValues(values, "a..b=c")).ToNot(gomega.Succeed())
+		g.Expect(config.SetHelmValues(values, "a[99999999]=c")).ToNot(gomega.Succeed())
+	})
+}
+
+func TestSetHelmFileValues(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	g.Expect(os.WriteFile(path, []byte("-----BEGIN CERTIFICATE-----\n"), 0600)).To(gomega.Succeed())
+
+	values := make(map[string]interface{})
+	g.Expect(config.SetHelmFileValues(values, "tls.ca="+path)).To(gomega.Succeed())
+	g.Expect(values).To(gomega.Equal(map[string]interface{}{
+		"tls": map[string]interface{}{"ca": "-----BEGIN CERTIFICATE-----\n"},
+	}))
+
+	g.Expect(config.SetHelmFileValues(values, "tls.ca=/does/not/exist")).ToNot(gomega.Succeed())
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersionV1Alpha1 is the first versioned configuration schema
+	APIVersionV1Alpha1 = "k0rdentd.io/v1alpha1"
+
+	// CurrentAPIVersion is the configuration schema version produced by this release
+	CurrentAPIVersion = APIVersionV1Alpha1
+
+	// Kind is the kind of k0rdentd configuration documents
+	Kind = "K0rdentdConfig"
+
+	// unversionedAPIVersion identifies configuration files written before
+	// apiVersion was introduced
+	unversionedAPIVersion = ""
+)
+
+// Conversion upgrades a configuration document from one schema version to the next
+type Conversion struct {
+	// From is the schema version the conversion applies to
+	From string
+	// To is the schema version produced by the conversion
+	To string
+	// Convert rewrites the document mapping in place. apiVersion is updated by
+	// the caller once the conversion succeeds.
+	Convert func(doc *yaml.Node) error
+}
+
+// conversions lists every supported schema upgrade, oldest first.
+// When the configuration structure changes, add a new schema version and
+// register the conversion from the previous one here.
+var conversions = []Conversion{
+	{
+		From:    unversionedAPIVersion,
+		To:      APIVersionV1Alpha1,
+		Convert: convertUnversionedToV1Alpha1,
+	},
+}
+
+// convertUnversionedToV1Alpha1 converts a legacy configuration file.
+// v1alpha1 has the same structure as unversioned files, only kind is added.
+func convertUnversionedToV1Alpha1(doc *yaml.Node) error {
+	setMappingValue(doc, "kind", Kind)
+	return nil
+}
+
+// MigrateConfig converts a configuration document to CurrentAPIVersion,
+// keeping comments where possible. It returns the migrated document and the
+// schema version it was converted from. If the document is already at the
+// current version, it is returned unchanged.
+func MigrateConfig(data []byte) ([]byte, string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, "", fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	fromVersion, err := migrateNode(&root)
+	if err != nil {
+		return nil, "", err
+	}
+	if fromVersion == CurrentAPIVersion {
+		return data, fromVersion, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, "", fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+
+	return buf.Bytes(), fromVersion, nil
+}
+
+// migrateNode converts a parsed configuration document in place to
+// CurrentAPIVersion and returns the version it was converted from
+func migrateNode(root *yaml.Node) (string, error) {
+	doc := documentMapping(root)
+	if doc == nil {
+		// Empty document, nothing to convert
+		return CurrentAPIVersion, nil
+	}
+
+	if kind := mappingValue(doc, "kind"); kind != nil && kind.Value != Kind {
+		return "", fmt.Errorf("unsupported kind %q (expected %s)", kind.Value, Kind)
+	}
+
+	fromVersion := unversionedAPIVersion
+	if apiVersion := mappingValue(doc, "apiVersion"); apiVersion != nil {
+		fromVersion = apiVersion.Value
+	}
+
+	version := fromVersion
+	for version != CurrentAPIVersion {
+		conversion, ok := findConversion(version)
+		if !ok {
+			return "", fmt.Errorf("unsupported apiVersion %q (supported: %s)", version, CurrentAPIVersion)
+		}
+		if err := conversion.Convert(doc); err != nil {
+			return "", fmt.Errorf("failed to convert config from %q to %s: %w", version, conversion.To, err)
+		}
+		setMappingValue(doc, "apiVersion", conversion.To)
+		version = conversion.To
+	}
+
+	return fromVersion, nil
+}
+
+// findConversion returns the conversion that applies to the given version
+func findConversion(version string) (Conversion, bool) {
+	for _, conversion := range conversions {
+		if conversion.From == version {
+			return conversion, true
+		}
+	}
+	return Conversion{}, false
+}
+
+// documentMapping returns the top-level mapping of a parsed document,
+// or nil if the document is empty
+func documentMapping(root *yaml.Node) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	return node
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for idx := 0; idx+1 < len(mapping.Content); idx += 2 {
+		if mapping.Content[idx].Value == key {
+			return mapping.Content[idx+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets a scalar value in a mapping node. New keys are
+// inserted before the existing ones so apiVersion and kind stay on top.
+func setMappingValue(mapping *yaml.Node, key, value string) {
+	if existing := mappingValue(mapping, key); existing != nil {
+		existing.Kind = yaml.ScalarNode
+		existing.Tag = "!!str"
+		existing.Value = value
+		return
+	}
+
+	insertAt := 0
+	// Keep apiVersion before kind
+	if key == "kind" && len(mapping.Content) > 0 && mapping.Content[0].Value == "apiVersion" {
+		insertAt = 2
+	}
+	nodes := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}
+	mapping.Content = append(mapping.Content[:insertAt], append(nodes, mapping.Content[insertAt:]...)...)
+}
@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	t.Run("unversioned config gets apiVersion and kind", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		migrated, fromVersion, err := config.MigrateConfig([]byte(`# k0rdentd configuration
+k0s:
+  version: v1.32.4+k0s.0 # pinned
+k0rdent:
+  version: 1.2.2
+`))
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(fromVersion).To(gomega.BeEmpty())
+		g.Expect(string(migrated)).To(gomega.HavePrefix("apiVersion: k0rdentd.io/v1alpha1\nkind: K0rdentdConfig\n"))
+		g.Expect(string(migrated)).To(gomega.ContainSubstring("# k0rdentd configuration"))
+		g.Expect(string(migrated)).To(gomega.ContainSubstring("version: v1.32.4+k0s.0 # pinned"))
+	})
+
+	t.Run("current config is returned unchanged", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		data := []byte("apiVersion: k0rdentd.io/v1alpha1\nkind: K0rdentdConfig\nk0s:\n    version: v1.32.4+k0s.0\n")
+		migrated, fromVersion, err := config.MigrateConfig(data)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(fromVersion).To(gomega.Equal(config.CurrentAPIVersion))
+		g.Expect(migrated).To(gomega.Equal(data))
+	})
+
+	t.Run("unknown apiVersion is rejected", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		_, _, err := config.MigrateConfig([]byte("apiVersion: k0rdentd.io/v9\n"))
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unsupported apiVersion "k0rdentd.io/v9"`)))
+	})
+
+	t.Run("unknown kind is rejected", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		_, _, err := config.MigrateConfig([]byte("apiVersion: k0rdentd.io/v1alpha1\nkind: Cluster\n"))
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unsupported kind "Cluster"`)))
+	})
+}
+
+func TestLoadConfigVersioned(t *testing.T) {
+	t.Run("unversioned config is converted on load", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `
+k0s:
+  version: v1.32.4+k0s.0
+`)
+		cfg, err := config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.APIVersion).To(gomega.Equal(config.CurrentAPIVersion))
+		g.Expect(cfg.Kind).To(gomega.Equal(config.Kind))
+		g.Expect(cfg.K0s.Version).To(gomega.Equal("v1.32.4+k0s.0"))
+	})
+
+	t.Run("versioned config loads", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `apiVersion: k0rdentd.io/v1alpha1
+kind: K0rdentdConfig
+k0s:
+  version: v1.32.4+k0s.0
+`)
+		cfg, err := config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.APIVersion).To(gomega.Equal(config.CurrentAPIVersion))
+	})
+
+	t.Run("empty config loads", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		cfg, err := config.LoadConfig(writeConfigFile(t, ""))
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.LogLevel).To(gomega.Equal("info"))
+	})
+}
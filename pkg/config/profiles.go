@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProfileSingleNode is a single controller+worker node
+	ProfileSingleNode = "single-node"
+	// ProfileHAController is the first controller of a highly available control plane
+	ProfileHAController = "ha-controller"
+	// ProfileAirgap is a single node installed from an airgap bundle
+	ProfileAirgap = "airgap"
+)
+
+// Default IPv6 networks, for IPv6-only and dual-stack clusters
+const (
+	DefaultIPv6PodCIDR     = "fd00::/108"
+	DefaultIPv6ServiceCIDR = "fd01::/108"
+)
+
+// Profiles lists the topologies supported by ProfileConfig
+var Profiles = []string{ProfileSingleNode, ProfileHAController, ProfileAirgap}
+
+// profileHeaders describe each profile at the top of the generated file
+var profileHeaders = map[string]string{
+	ProfileSingleNode: `k0rdentd configuration - single-node profile
+A single node runs both the k0s control plane and workloads.
+Install with: sudo k0rdentd install`,
+	ProfileHAController: `k0rdentd configuration - ha-controller profile
+First controller of a highly available control plane.
+Install with: sudo k0rdentd install
+Then add controllers and workers with: sudo k0rdentd export-join-config`,
+	ProfileAirgap: `k0rdentd configuration - airgap profile
+Installs k0s and k0rdent without internet access, from an airgap bundle.
+Start the registry first: sudo k0rdentd registry --bundle-path <bundle>
+Then install with: sudo k0rdentd install`,
+}
+
+// fieldComments document fields in generated configuration files, keyed by
+// YAML path without list indexes
+var fieldComments = map[string]string{
+	"k0s":                                   "K0s configuration. Set version to pin k0s (e.g. v1.32.4+k0s.0), the installed or latest version is used otherwise",
+	"k0s.api.address":                       "Address other nodes use to reach the Kubernetes API",
+	"k0s.network.provider":                  "CNI provider: kuberouter, calico or custom",
+	"k0s.network.podCIDR":                   "Pod network, must not overlap with the service network or the host networks",
+	"k0s.network.serviceCIDR":               "Service network",
+	"k0s.network.dualStack":                 "Dual-stack: IPv6 networks added to the IPv4 podCIDR and serviceCIDR",
+	"k0s.api.externalAddress":               "Stable address of the API, such as a load balancer or the controllers VIP, used by joining nodes",
+	"k0s.api.sans":                          "Additional addresses of the API server certificate",
+	"k0s.network.controlPlaneLoadBalancing": "Keepalived VIP shared by the controllers",
+	"k0s.network.nodeLocalLoadBalancing":    "Workers reach all controllers through a local proxy",
+	"k0s.storage.type":                      "Cluster state storage: etcd or kine",
+	"k0s.storage.etcd.peerAddress":          "Address other controllers use to reach this etcd member",
+	"k0s.spec":                              "Raw k0s ClusterConfig spec, merged over the generated one",
+	"k0s.extensions.helm":                   "Additional helm repositories and charts installed next to k0rdent",
+	"k0rdent":                               "K0rdent configuration",
+	"k0rdent.helm.values":                   "Values passed to the k0rdent helm chart",
+	"k0rdent.credentials":                   "Cloud provider credentials. Secrets accept env:NAME, file:/path and ${NAME} references",
+	"airgap.bundlePath":                     "Airgap bundle, as a tar.gz archive or an extracted directory",
+	"airgap.registry.address":               "Local OCI registry serving the bundle images",
+	"airgap.registry.insecure":              "Use HTTP to reach the registry",
+}
+
+// ProfileConfig returns the starting configuration of a profile.
+// internalIP is the address of this node, used by multi-node profiles; it
+// may be empty if it could not be detected.
+func ProfileConfig(profile, internalIP string) (*K0rdentdConfig, error) {
+	cfg := DefaultConfig()
+	cfg.K0s.Network = NetworkConfig{
+		Provider:    "kuberouter",
+		PodCIDR:     "10.244.0.0/16",
+		ServiceCIDR: "10.96.0.0/12",
+	}
+	// Nodes with an IPv6 address only get an IPv6-only cluster
+	if ip := net.ParseIP(internalIP); ip != nil && ip.To4() == nil {
+		cfg.K0s.Network.PodCIDR = DefaultIPv6PodCIDR
+		cfg.K0s.Network.ServiceCIDR = DefaultIPv6ServiceCIDR
+	}
+
+	switch profile {
+	case ProfileSingleNode:
+		// The defaults fit a single node
+	case ProfileHAController:
+		cfg.K0s.API.Address = internalIP
+		cfg.K0s.Storage = StorageConfig{
+			Type: "etcd",
+			Etcd: EtcdConfig{PeerAddress: internalIP},
+		}
+	case ProfileAirgap:
+		cfg.Airgap = AirgapConfig{
+			BundlePath: "/opt/k0rdent/airgap-bundle-" + cfg.K0rdent.Version + ".tar.gz",
+			Registry: RegistryConfig{
+				Address:  "localhost:5000",
+				Insecure: true,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unknown profile %q (expected one of: %s)", profile, strings.Join(Profiles, ", "))
+	}
+	return cfg, nil
+}
+
+// MarshalCommentedConfig marshals configuration to YAML, leaving out empty
+// values and documenting fields with comments. The profile, if known, is
+// described at the top of the file.
+func MarshalCommentedConfig(cfg *K0rdentdConfig, profile string) ([]byte, error) {
+	var root yaml.Node
+	if err := root.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	pruneEmpty(&root, "")
+	commentFields(&root, "")
+	if header, ok := profileHeaders[profile]; ok {
+		root.HeadComment = header
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// pruneEmpty removes empty scalars and collections from mappings, except in
+// free-form helm values. It returns true if node itself is empty.
+func pruneEmpty(node *yaml.Node, path string) bool {
+	if path == "k0rdent.helm.values" || path == "k0s.extensions.helm.charts.values" {
+		return len(node.Content) == 0
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			if !pruneEmpty(node.Content[idx+1], joinPath(path, node.Content[idx].Value)) {
+				content = append(content, node.Content[idx], node.Content[idx+1])
+			}
+		}
+		node.Content = content
+		return len(content) == 0
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			pruneEmpty(item, path)
+		}
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		return node.Value == "" || node.Value == "0" || node.Value == "false" || node.Tag == "!!null"
+	}
+	return false
+}
+
+// commentFields attaches fieldComments to the keys of a mapping
+func commentFields(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			keyPath := joinPath(path, node.Content[idx].Value)
+			if comment, ok := fieldComments[keyPath]; ok {
+				node.Content[idx].HeadComment = comment
+			}
+			commentFields(node.Content[idx+1], keyPath)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			commentFields(item, path)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestProfileConfig(t *testing.T) {
+	for _, profile := range config.Profiles {
+		t.Run(profile, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			cfg, err := config.ProfileConfig(profile, "10.0.0.5")
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(cfg.Validate()).To(gomega.Succeed())
+
+			data, err := config.MarshalCommentedConfig(cfg, profile)
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(string(data)).To(gomega.HavePrefix("# k0rdentd configuration - " + profile + " profile"))
+			g.Expect(string(data)).To(gomega.ContainSubstring("# CNI provider: kuberouter, calico or custom"))
+
+			// The generated file loads back to the same configuration
+			loaded, err := config.LoadConfig(writeConfigFile(t, string(data)))
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(loaded.K0s).To(gomega.Equal(cfg.K0s))
+			g.Expect(loaded.Airgap).To(gomega.Equal(cfg.Airgap))
+			g.Expect(loaded.K0rdent.Version).To(gomega.Equal(cfg.K0rdent.Version))
+		})
+	}
+
+	t.Run("ha-controller uses the internal IP", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		cfg, err := config.ProfileConfig(config.ProfileHAController, "10.0.0.5")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.API.Address).To(gomega.Equal("10.0.0.5"))
+		g.Expect(cfg.K0s.Storage.Etcd.PeerAddress).To(gomega.Equal("10.0.0.5"))
+	})
+
+	t.Run("IPv6 nodes get IPv6 networks", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		cfg, err := config.ProfileConfig(config.ProfileHAController, "2001:db8::10")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.API.Address).To(gomega.Equal("2001:db8::10"))
+		g.Expect(cfg.K0s.Network.PodCIDR).To(gomega.Equal(config.DefaultIPv6PodCIDR))
+		g.Expect(cfg.K0s.Network.ServiceCIDR).To(gomega.Equal(config.DefaultIPv6ServiceCIDR))
+		g.Expect(cfg.Validate()).To(gomega.Succeed())
+	})
+
+	t.Run("unknown profile is rejected", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		_, err := config.ProfileConfig("multi-cloud", "")
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown profile "multi-cloud"`)))
+	})
+}
@@ -0,0 +1,47 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestRedacted(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Join = config.JoinConfig{Mode: "worker", Server: "10.0.0.1", Token: "join-token"}
+	cfg.K0rdent.Helm.Values["registry"] = map[string]interface{}{"url": "registry.local", "password": "hunter2"}
+	cfg.K0rdent.Credentials = config.CredentialsConfig{
+		AWS: []config.AWSCredential{
+			{Name: "aws", Region: "us-east-1", AccessKeyID: "AKIA", SecretAccessKey: "aws-secret"},
+		},
+		Azure: []config.AzureCredential{
+			{Name: "azure", ClientID: "client", ClientSecret: "azure-secret"},
+		},
+		OpenStack: []config.OpenStackCredential{
+			{Name: "openstack", Username: "admin", Password: "openstack-password"},
+		},
+	}
+
+	redacted, err := cfg.Redacted()
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	g.Expect(redacted.Join.Token).To(gomega.Equal(config.RedactedValue))
+	g.Expect(redacted.Join.Server).To(gomega.Equal("10.0.0.1"))
+	g.Expect(redacted.K0rdent.Credentials.AWS[0].SecretAccessKey).To(gomega.Equal(config.RedactedValue))
+	g.Expect(redacted.K0rdent.Credentials.AWS[0].AccessKeyID).To(gomega.Equal("AKIA"))
+	g.Expect(redacted.K0rdent.Credentials.AWS[0].SessionToken).To(gomega.BeEmpty())
+	g.Expect(redacted.K0rdent.Credentials.Azure[0].ClientSecret).To(gomega.Equal(config.RedactedValue))
+	g.Expect(redacted.K0rdent.Credentials.OpenStack[0].Password).To(gomega.Equal(config.RedactedValue))
+	g.Expect(redacted.K0rdent.Helm.Values["registry"]).To(gomega.Equal(map[string]interface{}{
+		"url":      "registry.local",
+		"password": config.RedactedValue,
+	}))
+
+	// The original configuration is left untouched
+	g.Expect(cfg.Join.Token).To(gomega.Equal("join-token"))
+	g.Expect(cfg.K0rdent.Credentials.AWS[0].SecretAccessKey).To(gomega.Equal("aws-secret"))
+	g.Expect(cfg.K0rdent.Helm.Values["registry"]).To(gomega.HaveKeyWithValue("password", "hunter2"))
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect of the generated schema
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema describing the configuration file.
+// It is generated from K0rdentdConfig, so it always matches what LoadConfig
+// accepts. Allowed values are taken from the enum struct tags.
+func JSONSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(K0rdentdConfig{}))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = Kind
+
+	// apiVersion and kind only accept the current values
+	properties := schema["properties"].(map[string]interface{})
+	properties["apiVersion"] = map[string]interface{}{"type": "string", "enum": []string{CurrentAPIVersion}}
+	properties["kind"] = map[string]interface{}{"type": "string", "enum": []string{Kind}}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema returns the JSON Schema of a Go type
+func typeSchema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for idx := 0; idx < typ.NumField(); idx++ {
+			field := typ.Field(idx)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			tag := field.Tag.Get("yaml")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+
+			fieldSchema := typeSchema(field.Type)
+			if enum := field.Tag.Get("enum"); enum != "" {
+				fieldSchema["enum"] = strings.Split(enum, ",")
+			}
+			properties[name] = fieldSchema
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(typ.Elem()),
+		}
+	case reflect.Map:
+		// Free-form maps such as helm values
+		return map[string]interface{}{"type": "object"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
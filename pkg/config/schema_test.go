@@ -0,0 +1,40 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestJSONSchema(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	data, err := config.JSONSchema()
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	var schema map[string]interface{}
+	g.Expect(json.Unmarshal(data, &schema)).To(gomega.Succeed())
+	g.Expect(schema["additionalProperties"]).To(gomega.BeFalse())
+
+	// property returns the schema at the given property path
+	property := func(path ...string) map[string]interface{} {
+		node := schema
+		for _, name := range path {
+			node = node["properties"].(map[string]interface{})[name].(map[string]interface{})
+		}
+		return node
+	}
+
+	g.Expect(property("apiVersion")["enum"]).To(gomega.ConsistOf(config.CurrentAPIVersion))
+	g.Expect(property("join", "mode")["enum"]).To(gomega.ConsistOf("controller", "worker"))
+	g.Expect(property("k0s", "network", "provider")["enum"]).To(gomega.ConsistOf("kuberouter", "calico", "custom"))
+	g.Expect(property("k0s", "api", "port")["type"]).To(gomega.Equal("integer"))
+	g.Expect(property("k0rdent", "helm", "values")["type"]).To(gomega.Equal("object"))
+	g.Expect(property("k0rdent", "helm", "values")).ToNot(gomega.HaveKey("additionalProperties"))
+
+	aws := property("k0rdent", "credentials", "aws")
+	g.Expect(aws["type"]).To(gomega.Equal("array"))
+	g.Expect(aws["items"].(map[string]interface{})["properties"]).To(gomega.HaveKey("secretAccessKey"))
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	// secretRefEnvPrefix marks a value read from an environment variable (env:AWS_SECRET)
+	secretRefEnvPrefix = "env:"
+	// secretRefFilePrefix marks a value read from a file (file:/run/secrets/aws)
+	secretRefFilePrefix = "file:"
+	// secretRefLiteralPrefix marks a value taken as is (literal:env:abc is env:abc)
+	secretRefLiteralPrefix = "literal:"
+)
+
+// secretRefVarPattern matches ${VAR} placeholders interpolated from the
+// environment, and their $${VAR} escaped form
+var secretRefVarPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ResolveSecretRef resolves a secret reference to its actual value:
+//   - env:NAME reads the environment variable NAME
+//   - file:/path reads the file content, without trailing newlines
+//   - literal:value is value, as is
+//   - ${NAME} placeholders are replaced by the environment variable NAME,
+//     $${NAME} stands for a literal ${NAME}
+//
+// Any other value is returned unchanged.
+func ResolveSecretRef(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretRefLiteralPrefix):
+		return strings.TrimPrefix(value, secretRefLiteralPrefix), nil
+
+	case strings.HasPrefix(value, secretRefEnvPrefix):
+		name := strings.TrimPrefix(value, secretRefEnvPrefix)
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return resolved, nil
+
+	case strings.HasPrefix(value, secretRefFilePrefix):
+		path := strings.TrimPrefix(value, secretRefFilePrefix)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var missing []string
+	resolved := secretRefVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		name := secretRefVarPattern.FindStringSubmatch(match)[1]
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return envValue
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable(s) not set: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// isSecretField returns true if the struct field holds sensitive data
+func isSecretField(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true"
+}
+
+// acceptsSecretRef returns true if the struct field accepts secret
+// references: secrets and the identifiers stored next to them
+func acceptsSecretRef(field reflect.StructField) bool {
+	return isSecretField(field) || field.Tag.Get("secretref") == "true"
+}
+
+// resolveSecretRefs resolves the references of every field tagged
+// secret:"true" or secretref:"true"
+func resolveSecretRefs(cfg *K0rdentdConfig) error {
+	var errs ValidationErrors
+	walkStringFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		if value.String() == "" || !acceptsSecretRef(field) {
+			return
+		}
+		resolved, err := ResolveSecretRef(value.String())
+		if err != nil {
+			errs.add(path, "%v", err)
+			return
+		}
+		value.SetString(resolved)
+	})
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// walkStringFields calls fn with the YAML path, struct field and value of
+// every exported string field reachable from v
+func walkStringFields(v reflect.Value, path string, fn func(path string, field reflect.StructField, value reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkStringFields(v.Elem(), path, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < v.Len(); idx++ {
+			walkStringFields(v.Index(idx), fmt.Sprintf("%s[%d]", path, idx), fn)
+		}
+	case reflect.Struct:
+		typ := v.Type()
+		for idx := 0; idx < typ.NumField(); idx++ {
+			field := typ.Field(idx)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fieldPath := joinPath(path, name)
+			if field.Type.Kind() == reflect.String {
+				fn(fieldPath, field, v.Field(idx))
+				continue
+			}
+			walkStringFields(v.Field(idx), fieldPath, fn)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestResolveSecretRef(t *testing.T) {
+	t.Setenv("K0RDENTD_TEST_SECRET", "s3cr3t")
+	t.Setenv("K0RDENTD_TEST_REGION", "eu")
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{name: "plain value", value: "plain$value", expected: "plain$value"},
+		{name: "env reference", value: "env:K0RDENTD_TEST_SECRET", expected: "s3cr3t"},
+		{name: "missing env reference", value: "env:K0RDENTD_TEST_MISSING", expectError: true},
+		{name: "file reference", value: "file:" + secretFile, expected: "from-file"},
+		{name: "missing file reference", value: "file:/nonexistent/secret", expectError: true},
+		{name: "interpolation", value: "${K0RDENTD_TEST_REGION}-west-${K0RDENTD_TEST_SECRET}", expected: "eu-west-s3cr3t"},
+		{name: "missing interpolation variable", value: "${K0RDENTD_TEST_MISSING}", expectError: true},
+		{name: "escaped interpolation", value: "a$${K0RDENTD_TEST_MISSING}-${K0RDENTD_TEST_REGION}", expected: "a${K0RDENTD_TEST_MISSING}-eu"},
+		{name: "literal value", value: "literal:env:K0RDENTD_TEST_SECRET", expected: "env:K0RDENTD_TEST_SECRET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			resolved, err := config.ResolveSecretRef(tt.value)
+			if tt.expectError {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(resolved).To(gomega.Equal(tt.expected))
+		})
+	}
+}
+
+func TestLoadConfigResolvesSecretRefs(t *testing.T) {
+	t.Run("references are resolved at load time", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		t.Setenv("K0RDENTD_TEST_AWS_KEY", "AKIAEXAMPLE")
+		t.Setenv("K0RDENTD_TEST_AWS_SECRET", "aws-secret")
+		t.Setenv("K0RDENTD_TEST_JOIN_TOKEN", "join-token")
+
+		path := writeConfigFile(t, `
+k0rdent:
+  credentials:
+    aws:
+      - name: aws
+        region: us-east-1
+        accessKeyID: ${K0RDENTD_TEST_AWS_KEY}
+        secretAccessKey: env:K0RDENTD_TEST_AWS_SECRET
+join:
+  token: env:K0RDENTD_TEST_JOIN_TOKEN
+`)
+		cfg, err := config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0rdent.Credentials.AWS[0].AccessKeyID).To(gomega.Equal("AKIAEXAMPLE"))
+		g.Expect(cfg.K0rdent.Credentials.AWS[0].SecretAccessKey).To(gomega.Equal("aws-secret"))
+		g.Expect(cfg.Join.Token).To(gomega.Equal("join-token"))
+	})
+
+	t.Run("only secrets and their identifiers are resolved", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		t.Setenv("K0RDENTD_TEST_AWS_KEY", "AKIAEXAMPLE")
+
+		path := writeConfigFile(t, `
+k0rdent:
+  credentials:
+    aws:
+      - name: env:prod
+        region: ${K0RDENTD_TEST_UNSET}
+        accessKeyID: ${K0RDENTD_TEST_AWS_KEY}
+        secretAccessKey: literal:file:abc
+`)
+		cfg, err := config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0rdent.Credentials.AWS[0]).To(gomega.Equal(config.AWSCredential{
+			Name:            "env:prod",
+			Region:          "${K0RDENTD_TEST_UNSET}",
+			AccessKeyID:     "AKIAEXAMPLE",
+			SecretAccessKey: "file:abc",
+		}))
+	})
+
+	t.Run("unresolvable references report the field", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `
+k0rdent:
+  credentials:
+    azure:
+      - name: azure
+        clientSecret: env:K0RDENTD_TEST_MISSING
+`)
+		_, err := config.LoadConfig(path)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("k0rdent.credentials.azure[0].clientSecret"))
+		g.Expect(err.Error()).To(gomega.ContainSubstring("K0RDENTD_TEST_MISSING"))
+	})
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnknownFieldError describes a key in the configuration file that doesn't
+// match any field of the configuration structure
+type UnknownFieldError struct {
+	// Path is the YAML path of the unknown key (e.g. k0s.network.podCidr)
+	Path string
+	// Line and Column locate the key in the configuration file
+	Line   int
+	Column int
+	// Suggestion is the most likely intended key, empty if none was found
+	Suggestion string
+}
+
+// Error implements the error interface
+func (e UnknownFieldError) Error() string {
+	msg := fmt.Sprintf("line %d, column %d: unknown field %q", e.Line, e.Column, e.Path)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// UnknownFieldErrors is the list of all unknown keys found in a configuration file
+type UnknownFieldErrors []UnknownFieldError
+
+// Error implements the error interface, listing every unknown key on its own line
+func (e UnknownFieldErrors) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d unknown field(s) found (use --lenient to ignore them):", len(e)))
+	for _, ferr := range e {
+		sb.WriteString("\n  - ")
+		sb.WriteString(ferr.Error())
+	}
+	return sb.String()
+}
+
+// decodeStrict decodes a parsed document into cfg, failing on keys that
+// don't match any field of K0rdentdConfig
+func decodeStrict(root *yaml.Node, cfg *K0rdentdConfig) error {
+	if unknown := findUnknownFields(root, reflect.TypeOf(*cfg)); len(unknown) > 0 {
+		return unknown
+	}
+
+	// Catch anything the walker might have missed
+	data, err := yaml.Marshal(root)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// findUnknownFields walks the YAML document alongside the Go type and returns
+// every mapping key that has no matching struct field
+func findUnknownFields(root *yaml.Node, typ reflect.Type) UnknownFieldErrors {
+	allPaths := schemaPaths(typ, "")
+
+	var errs UnknownFieldErrors
+	var walk func(node *yaml.Node, typ reflect.Type, path string)
+	walk = func(node *yaml.Node, typ reflect.Type, path string) {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if node.Kind == yaml.DocumentNode {
+			if len(node.Content) > 0 {
+				walk(node.Content[0], typ, path)
+			}
+			return
+		}
+		if node.Kind == yaml.AliasNode && node.Alias != nil {
+			walk(node.Alias, typ, path)
+			return
+		}
+
+		switch typ.Kind() {
+		case reflect.Struct:
+			if node.Kind != yaml.MappingNode {
+				return
+			}
+			fields := structFields(typ)
+			for idx := 0; idx+1 < len(node.Content); idx += 2 {
+				key, value := node.Content[idx], node.Content[idx+1]
+				keyPath := joinPath(path, key.Value)
+				fieldType, ok := fields[key.Value]
+				if !ok {
+					errs = append(errs, UnknownFieldError{
+						Path:       keyPath,
+						Line:       key.Line,
+						Column:     key.Column,
+						Suggestion: suggestField(key.Value, path, fields, allPaths),
+					})
+					continue
+				}
+				walk(value, fieldType, keyPath)
+			}
+		case reflect.Slice, reflect.Array:
+			if node.Kind != yaml.SequenceNode {
+				return
+			}
+			for idx, item := range node.Content {
+				walk(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, idx))
+			}
+		case reflect.Map:
+			if node.Kind != yaml.MappingNode {
+				return
+			}
+			for idx := 0; idx+1 < len(node.Content); idx += 2 {
+				walk(node.Content[idx+1], typ.Elem(), joinPath(path, node.Content[idx].Value))
+			}
+		}
+	}
+
+	walk(root, typ, "")
+	return errs
+}
+
+// structFields returns the YAML keys of a struct type mapped to their field types
+func structFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			for key, fieldType := range structFields(field.Type) {
+				fields[key] = fieldType
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// schemaPaths returns the YAML paths of every field reachable from typ
+func schemaPaths(typ reflect.Type, prefix string) []string {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var paths []string
+	for key, fieldType := range structFields(typ) {
+		path := joinPath(prefix, key)
+		paths = append(paths, path)
+		paths = append(paths, schemaPaths(fieldType, path)...)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// suggestField finds the most likely intended key for an unknown key.
+// Keys at the same level are preferred; otherwise a field with the same
+// name elsewhere in the schema is suggested with its full path.
+func suggestField(key, parentPath string, fields map[string]reflect.Type, allPaths []string) string {
+	best := ""
+	bestDistance := len(key)/3 + 1
+	if bestDistance < 2 {
+		bestDistance = 2
+	}
+	for candidate := range fields {
+		if strings.EqualFold(candidate, key) {
+			return candidate
+		}
+		distance := levenshtein(strings.ToLower(key), strings.ToLower(candidate))
+		if distance < bestDistance || (distance == bestDistance && best != "" && candidate < best) {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	// The key might be valid, but misplaced
+	for _, path := range allPaths {
+		lastDot := strings.LastIndex(path, ".")
+		if strings.EqualFold(path[lastDot+1:], key) && path != joinPath(parentPath, key) {
+			return path
+		}
+	}
+	return ""
+}
+
+// levenshtein computes the edit distance between two strings
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// joinPath appends key to a dotted YAML path
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,114 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+// writeConfigFile writes content to a temporary config file and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "k0rdentd.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	t.Run("valid config loads", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `
+k0s:
+  network:
+    podCIDR: 10.244.0.0/16
+k0rdent:
+  version: 1.2.2
+  credentials:
+    aws:
+      - name: aws
+        region: us-east-1
+`)
+		cfg, err := config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.Network.PodCIDR).To(gomega.Equal("10.244.0.0/16"))
+		g.Expect(cfg.K0rdent.Credentials.AWS).To(gomega.HaveLen(1))
+	})
+
+	t.Run("unknown fields are reported with position and suggestion", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `k0s:
+  network:
+    podCidr: 10.244.0.0/16
+    servicCIDR: 10.96.0.0/12
+credentials:
+  aws: []
+`)
+		_, err := config.LoadConfig(path)
+		g.Expect(err).To(gomega.HaveOccurred())
+
+		var unknown config.UnknownFieldErrors
+		g.Expect(errors.As(err, &unknown)).To(gomega.BeTrue())
+		g.Expect(unknown).To(gomega.ConsistOf(
+			config.UnknownFieldError{Path: "k0s.network.podCidr", Line: 3, Column: 5, Suggestion: "podCIDR"},
+			config.UnknownFieldError{Path: "k0s.network.servicCIDR", Line: 4, Column: 5, Suggestion: "serviceCIDR"},
+			config.UnknownFieldError{Path: "credentials", Line: 5, Column: 1, Suggestion: "k0rdent.credentials"},
+		))
+	})
+
+	t.Run("free-form helm values are not checked", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `
+k0rdent:
+  helm:
+    values:
+      anything:
+        goes: here
+`)
+		_, err := config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	t.Run("lenient mode ignores unknown fields", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		path := writeConfigFile(t, `
+k0s:
+  version: v1.32.4+k0s.0
+  unknown: true
+`)
+		cfg, err := config.LoadConfigWithOptions(path, config.LoadOptions{Lenient: true})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(cfg.K0s.Version).To(gomega.Equal("v1.32.4+k0s.0"))
+	})
+
+	t.Run("example config is valid", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		secretFile := filepath.Join(t.TempDir(), "azure-client-secret")
+		g.Expect(os.WriteFile(secretFile, []byte("secret"), 0600)).To(gomega.Succeed())
+		for _, name := range []string{
+			"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY",
+			"AZURE_SUBSCRIPTION_ID", "AZURE_CLIENT_ID", "AZURE_TENANT_ID",
+			"OS_APPLICATION_CREDENTIAL_ID", "OS_APPLICATION_CREDENTIAL_SECRET",
+		} {
+			t.Setenv(name, "value")
+		}
+
+		data, err := os.ReadFile("../../examples/k0rdentd.yaml")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		path := writeConfigFile(t, strings.ReplaceAll(string(data), "/run/secrets/azure-client-secret", secretFile))
+
+		_, err = config.LoadConfig(path)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Phases whose waits are bounded by the timeouts section
+const (
+	TimeoutK0sReady         = "k0sReady"
+	TimeoutK0rdentReady     = "k0rdentReady"
+	TimeoutProviders        = "providers"
+	TimeoutK0rdentUpgrade   = "k0rdentUpgrade"
+	TimeoutAutopilot        = "autopilot"
+	TimeoutK0rdentUninstall = "k0rdentUninstall"
+)
+
+// TimeoutsConfig bounds the waits of each phase, as Go durations such as
+// 90s or 15m. Unset phases use DefaultTimeouts.
+type TimeoutsConfig struct {
+	// K0sReady bounds the wait for k0s to start
+	K0sReady string `yaml:"k0sReady,omitempty"`
+	// K0rdentReady bounds the wait for the k0rdent deployments to be ready
+	K0rdentReady string `yaml:"k0rdentReady,omitempty"`
+	// Providers bounds the wait for the CAPI providers of the credentials
+	Providers string `yaml:"providers,omitempty"`
+	// K0rdentUpgrade bounds the wait for the upgraded k0rdent chart
+	K0rdentUpgrade string `yaml:"k0rdentUpgrade,omitempty"`
+	// Autopilot bounds the k0s upgrade of all nodes by autopilot
+	Autopilot string `yaml:"autopilot,omitempty"`
+	// K0rdentUninstall bounds each wait of uninstall --k0rdent-only
+	K0rdentUninstall string `yaml:"k0rdentUninstall,omitempty"`
+}
+
+// DefaultTimeouts are the timeouts of the phases left unset
+var DefaultTimeouts = TimeoutsConfig{
+	K0sReady:         "5m",
+	K0rdentReady:     "15m",
+	Providers:        "15m",
+	K0rdentUpgrade:   "15m",
+	Autopilot:        "2h",
+	K0rdentUninstall: "10m",
+}
+
+// TimeoutPhases lists the phases of the timeouts section
+func TimeoutPhases() []string {
+	typ := reflect.TypeOf(TimeoutsConfig{})
+	phases := make([]string, 0, typ.NumField())
+	for idx := 0; idx < typ.NumField(); idx++ {
+		phases = append(phases, timeoutPhase(typ.Field(idx)))
+	}
+	return phases
+}
+
+// Timeout returns the timeout of a phase, its default when it is unset or
+// invalid
+func (t TimeoutsConfig) Timeout(phase string) time.Duration {
+	if value := t.field(phase); value.IsValid() && value.String() != "" {
+		if timeout, err := time.ParseDuration(value.String()); err == nil && timeout > 0 {
+			return timeout
+		}
+	}
+	timeout, _ := time.ParseDuration(DefaultTimeouts.field(phase).String())
+	return timeout
+}
+
+// Set applies a --timeout style expression, such as "k0rdentReady=30m"
+func (t *TimeoutsConfig) Set(expr string) error {
+	phase, value, found := strings.Cut(expr, "=")
+	if !found {
+		return fmt.Errorf("invalid timeout %q: must be in the phase=duration form", expr)
+	}
+	field := t.field(phase)
+	if !field.IsValid() {
+		return fmt.Errorf("unknown timeout phase %q: must be one of %s", phase, strings.Join(TimeoutPhases(), ", "))
+	}
+	if err := validateTimeout(value); err != nil {
+		return fmt.Errorf("invalid timeout of %s: %w", phase, err)
+	}
+	field.SetString(value)
+	return nil
+}
+
+// field returns the field of a phase, the zero Value for unknown phases
+func (t *TimeoutsConfig) field(phase string) reflect.Value {
+	value := reflect.ValueOf(t).Elem()
+	for idx := 0; idx < value.NumField(); idx++ {
+		if timeoutPhase(value.Type().Field(idx)) == phase {
+			return value.Field(idx)
+		}
+	}
+	return reflect.Value{}
+}
+
+// timeoutPhase returns the phase of a TimeoutsConfig field, its YAML key
+func timeoutPhase(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
+
+// validateTimeout checks that value is a positive duration
+func validateTimeout(value string) error {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 90s or 15m", value)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("%q must be positive", value)
+	}
+	return nil
+}
+
+// validateTimeouts validates the timeouts section
+func validateTimeouts(errs *ValidationErrors, cfg TimeoutsConfig) {
+	for _, phase := range TimeoutPhases() {
+		value := cfg.field(phase).String()
+		if value == "" {
+			continue
+		}
+		if err := validateTimeout(value); err != nil {
+			errs.add("timeouts."+phase, "%v", err)
+		}
+	}
+}
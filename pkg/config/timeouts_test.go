@@ -0,0 +1,38 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	timeouts := config.TimeoutsConfig{K0rdentReady: "30m", Providers: "invalid"}
+	g.Expect(timeouts.Timeout(config.TimeoutK0rdentReady)).To(gomega.Equal(30 * time.Minute))
+	g.Expect(timeouts.Timeout(config.TimeoutProviders)).To(gomega.Equal(15 * time.Minute))
+	g.Expect(timeouts.Timeout(config.TimeoutK0sReady)).To(gomega.Equal(5 * time.Minute))
+	g.Expect(timeouts.Timeout(config.TimeoutAutopilot)).To(gomega.Equal(2 * time.Hour))
+
+	for _, phase := range config.TimeoutPhases() {
+		g.Expect(config.TimeoutsConfig{}.Timeout(phase)).To(gomega.BeNumerically(">", 0), phase)
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var timeouts config.TimeoutsConfig
+	g.Expect(timeouts.Set("k0rdentReady=45m")).To(gomega.Succeed())
+	g.Expect(timeouts.Set("k0rdentUninstall=1h30m")).To(gomega.Succeed())
+	g.Expect(timeouts).To(gomega.Equal(config.TimeoutsConfig{K0rdentReady: "45m", K0rdentUninstall: "1h30m"}))
+
+	g.Expect(timeouts.Set("k0rdentReady")).To(gomega.MatchError(gomega.ContainSubstring("phase=duration")))
+	g.Expect(timeouts.Set("unknown=5m")).To(gomega.MatchError(gomega.ContainSubstring("must be one of k0sReady, k0rdentReady")))
+	g.Expect(timeouts.Set("k0sReady=5")).To(gomega.MatchError(gomega.ContainSubstring("not a duration")))
+	g.Expect(timeouts.Set("k0sReady=0s")).To(gomega.MatchError(gomega.ContainSubstring("must be positive")))
+	g.Expect(timeouts.K0sReady).To(gomega.BeEmpty())
+}
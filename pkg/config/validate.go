@@ -0,0 +1,467 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/k0s"
+)
+
+// dnsNamePattern matches DNS names such as api.example.com
+var dnsNamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?)*$`)
+
+// ValidationError describes a single problem found in a configuration
+type ValidationError struct {
+	// Field is the YAML path of the offending field (e.g. k0s.network.podCIDR)
+	Field string
+	// Message describes what is wrong with the field
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is the list of all problems found in a configuration
+type ValidationErrors []ValidationError
+
+// Error implements the error interface, listing every problem on its own line
+func (e ValidationErrors) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d problem(s) found:", len(e)))
+	for _, verr := range e {
+		sb.WriteString("\n  - ")
+		sb.WriteString(verr.Error())
+	}
+	return sb.String()
+}
+
+// add records a new problem for the given field
+func (e *ValidationErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate performs semantic validation of the configuration.
+// It returns nil if the configuration is valid, or ValidationErrors listing
+// every problem found (not only the first one).
+func (c *K0rdentdConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.APIVersion != "" && c.APIVersion != CurrentAPIVersion {
+		errs.add("apiVersion", "unsupported apiVersion %q (expected %s)", c.APIVersion, CurrentAPIVersion)
+	}
+	if c.Kind != "" && c.Kind != Kind {
+		errs.add("kind", "unsupported kind %q (expected %s)", c.Kind, Kind)
+	}
+
+	validateEnums(&errs, c)
+	validateK0s(&errs, c.K0s)
+	validateCredentials(&errs, c.K0rdent.Credentials)
+
+	for idx, path := range c.K0rdent.Helm.ValuesFiles {
+		if _, err := os.Stat(path); err != nil {
+			errs.add(fmt.Sprintf("k0rdent.helm.valuesFiles[%d]", idx), "%v", err)
+		}
+	}
+	validateAirgap(&errs, c.Airgap)
+	validateTimeouts(&errs, c.Timeouts)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateK0s validates the k0s section
+func validateK0s(errs *ValidationErrors, cfg K0sConfig) {
+	if cfg.Version != "" {
+		if err := k0s.ValidateVersion(cfg.Version); err != nil {
+			errs.add("k0s.version", "%v", err)
+		}
+	}
+
+	if cfg.API.Port < 0 || cfg.API.Port > 65535 {
+		errs.add("k0s.api.port", "port %d is out of range (1-65535)", cfg.API.Port)
+	}
+
+	if cfg.API.Address != "" && net.ParseIP(cfg.API.Address) == nil {
+		errs.add("k0s.api.address", "%q is not a valid IP address", cfg.API.Address)
+	}
+
+	if cfg.API.ExternalAddress != "" && !isHostOrIP(cfg.API.ExternalAddress) {
+		errs.add("k0s.api.externalAddress", "%q must be an IP address or a DNS name, without port", cfg.API.ExternalAddress)
+	}
+	for idx, san := range cfg.API.SANs {
+		if !isHostOrIP(san) {
+			errs.add(fmt.Sprintf("k0s.api.sans[%d]", idx), "%q must be an IP address or a DNS name", san)
+		}
+	}
+
+	validateNetwork(errs, cfg.Network)
+	validateControlPlaneLoadBalancing(errs, cfg.Network.ControlPlaneLoadBalancing)
+
+	if cfg.ConfigOverlay != "" {
+		if _, err := os.Stat(cfg.ConfigOverlay); err != nil {
+			errs.add("k0s.configOverlay", "%v", err)
+		}
+	}
+
+	validateStorage(errs, cfg.Storage)
+	validateHelmExtensions(errs, cfg.Extensions.Helm)
+}
+
+// validateStorage checks that the kine and external etcd settings match the
+// storage type and are complete
+func validateStorage(errs *ValidationErrors, cfg StorageConfig) {
+	kine := cfg.Kine
+	if cfg.Type != "kine" && kine != (KineConfig{}) {
+		errs.add("k0s.storage.kine", "requires storage type kine")
+	}
+	if cfg.Type == "kine" && (cfg.Etcd.PeerAddress != "" || cfg.Etcd.ExternalCluster.IsSet()) {
+		errs.add("k0s.storage.etcd", "requires storage type etcd")
+	}
+
+	switch kine.Backend {
+	case "", "sqlite":
+		if kine.Path != "" && !filepath.IsAbs(kine.Path) {
+			errs.add("k0s.storage.kine.path", "%q must be an absolute path", kine.Path)
+		}
+		if kine.DataSource != "" {
+			errs.add("k0s.storage.kine.dataSource", "is only used by the postgres and mysql backends, set path for sqlite")
+		}
+	case "postgres", "mysql":
+		prefixes := map[string][]string{
+			"postgres": {"postgres://", "postgresql://"},
+			"mysql":    {"mysql://"},
+		}[kine.Backend]
+		switch {
+		case kine.DataSource == "":
+			errs.add("k0s.storage.kine.dataSource", "dataSource is required for the %s backend", kine.Backend)
+		case !slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(kine.DataSource, prefix) }):
+			// Don't echo the data source, it holds credentials
+			errs.add("k0s.storage.kine.dataSource", "must start with %s", strings.Join(prefixes, " or "))
+		}
+		if kine.Path != "" {
+			errs.add("k0s.storage.kine.path", "is only used by the sqlite backend")
+		}
+	}
+
+	external := cfg.Etcd.ExternalCluster
+	if !external.IsSet() {
+		if external.EtcdPrefix != "" || external.CAFile != "" || external.ClientCertFile != "" || external.ClientKeyFile != "" {
+			errs.add("k0s.storage.etcd.externalCluster.endpoints", "at least one endpoint is required")
+		}
+		return
+	}
+	for idx, endpoint := range external.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add(fmt.Sprintf("k0s.storage.etcd.externalCluster.endpoints[%d]", idx), "%q must be an http:// or https:// URL", endpoint)
+		}
+	}
+	if (external.ClientCertFile == "") != (external.ClientKeyFile == "") {
+		errs.add("k0s.storage.etcd.externalCluster", "clientCertFile and clientKeyFile must be set together")
+	}
+	for field, path := range map[string]string{
+		"caFile":         external.CAFile,
+		"clientCertFile": external.ClientCertFile,
+		"clientKeyFile":  external.ClientKeyFile,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs.add("k0s.storage.etcd.externalCluster."+field, "%v", err)
+		}
+	}
+}
+
+// validateHelmExtensions checks additional helm repositories and charts.
+// Names must be unique and must not clash with the k0rdent repository and
+// release generated by k0rdentd. Charts pulled from the airgap registry are
+// found by their name only, so different charts must not share it.
+func validateHelmExtensions(errs *ValidationErrors, cfg HelmExtensionsConfig) {
+	repositories := map[string]bool{k0rdentHelmRepository: true}
+	for idx, repo := range cfg.Repositories {
+		path := fmt.Sprintf("k0s.extensions.helm.repositories[%d]", idx)
+		switch {
+		case repo.Name == "":
+			errs.add(path+".name", "name is required")
+		case repositories[repo.Name]:
+			errs.add(path+".name", "duplicate or reserved repository name %q", repo.Name)
+		}
+		repositories[repo.Name] = true
+
+		if repo.URL == "" {
+			errs.add(path+".url", "url is required")
+		} else if _, err := url.ParseRequestURI(repo.URL); err != nil {
+			errs.add(path+".url", "%q is not a valid URL", repo.URL)
+		}
+	}
+
+	charts := map[string]bool{k0rdentHelmRelease: true}
+	airgapCharts := make(map[string]string)
+	for idx, chart := range cfg.Charts {
+		path := fmt.Sprintf("k0s.extensions.helm.charts[%d]", idx)
+		switch {
+		case chart.Name == "":
+			errs.add(path+".name", "name is required")
+		case charts[chart.Name]:
+			errs.add(path+".name", "duplicate or reserved chart name %q", chart.Name)
+		}
+		charts[chart.Name] = true
+
+		if chart.Namespace == "" {
+			errs.add(path+".namespace", "namespace is required")
+		}
+
+		// Charts from repositories are referenced as <repository>/<chart>
+		switch {
+		case chart.ChartName == "":
+			errs.add(path+".chartname", "chartname is required")
+		case strings.HasPrefix(chart.ChartName, "oci://"), strings.HasPrefix(chart.ChartName, "/"):
+			// OCI and local charts don't need a repository
+		default:
+			repo, _, found := strings.Cut(chart.ChartName, "/")
+			if !found || !repositories[repo] {
+				errs.add(path+".chartname", "%q must be <repository>/<chart> with a configured repository, an oci:// URL or an absolute path", chart.ChartName)
+			}
+		}
+
+		if chart.ChartName != "" && !strings.HasPrefix(chart.ChartName, "/") {
+			name := chart.ChartName[strings.LastIndex(chart.ChartName, "/")+1:]
+			if other, ok := airgapCharts[name]; ok && other != chart.ChartName {
+				errs.add(path+".chartname", "%q and %q are both pulled as charts/%s from the airgap registry, rename one of them", other, chart.ChartName, name)
+			} else if !ok {
+				airgapCharts[name] = chart.ChartName
+			}
+		}
+	}
+}
+
+// The k0rdent helm repository and release generated by k0rdentd
+const (
+	k0rdentHelmRepository = "k0rdent"
+	k0rdentHelmRelease    = "kcm"
+)
+
+// validateNetwork validates CIDRs and checks that the pod and service networks
+// don't overlap and that their IP families fit the single or dual-stack setup
+func validateNetwork(errs *ValidationErrors, cfg NetworkConfig) {
+	podNet := parseCIDR(errs, "k0s.network.podCIDR", cfg.PodCIDR)
+	serviceNet := parseCIDR(errs, "k0s.network.serviceCIDR", cfg.ServiceCIDR)
+
+	if podNet != nil && serviceNet != nil {
+		if isIPv6Net(podNet) != isIPv6Net(serviceNet) {
+			errs.add("k0s.network.serviceCIDR", "%s and podCIDR %s must be of the same IP family", cfg.ServiceCIDR, cfg.PodCIDR)
+		} else if cidrsOverlap(podNet, serviceNet) {
+			errs.add("k0s.network.serviceCIDR", "%s overlaps with podCIDR %s", cfg.ServiceCIDR, cfg.PodCIDR)
+		}
+	}
+
+	dualStack := cfg.DualStack
+	if !dualStack.Enabled {
+		if dualStack.IPv6PodCIDR != "" || dualStack.IPv6ServiceCIDR != "" {
+			errs.add("k0s.network.dualStack.enabled", "must be true to use the IPv6 networks")
+		}
+		return
+	}
+
+	// In dual-stack mode IPv4 is the primary family
+	if podNet != nil && isIPv6Net(podNet) {
+		errs.add("k0s.network.podCIDR", "%s must be an IPv4 network when dual-stack is enabled", cfg.PodCIDR)
+	}
+	if serviceNet != nil && isIPv6Net(serviceNet) {
+		errs.add("k0s.network.serviceCIDR", "%s must be an IPv4 network when dual-stack is enabled", cfg.ServiceCIDR)
+	}
+
+	ipv6PodNet := parseIPv6CIDR(errs, "k0s.network.dualStack.IPv6podCIDR", dualStack.IPv6PodCIDR)
+	ipv6ServiceNet := parseIPv6CIDR(errs, "k0s.network.dualStack.IPv6serviceCIDR", dualStack.IPv6ServiceCIDR)
+	if ipv6PodNet != nil && ipv6ServiceNet != nil && cidrsOverlap(ipv6PodNet, ipv6ServiceNet) {
+		errs.add("k0s.network.dualStack.IPv6serviceCIDR", "%s overlaps with IPv6podCIDR %s", dualStack.IPv6ServiceCIDR, dualStack.IPv6PodCIDR)
+	}
+}
+
+// validateControlPlaneLoadBalancing checks the keepalived settings of the
+// control plane load balancing
+func validateControlPlaneLoadBalancing(errs *ValidationErrors, cfg ControlPlaneLoadBalancingConfig) {
+	const field = "k0s.network.controlPlaneLoadBalancing"
+	keepalived := cfg.Keepalived
+
+	if !cfg.Enabled {
+		if len(keepalived.VRRPInstances) > 0 || len(keepalived.VirtualServers) > 0 {
+			errs.add(field+".enabled", "must be true to use the keepalived settings")
+		}
+		return
+	}
+	if len(keepalived.VRRPInstances) == 0 {
+		errs.add(field+".keepalived.vrrpInstances", "at least one VRRP instance is required")
+	}
+
+	for idx, instance := range keepalived.VRRPInstances {
+		instanceField := fmt.Sprintf("%s.keepalived.vrrpInstances[%d]", field, idx)
+		if len(instance.VirtualIPs) == 0 {
+			errs.add(instanceField+".virtualIPs", "at least one virtual IP is required")
+		}
+		for vipIdx, vip := range instance.VirtualIPs {
+			if _, _, err := net.ParseCIDR(vip); err != nil {
+				errs.add(fmt.Sprintf("%s.virtualIPs[%d]", instanceField, vipIdx), "%q must be an IP address with its network prefix, e.g. 192.168.1.100/24", vip)
+			}
+		}
+		// keepalived silently truncates longer passwords
+		if instance.AuthPass == "" || len(instance.AuthPass) > 8 {
+			errs.add(instanceField+".authPass", "must be 1 to 8 characters long")
+		}
+		if instance.VirtualRouterID < 0 || instance.VirtualRouterID > 255 {
+			errs.add(instanceField+".virtualRouterID", "%d is out of range (1-255)", instance.VirtualRouterID)
+		}
+	}
+
+	for idx, server := range keepalived.VirtualServers {
+		if net.ParseIP(server.IPAddress) == nil {
+			errs.add(fmt.Sprintf("%s.keepalived.virtualServers[%d].ipAddress", field, idx), "%q is not a valid IP address", server.IPAddress)
+		}
+	}
+}
+
+// isHostOrIP returns true for IP addresses and DNS names
+func isHostOrIP(value string) bool {
+	return net.ParseIP(value) != nil || dnsNamePattern.MatchString(value)
+}
+
+// parseCIDR parses an optional network, recording an error if it is invalid
+func parseCIDR(errs *ValidationErrors, field, value string) *net.IPNet {
+	if value == "" {
+		return nil
+	}
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		errs.add(field, "%q is not a valid CIDR", value)
+		return nil
+	}
+	return ipNet
+}
+
+// parseIPv6CIDR parses a required IPv6 network of the dual-stack configuration
+func parseIPv6CIDR(errs *ValidationErrors, field, value string) *net.IPNet {
+	if value == "" {
+		errs.add(field, "an IPv6 network is required when dual-stack is enabled")
+		return nil
+	}
+	ipNet := parseCIDR(errs, field, value)
+	if ipNet != nil && !isIPv6Net(ipNet) {
+		errs.add(field, "%s is not an IPv6 network", value)
+		return nil
+	}
+	return ipNet
+}
+
+// isIPv6Net returns true for IPv6 networks
+func isIPv6Net(ipNet *net.IPNet) bool {
+	return ipNet.IP.To4() == nil
+}
+
+// cidrsOverlap returns true if the two networks share at least one address
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// validateEnums checks every field tagged enum:"..." against its allowed values
+func validateEnums(errs *ValidationErrors, cfg *K0rdentdConfig) {
+	walkStringFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		enum := field.Tag.Get("enum")
+		if enum == "" || value.String() == "" {
+			return
+		}
+		allowed := strings.Split(enum, ",")
+		if !slices.Contains(allowed, value.String()) {
+			errs.add(path, "invalid value %q: must be one of %s", value.String(), strings.Join(allowed, ", "))
+		}
+	})
+}
+
+// validateAirgap validates the airgap section
+func validateAirgap(errs *ValidationErrors, cfg AirgapConfig) {
+	if cfg.Registry.Address != "" {
+		if err := validateHostPort(cfg.Registry.Address); err != nil {
+			errs.add("airgap.registry.address", "%v", err)
+		}
+	}
+}
+
+// validateHostPort checks that address is in the host:port form with a valid port
+func validateHostPort(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%q must be in the host:port form", address)
+	}
+	if host == "" {
+		return fmt.Errorf("%q is missing a host", address)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil || portNum < 1 || portNum > 65535 {
+		return fmt.Errorf("%q has an invalid port %q", address, port)
+	}
+	return nil
+}
+
+// validateCredentials checks required fields of every credential and that
+// credential names are unique (they all end up in the same namespace)
+func validateCredentials(errs *ValidationErrors, cfg CredentialsConfig) {
+	seen := make(map[string]string)
+	checkName := func(path, name string) {
+		if name == "" {
+			errs.add(path+".name", "name is required")
+			return
+		}
+		if first, ok := seen[name]; ok {
+			errs.add(path+".name", "duplicate credential name %q (already used by %s)", name, first)
+			return
+		}
+		seen[name] = path
+	}
+	required := func(path, field, value string) {
+		if value == "" {
+			errs.add(path+"."+field, "%s is required", field)
+		}
+	}
+
+	for idx, cred := range cfg.AWS {
+		path := fmt.Sprintf("k0rdent.credentials.aws[%d]", idx)
+		checkName(path, cred.Name)
+		required(path, "region", cred.Region)
+		required(path, "accessKeyID", cred.AccessKeyID)
+		required(path, "secretAccessKey", cred.SecretAccessKey)
+	}
+
+	for idx, cred := range cfg.Azure {
+		path := fmt.Sprintf("k0rdent.credentials.azure[%d]", idx)
+		checkName(path, cred.Name)
+		required(path, "subscriptionID", cred.SubscriptionID)
+		required(path, "clientID", cred.ClientID)
+		required(path, "clientSecret", cred.ClientSecret)
+		required(path, "tenantID", cred.TenantID)
+	}
+
+	for idx, cred := range cfg.OpenStack {
+		path := fmt.Sprintf("k0rdent.credentials.openstack[%d]", idx)
+		checkName(path, cred.Name)
+		required(path, "authURL", cred.AuthURL)
+		required(path, "region", cred.Region)
+
+		hasAppCred := cred.ApplicationCredentialID != "" && cred.ApplicationCredentialSecret != ""
+		hasPassword := cred.Username != "" && cred.Password != ""
+		if !hasAppCred && !hasPassword {
+			errs.add(path, "either applicationCredentialID/applicationCredentialSecret or username/password is required")
+		}
+	}
+}
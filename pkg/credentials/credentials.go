@@ -10,7 +10,8 @@ import (
 	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
 	"github.com/belgaied2/k0rdentd/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
@@ -65,35 +66,53 @@ func (m *Manager) CreateAll(ctx context.Context, cfg config.CredentialsConfig) e
 	return nil
 }
 
+// DeleteAll deletes the credentials of cfg created by CreateAll, in
+// reverse creation order. Missing resources are skipped.
+func (m *Manager) DeleteAll(ctx context.Context, cfg config.CredentialsConfig) error {
+	objects := Resources(cfg)
+	for idx := len(objects) - 1; idx >= 0; idx-- {
+		if err := m.delete(ctx, objects[idx]); err != nil {
+			return err
+		}
+	}
+
+	utils.GetLogger().Info("✅ Cloud provider credentials deleted")
+	return nil
+}
+
+// delete deletes an object built by Resources
+func (m *Manager) delete(ctx context.Context, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		utils.GetLogger().Debugf("Deleting Secret %s/%s", o.Namespace, o.Name)
+		return m.client.DeleteSecret(ctx, o.Namespace, o.Name)
+	case *unstructured.Unstructured:
+		gvk := o.GroupVersionKind()
+		namespace := o.GetNamespace()
+		// Created cluster-scoped, see CreateAWSClusterStaticIdentity
+		if gvk.Kind == awsIdentityKind {
+			namespace = ""
+		}
+		utils.GetLogger().Debugf("Deleting %s %s", gvk.Kind, o.GetName())
+		return m.client.DeleteResource(ctx, gvk.GroupVersion().WithResource(resourceNames[gvk.Kind]), namespace, o.GetName())
+	default:
+		return fmt.Errorf("unexpected credential object %T", obj)
+	}
+}
+
 // createAWSCredentials creates AWS credentials (Secret + AWSClusterStaticIdentity + Credential)
 // This function is idempotent - each resource is only created if it doesn't already exist.
 func (m *Manager) createAWSCredentials(ctx context.Context, cred config.AWSCredential) error {
 	utils.GetLogger().Debugf("Creating AWS credential: %s", cred.Name)
 	utils.GetLogger().Debugf("AWS credential region: %s", cred.Region)
 
-	secretName := fmt.Sprintf("%s-secret", cred.Name)
-	identityName := fmt.Sprintf("%s-identity", cred.Name)
+	secretName := secretNameFor(cred.Name)
+	identityName := identityNameFor(cred.Name)
 
-	// Prepare Secret with AWS credentials
-	secretData := map[string]string{
-		"AccessKeyID":     cred.AccessKeyID,
-		"SecretAccessKey": cred.SecretAccessKey,
-	}
 	if cred.SessionToken != "" {
-		secretData["SessionToken"] = cred.SessionToken
 		utils.GetLogger().Debug("Including SessionToken in AWS secret (MFA/SSO enabled)")
 	}
-
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: KCMNamespace,
-			Labels: map[string]string{
-				KCMComponentLabel: KCMComponentValue,
-			},
-		},
-		StringData: secretData,
-	}
+	secret := awsSecret(cred)
 
 	// Create Secret (if not exists)
 	if err := m.createIfNotExists(
@@ -129,7 +148,7 @@ func (m *Manager) createAWSCredentials(ctx context.Context, cred config.AWSCrede
 	}
 
 	// Create k0rdent Credential (if not exists)
-	description := fmt.Sprintf("AWS credentials for %s in region %s", cred.Name, cred.Region)
+	description := awsDescription(cred)
 	if err := m.createIfNotExists(
 		ctx,
 		ResourceSpec{
@@ -139,7 +158,7 @@ func (m *Manager) createAWSCredentials(ctx context.Context, cred config.AWSCrede
 		},
 		m.client.CredentialExists,
 		func(ctx context.Context) error {
-			return m.client.CreateCredential(ctx, cred.Name, description, "AWSClusterStaticIdentity", identityName, "infrastructure.cluster.x-k8s.io/v1beta2", KCMNamespace)
+			return m.client.CreateCredential(ctx, cred.Name, description, awsIdentityKind, identityName, awsIdentityAPIVersion, KCMNamespace)
 		},
 	); err != nil {
 		utils.GetLogger().Warnf("⚠️ Failed to create k0rdent Credential %s: %v (continuing with best effort)", cred.Name, err)
@@ -153,22 +172,10 @@ func (m *Manager) createAWSCredentials(ctx context.Context, cred config.AWSCrede
 func (m *Manager) createAzureCredentials(ctx context.Context, cred config.AzureCredential) error {
 	utils.GetLogger().Debugf("Creating Azure credential: %s", cred.Name)
 
-	secretName := fmt.Sprintf("%s-secret", cred.Name)
-	identityName := fmt.Sprintf("%s-identity", cred.Name)
+	secretName := secretNameFor(cred.Name)
+	identityName := identityNameFor(cred.Name)
 
-	// Prepare Secret with Azure client secret
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: KCMNamespace,
-			Labels: map[string]string{
-				KCMComponentLabel: KCMComponentValue,
-			},
-		},
-		StringData: map[string]string{
-			"clientSecret": cred.ClientSecret,
-		},
-	}
+	secret := azureSecret(cred)
 
 	// Create Secret (if not exists)
 	if err := m.createIfNotExists(
@@ -204,7 +211,7 @@ func (m *Manager) createAzureCredentials(ctx context.Context, cred config.AzureC
 	}
 
 	// Create k0rdent Credential (if not exists)
-	description := fmt.Sprintf("Azure credentials for %s (subscription: %s)", cred.Name, cred.SubscriptionID)
+	description := azureDescription(cred)
 	if err := m.createIfNotExists(
 		ctx,
 		ResourceSpec{
@@ -214,7 +221,7 @@ func (m *Manager) createAzureCredentials(ctx context.Context, cred config.AzureC
 		},
 		m.client.CredentialExists,
 		func(ctx context.Context) error {
-			return m.client.CreateCredential(ctx, cred.Name, description, "AzureClusterIdentity", identityName, "infrastructure.cluster.x-k8s.io/v1beta1", KCMNamespace)
+			return m.client.CreateCredential(ctx, cred.Name, description, azureIdentityKind, identityName, azureIdentityAPIVersion, KCMNamespace)
 		},
 	); err != nil {
 		utils.GetLogger().Warnf("⚠️ Failed to create k0rdent Credential %s: %v (continuing with best effort)", cred.Name, err)
@@ -228,24 +235,9 @@ func (m *Manager) createAzureCredentials(ctx context.Context, cred config.AzureC
 func (m *Manager) createOpenStackCredentials(ctx context.Context, cred config.OpenStackCredential) error {
 	utils.GetLogger().Debugf("Creating OpenStack credential: %s", cred.Name)
 
-	secretName := fmt.Sprintf("%s-config", cred.Name)
+	secretName := openStackSecretNameFor(cred.Name)
 
-	// Build clouds.yaml content
-	cloudsYAML := m.buildOpenStackCloudsYAML(cred)
-
-	// Prepare Secret with clouds.yaml
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: KCMNamespace,
-			Labels: map[string]string{
-				KCMComponentLabel: KCMComponentValue,
-			},
-		},
-		StringData: map[string]string{
-			"clouds.yaml": cloudsYAML,
-		},
-	}
+	secret := openStackSecret(cred)
 
 	// Create Secret (if not exists)
 	if err := m.createIfNotExists(
@@ -265,7 +257,7 @@ func (m *Manager) createOpenStackCredentials(ctx context.Context, cred config.Op
 	}
 
 	// Create k0rdent Credential (if not exists, no Identity object for OpenStack)
-	description := fmt.Sprintf("OpenStack credentials for %s (region: %s)", cred.Name, cred.Region)
+	description := openStackDescription(cred)
 	if err := m.createIfNotExists(
 		ctx,
 		ResourceSpec{
@@ -285,7 +277,7 @@ func (m *Manager) createOpenStackCredentials(ctx context.Context, cred config.Op
 }
 
 // buildOpenStackCloudsYAML generates the clouds.yaml content for OpenStack credentials
-func (m *Manager) buildOpenStackCloudsYAML(cred config.OpenStackCredential) string {
+func buildOpenStackCloudsYAML(cred config.OpenStackCredential) string {
 	var authSection string
 
 	if cred.ApplicationCredentialID != "" && cred.ApplicationCredentialSecret != "" {
@@ -524,3 +524,54 @@ func TestCreateAWSCredentialsPartialFailure(t *testing.T) {
 	assert.NotNil(t, existingSecret)
 }
 
+
+func TestDeleteAll(t *testing.T) {
+	ctx := context.Background()
+
+	fakeClient := fake.NewSimpleClientset()
+	fakeDynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	client := k8sclient.NewFromClientsetAndDynamic(fakeClient, fakeDynamicClient)
+	manager := NewManager(client)
+
+	cfg := config.CredentialsConfig{
+		Azure: []config.AzureCredential{
+			{
+				Name:           "azure-cred-1",
+				SubscriptionID: "12345678-1234-1234-1234-123456789012",
+				ClientID:       "87654321-4321-4321-4321-210987654321",
+				ClientSecret:   "my-client-secret",
+				TenantID:       "11111111-1111-1111-1111-111111111111",
+			},
+		},
+		OpenStack: []config.OpenStackCredential{
+			{
+				Name:                        "openstack-cred-1",
+				AuthURL:                     "https://openstack.example.com:5000/v3",
+				Region:                      "RegionOne",
+				ApplicationCredentialID:     "app-cred-id",
+				ApplicationCredentialSecret: "app-cred-secret",
+			},
+		},
+	}
+
+	err := manager.CreateAll(ctx, cfg)
+	assert.NoError(t, err)
+
+	err = manager.DeleteAll(ctx, cfg)
+	assert.NoError(t, err)
+
+	exists, err := client.SecretExists(ctx, KCMNamespace, "azure-cred-1-secret")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = client.AzureClusterIdentityExists(ctx, KCMNamespace, "azure-cred-1-identity")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = client.CredentialExists(ctx, KCMNamespace, "openstack-cred-1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Deleting again skips the missing resources
+	err = manager.DeleteAll(ctx, cfg)
+	assert.NoError(t, err)
+}
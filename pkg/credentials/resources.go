@@ -0,0 +1,132 @@
+package credentials
+
+import (
+	"fmt"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	awsIdentityKind         = "AWSClusterStaticIdentity"
+	awsIdentityAPIVersion   = "infrastructure.cluster.x-k8s.io/v1beta2"
+	azureIdentityKind       = "AzureClusterIdentity"
+	azureIdentityAPIVersion = "infrastructure.cluster.x-k8s.io/v1beta1"
+)
+
+// resourceNames are the resources of the custom objects of credentials
+var resourceNames = map[string]string{
+	awsIdentityKind:   "awsclusterstaticidentities",
+	azureIdentityKind: "azureclusteridentities",
+	"Credential":      "credentials",
+}
+
+// Resources returns the Kubernetes objects created for the configured
+// credentials, in creation order
+func Resources(cfg config.CredentialsConfig) []runtime.Object {
+	var objects []runtime.Object
+
+	for _, cred := range cfg.AWS {
+		identityName := identityNameFor(cred.Name)
+		objects = append(objects,
+			awsSecret(cred),
+			k8sclient.NewAWSClusterStaticIdentity(identityName, secretNameFor(cred.Name), KCMNamespace),
+			k8sclient.NewCredential(cred.Name, awsDescription(cred), awsIdentityKind, identityName, awsIdentityAPIVersion, KCMNamespace),
+		)
+	}
+
+	for _, cred := range cfg.Azure {
+		identityName := identityNameFor(cred.Name)
+		objects = append(objects,
+			azureSecret(cred),
+			k8sclient.NewAzureClusterIdentity(identityName, cred.ClientID, cred.TenantID, secretNameFor(cred.Name), KCMNamespace),
+			k8sclient.NewCredential(cred.Name, azureDescription(cred), azureIdentityKind, identityName, azureIdentityAPIVersion, KCMNamespace),
+		)
+	}
+
+	for _, cred := range cfg.OpenStack {
+		objects = append(objects,
+			openStackSecret(cred),
+			k8sclient.NewCredential(cred.Name, openStackDescription(cred), "Secret", openStackSecretNameFor(cred.Name), "v1", KCMNamespace),
+		)
+	}
+
+	return objects
+}
+
+// secretNameFor returns the name of the Secret of a credential
+func secretNameFor(name string) string {
+	return fmt.Sprintf("%s-secret", name)
+}
+
+// identityNameFor returns the name of the cluster identity of a credential
+func identityNameFor(name string) string {
+	return fmt.Sprintf("%s-identity", name)
+}
+
+// openStackSecretNameFor returns the name of the Secret holding clouds.yaml
+func openStackSecretNameFor(name string) string {
+	return fmt.Sprintf("%s-config", name)
+}
+
+// newSecret builds a k0rdent component Secret in the KCM namespace
+func newSecret(name string, data map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: KCMNamespace,
+			Labels: map[string]string{
+				KCMComponentLabel: KCMComponentValue,
+			},
+		},
+		StringData: data,
+	}
+}
+
+// awsSecret builds the Secret holding AWS credentials
+func awsSecret(cred config.AWSCredential) *corev1.Secret {
+	data := map[string]string{
+		"AccessKeyID":     cred.AccessKeyID,
+		"SecretAccessKey": cred.SecretAccessKey,
+	}
+	if cred.SessionToken != "" {
+		data["SessionToken"] = cred.SessionToken
+	}
+	return newSecret(secretNameFor(cred.Name), data)
+}
+
+// azureSecret builds the Secret holding the Azure client secret
+func azureSecret(cred config.AzureCredential) *corev1.Secret {
+	return newSecret(secretNameFor(cred.Name), map[string]string{
+		"clientSecret": cred.ClientSecret,
+	})
+}
+
+// openStackSecret builds the Secret holding the OpenStack clouds.yaml
+func openStackSecret(cred config.OpenStackCredential) *corev1.Secret {
+	return newSecret(openStackSecretNameFor(cred.Name), map[string]string{
+		"clouds.yaml": buildOpenStackCloudsYAML(cred),
+	})
+}
+
+// awsDescription describes an AWS Credential
+func awsDescription(cred config.AWSCredential) string {
+	return fmt.Sprintf("AWS credentials for %s in region %s", cred.Name, cred.Region)
+}
+
+// azureDescription describes an Azure Credential
+func azureDescription(cred config.AzureCredential) string {
+	return fmt.Sprintf("Azure credentials for %s (subscription: %s)", cred.Name, cred.SubscriptionID)
+}
+
+// openStackDescription describes an OpenStack Credential
+func openStackDescription(cred config.OpenStackCredential) string {
+	return fmt.Sprintf("OpenStack credentials for %s (region: %s)", cred.Name, cred.Region)
+}
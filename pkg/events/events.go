@@ -0,0 +1,88 @@
+// Package events reports the progress of an installation as JSON events,
+// one per line, for automation tools such as Ansible or Terraform.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Type is the kind of progress event
+type Type string
+
+const (
+	StepStarted  Type = "step_started"
+	StepFinished Type = "step_finished"
+	StepFailed   Type = "step_failed"
+	StepSkipped  Type = "step_skipped"
+	// WaitStarted and its outcomes report the waits for a resource
+	WaitStarted  Type = "wait_started"
+	WaitFinished Type = "wait_finished"
+	WaitFailed   Type = "wait_failed"
+	// InstallFinished is the last event of a successful installation
+	InstallFinished Type = "install_finished"
+	InstallFailed   Type = "install_failed"
+)
+
+// Event is a phase transition of the installation
+type Event struct {
+	Time        time.Time `json:"time"`
+	Type        Type      `json:"type"`
+	Step        string    `json:"step,omitempty"`
+	Description string    `json:"description,omitempty"`
+	// Resource is what a wait is waiting for
+	Resource string `json:"resource,omitempty"`
+	// DurationSeconds is set once a step or a wait ends
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	// Summary is set by InstallFinished
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// Summary tells how to reach the installed k0rdent
+type Summary struct {
+	UIURL         string `json:"uiURL,omitempty"`
+	UINodePortURL string `json:"uiNodePortURL,omitempty"`
+	UIUsername    string `json:"uiUsername,omitempty"`
+	// UIPasswordFrom tells where to read the UI password, which is not part
+	// of the events
+	UIPasswordFrom string `json:"uiPasswordFrom,omitempty"`
+	// Credentials are the names of the cloud provider credentials
+	Credentials []string `json:"credentials,omitempty"`
+}
+
+// Recorder writes events as JSON lines. A nil Recorder drops the events,
+// so that callers don't need to check whether events are enabled.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+}
+
+// NewRecorder returns a Recorder writing to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// Emit writes an event, setting its time if unset. Write errors are
+// ignored: events must not fail the installation.
+func (r *Recorder) Emit(event Event) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.Time.IsZero() {
+		event.Time = r.now().UTC()
+	}
+	_ = r.enc.Encode(event)
+}
+
+// Seconds returns the duration since start, rounded to milliseconds, for
+// DurationSeconds
+func Seconds(start time.Time) float64 {
+	return time.Since(start).Round(time.Millisecond).Seconds()
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestRecorder(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var out bytes.Buffer
+	recorder := NewRecorder(&out)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorder.now = func() time.Time { return now }
+
+	recorder.Emit(Event{Type: StepStarted, Step: "install-k0s", Description: "Install and start k0s"})
+	recorder.Emit(Event{Type: StepFinished, Step: "install-k0s", DurationSeconds: 12.5})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	g.Expect(lines).To(gomega.HaveLen(2))
+	g.Expect(lines[0]).To(gomega.Equal(
+		`{"time":"2026-01-02T03:04:05Z","type":"step_started","step":"install-k0s","description":"Install and start k0s"}`))
+
+	var event Event
+	g.Expect(json.Unmarshal([]byte(lines[1]), &event)).To(gomega.Succeed())
+	g.Expect(event).To(gomega.Equal(Event{Time: now, Type: StepFinished, Step: "install-k0s", DurationSeconds: 12.5}))
+}
+
+func TestNilRecorder(t *testing.T) {
+	var recorder *Recorder
+	// Dropped without panicking
+	recorder.Emit(Event{Type: InstallFinished})
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+)
+
+// appendHelmExtensions appends the additional helm repositories and charts
+// of the configuration to the generated ones
+func appendHelmExtensions(helm *K0sHelmExtensions, cfg config.HelmExtensionsConfig) {
+	for _, repo := range cfg.Repositories {
+		k0sRepo := K0sHelmRepository{
+			Name:     repo.Name,
+			URL:      repo.URL,
+			CAFile:   repo.CAFile,
+			Username: repo.Username,
+			Password: repo.Password,
+		}
+		if repo.Insecure {
+			insecure := true
+			k0sRepo.Insecure = &insecure
+		}
+		helm.Repositories = append(helm.Repositories, k0sRepo)
+	}
+
+	for _, chart := range cfg.Charts {
+		helm.Charts = append(helm.Charts, k0sHelmChart(chart, chart.ChartName))
+	}
+}
+
+// appendAirgapHelmCharts appends the additional helm charts of the
+// configuration, pulling them from the local registry
+func appendAirgapHelmCharts(helm *K0sHelmExtensions, cfg config.HelmExtensionsConfig, registryAddr string) {
+	for _, chart := range cfg.Charts {
+		helm.Charts = append(helm.Charts, k0sHelmChart(chart, airgapChartName(chart.ChartName, registryAddr)))
+	}
+}
+
+// airgapChartName rewrites a chart reference to the charts of the local
+// registry. Local chart archives are kept as is.
+func airgapChartName(chartName, registryAddr string) string {
+	if strings.HasPrefix(chartName, "/") {
+		return chartName
+	}
+	return fmt.Sprintf("oci://%s/charts/%s", registryAddr, path.Base(chartName))
+}
+
+// k0sHelmChart converts a configured chart to its k0s form
+func k0sHelmChart(chart config.HelmChart, chartName string) K0sHelmChart {
+	return K0sHelmChart{
+		Name:      chart.Name,
+		Chartname: chartName,
+		Version:   chart.Version,
+		Namespace: chart.Namespace,
+		Values:    formatHelmValues(chart.Values),
+	}
+}
@@ -2,12 +2,16 @@ package generator
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/belgaied2/k0rdentd/pkg/config"
 	"gopkg.in/yaml.v3"
 )
 
-const defaultK0rdentHelmReleaseName = "kcm"
+// K0rdentHelmReleaseName is the name of the k0rdent chart and its Helm release
+const K0rdentHelmReleaseName = "kcm"
 
 // K0sClusterConfig represents the K0s cluster configuration structure
 type K0sClusterConfig struct {
@@ -30,26 +34,86 @@ type K0sClusterSpec struct {
 
 // K0sAPISpec represents K0s API specification
 type K0sAPISpec struct {
-	Address string `yaml:"address"`
-	Port    int    `yaml:"port"`
+	Address         string   `yaml:"address"`
+	Port            int      `yaml:"port"`
+	ExternalAddress string   `yaml:"externalAddress,omitempty"`
+	SANs            []string `yaml:"sans,omitempty"`
 }
 
 // K0sNetworkSpec represents K0s network specification
 type K0sNetworkSpec struct {
-	Provider    string `yaml:"provider"`
-	PodCIDR     string `yaml:"podCIDR"`
-	ServiceCIDR string `yaml:"serviceCIDR"`
+	Provider                  string                        `yaml:"provider,omitempty"`
+	PodCIDR                   string                        `yaml:"podCIDR,omitempty"`
+	ServiceCIDR               string                        `yaml:"serviceCIDR,omitempty"`
+	DualStack                 *K0sDualStackSpec             `yaml:"dualStack,omitempty"`
+	ControlPlaneLoadBalancing *K0sControlPlaneLoadBalancing `yaml:"controlPlaneLoadBalancing,omitempty"`
+	NodeLocalLoadBalancing    *K0sNodeLocalLoadBalancing    `yaml:"nodeLocalLoadBalancing,omitempty"`
+}
+
+// K0sControlPlaneLoadBalancing represents K0s control plane load balancing
+type K0sControlPlaneLoadBalancing struct {
+	Enabled    bool          `yaml:"enabled"`
+	Type       string        `yaml:"type"`
+	Keepalived K0sKeepalived `yaml:"keepalived"`
+}
+
+// K0sKeepalived represents the keepalived specification of K0s
+type K0sKeepalived struct {
+	VRRPInstances  []K0sVRRPInstance  `yaml:"vrrpInstances,omitempty"`
+	VirtualServers []K0sVirtualServer `yaml:"virtualServers,omitempty"`
+}
+
+// K0sVRRPInstance represents a keepalived VRRP instance
+type K0sVRRPInstance struct {
+	VirtualIPs      []string `yaml:"virtualIPs"`
+	AuthPass        string   `yaml:"authPass"`
+	VirtualRouterID int      `yaml:"virtualRouterID,omitempty"`
+	Interface       string   `yaml:"interface,omitempty"`
+}
+
+// K0sVirtualServer represents a keepalived virtual server
+type K0sVirtualServer struct {
+	IPAddress string `yaml:"ipAddress"`
+}
+
+// K0sNodeLocalLoadBalancing represents K0s node-local load balancing
+type K0sNodeLocalLoadBalancing struct {
+	Enabled bool   `yaml:"enabled"`
+	Type    string `yaml:"type"`
+}
+
+// K0sDualStackSpec represents K0s dual-stack specification
+type K0sDualStackSpec struct {
+	Enabled         bool   `yaml:"enabled"`
+	IPv6PodCIDR     string `yaml:"IPv6podCIDR"`
+	IPv6ServiceCIDR string `yaml:"IPv6serviceCIDR"`
 }
 
 // K0sStorageSpec represents K0s storage specification
 type K0sStorageSpec struct {
-	Type string      `yaml:"type"`
-	Etcd K0sEtcdSpec `yaml:"etcd,omitempty"`
+	Type string       `yaml:"type"`
+	Etcd K0sEtcdSpec  `yaml:"etcd,omitempty"`
+	Kine *K0sKineSpec `yaml:"kine,omitempty"`
 }
 
 // K0sEtcdSpec represents K0s etcd specification
 type K0sEtcdSpec struct {
-	PeerAddress string `yaml:"peerAddress"`
+	PeerAddress     string                  `yaml:"peerAddress,omitempty"`
+	ExternalCluster *K0sExternalClusterSpec `yaml:"externalCluster,omitempty"`
+}
+
+// K0sExternalClusterSpec represents an external etcd cluster
+type K0sExternalClusterSpec struct {
+	Endpoints      []string `yaml:"endpoints"`
+	EtcdPrefix     string   `yaml:"etcdPrefix,omitempty"`
+	CAFile         string   `yaml:"caFile,omitempty"`
+	ClientCertFile string   `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile  string   `yaml:"clientKeyFile,omitempty"`
+}
+
+// K0sKineSpec represents K0s kine specification
+type K0sKineSpec struct {
+	DataSource string `yaml:"dataSource"`
 }
 
 // K0sImagesSpec represents K0s images specification
@@ -74,6 +138,8 @@ type K0sHelmRepository struct {
 	URL      string `yaml:"url"`
 	Insecure *bool  `yaml:"insecure,omitempty"`
 	CAFile   string `yaml:"caFile,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
 }
 
 // K0sHelmChart represents a helm chart to install
@@ -87,6 +153,11 @@ type K0sHelmChart struct {
 
 // GenerateK0sConfig generates K0s configuration from k0rdentd configuration
 func GenerateK0sConfig(cfg *config.K0rdentdConfig) ([]byte, error) {
+	values, err := k0rdentHelmValues(cfg.K0rdent.Helm)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create K0s cluster configuration
 	k0sConfig := K0sClusterConfig{
 		APIVersion: "k0s.k0sproject.io/v1beta1",
@@ -107,11 +178,11 @@ func GenerateK0sConfig(cfg *config.K0rdentdConfig) ([]byte, error) {
 					},
 					Charts: []K0sHelmChart{
 						{
-							Name:      defaultK0rdentHelmReleaseName,
+							Name:      K0rdentHelmReleaseName,
 							Chartname: cfg.K0rdent.Helm.Chart,
 							Version:   cfg.K0rdent.Version,
 							Namespace: cfg.K0rdent.Helm.Namespace,
-							Values:    formatHelmValues(cfg.K0rdent.Helm.Values),
+							Values:    formatHelmValues(values),
 						},
 					},
 				},
@@ -119,43 +190,225 @@ func GenerateK0sConfig(cfg *config.K0rdentdConfig) ([]byte, error) {
 		},
 	}
 
-	// Only populate API spec if fields are set
-	if cfg.K0s.API.Address != "" || cfg.K0s.API.Port != 0 {
-		k0sConfig.Spec.API = &K0sAPISpec{
-			Address: cfg.K0s.API.Address,
-			Port:    cfg.K0s.API.Port,
+	k0sConfig.Spec.API = apiSpec(cfg.K0s)
+
+	appendHelmExtensions(&k0sConfig.Spec.Extensions.Helm, cfg.K0s.Extensions.Helm)
+
+	k0sConfig.Spec.Network = networkSpec(cfg.K0s.Network)
+
+	k0sConfig.Spec.Storage = storageSpec(cfg.K0s.Storage)
+
+	// Marshal to YAML, with the user provided spec overrides
+	configBytes, err := marshalK0sConfig(k0sConfig, cfg.K0s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal K0s config: %w", err)
+	}
+
+	return configBytes, nil
+}
+
+// storageSpec returns the K0s storage specification, or nil if no storage
+// field is set
+func storageSpec(cfg config.StorageConfig) *K0sStorageSpec {
+	if cfg.Type == "" && cfg.Etcd.PeerAddress == "" && !cfg.Etcd.ExternalCluster.IsSet() {
+		return nil
+	}
+
+	spec := &K0sStorageSpec{
+		Type: cfg.Type,
+		Etcd: K0sEtcdSpec{
+			PeerAddress: cfg.Etcd.PeerAddress,
+		},
+	}
+	if external := cfg.Etcd.ExternalCluster; external.IsSet() {
+		spec.Etcd.ExternalCluster = &K0sExternalClusterSpec{
+			Endpoints:      external.Endpoints,
+			EtcdPrefix:     external.EtcdPrefix,
+			CAFile:         external.CAFile,
+			ClientCertFile: external.ClientCertFile,
+			ClientKeyFile:  external.ClientKeyFile,
+		}
+	}
+	if cfg.Type == "kine" {
+		spec.Kine = &K0sKineSpec{
+			DataSource: cfg.Kine.DSN(),
+		}
+	}
+	return spec
+}
+
+// apiSpec returns the K0s API specification, or nil if no API field is
+// set. The virtual IPs of the controllers are added to the certificate SANs.
+func apiSpec(cfg config.K0sConfig) *K0sAPISpec {
+	sans := slices.Clone(cfg.API.SANs)
+	if cplb := cfg.Network.ControlPlaneLoadBalancing; cplb.Enabled {
+		for _, instance := range cplb.Keepalived.VRRPInstances {
+			for _, vip := range instance.VirtualIPs {
+				ip, _, _ := strings.Cut(vip, "/")
+				if !slices.Contains(sans, ip) {
+					sans = append(sans, ip)
+				}
+			}
 		}
 	}
 
-	// Only populate Network spec if fields are set
-	if cfg.K0s.Network.Provider != "" || cfg.K0s.Network.PodCIDR != "" || cfg.K0s.Network.ServiceCIDR != "" {
-		k0sConfig.Spec.Network = &K0sNetworkSpec{
-			Provider:    cfg.K0s.Network.Provider,
-			PodCIDR:     cfg.K0s.Network.PodCIDR,
-			ServiceCIDR: cfg.K0s.Network.ServiceCIDR,
+	if cfg.API.Address == "" && cfg.API.Port == 0 && cfg.API.ExternalAddress == "" && len(sans) == 0 {
+		return nil
+	}
+	return &K0sAPISpec{
+		Address:         cfg.API.Address,
+		Port:            cfg.API.Port,
+		ExternalAddress: cfg.API.ExternalAddress,
+		SANs:            sans,
+	}
+}
+
+// networkSpec returns the K0s network specification, or nil if no network
+// field is set
+func networkSpec(cfg config.NetworkConfig) *K0sNetworkSpec {
+	cplb, nllb := cfg.ControlPlaneLoadBalancing, cfg.NodeLocalLoadBalancing
+	if cfg.Provider == "" && cfg.PodCIDR == "" && cfg.ServiceCIDR == "" && !cfg.DualStack.Enabled && !cplb.Enabled && !nllb.Enabled {
+		return nil
+	}
+
+	spec := &K0sNetworkSpec{
+		Provider:    cfg.Provider,
+		PodCIDR:     cfg.PodCIDR,
+		ServiceCIDR: cfg.ServiceCIDR,
+	}
+	if cfg.DualStack.Enabled {
+		spec.DualStack = &K0sDualStackSpec{
+			Enabled:         true,
+			IPv6PodCIDR:     cfg.DualStack.IPv6PodCIDR,
+			IPv6ServiceCIDR: cfg.DualStack.IPv6ServiceCIDR,
+		}
+	}
+	if cplb.Enabled {
+		keepalived := K0sKeepalived{}
+		for _, instance := range cplb.Keepalived.VRRPInstances {
+			keepalived.VRRPInstances = append(keepalived.VRRPInstances, K0sVRRPInstance(instance))
+		}
+		for _, server := range cplb.Keepalived.VirtualServers {
+			keepalived.VirtualServers = append(keepalived.VirtualServers, K0sVirtualServer(server))
+		}
+		spec.ControlPlaneLoadBalancing = &K0sControlPlaneLoadBalancing{
+			Enabled:    true,
+			Type:       "Keepalived",
+			Keepalived: keepalived,
+		}
+	}
+	if nllb.Enabled {
+		nllbType := nllb.Type
+		if nllbType == "" {
+			nllbType = "EnvoyProxy"
+		}
+		spec.NodeLocalLoadBalancing = &K0sNodeLocalLoadBalancing{
+			Enabled: true,
+			Type:    nllbType,
 		}
 	}
+	return spec
+}
+
+// RedactK0sConfig returns a copy of a generated K0s configuration with
+// sensitive values redacted, suitable for logging
+func RedactK0sConfig(data []byte) ([]byte, error) {
+	// Work on the generic form so k0s spec overrides are kept
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse K0s config: %w", err)
+	}
 
-	// Only populate Storage spec if fields are set
-	if cfg.K0s.Storage.Type != "" || cfg.K0s.Storage.Etcd.PeerAddress != "" {
-		storageSpec := &K0sStorageSpec{
-			Type: cfg.K0s.Storage.Type,
+	for _, chart := range helmCharts(doc) {
+		values, ok := chart["values"].(string)
+		if !ok {
+			continue
+		}
+		redacted, err := config.RedactYAML(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse values of chart %v: %w", chart["name"], err)
 		}
-		if cfg.K0s.Storage.Etcd.PeerAddress != "" {
-			storageSpec.Etcd = K0sEtcdSpec{
-				PeerAddress: cfg.K0s.Storage.Etcd.PeerAddress,
+		chart["values"] = redacted
+	}
+	if spec, ok := doc["spec"].(map[string]interface{}); ok {
+		if storage, ok := spec["storage"].(map[string]interface{}); ok {
+			if kine, ok := storage["kine"].(map[string]interface{}); ok {
+				if dataSource, ok := kine["dataSource"].(string); ok {
+					kine["dataSource"] = config.RedactDSN(dataSource)
+				}
 			}
 		}
-		k0sConfig.Spec.Storage = storageSpec
 	}
+	config.Redact(&doc)
 
-	// Marshal to YAML
-	configBytes, err := yaml.Marshal(k0sConfig)
+	return yaml.Marshal(doc)
+}
+
+// RedactK0sConfigFile is RedactK0sConfig for file contents shown in dry-run
+// plans. Content that can't be parsed is hidden entirely.
+func RedactK0sConfigFile(data []byte) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	redacted, err := RedactK0sConfig(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal K0s config: %w", err)
+		return []byte(config.RedactedValue + "\n")
 	}
+	return redacted
+}
 
-	return configBytes, nil
+// HelmChartValues returns the values of each helm chart of a generated K0s
+// configuration, keyed by chart name
+func HelmChartValues(data []byte) (map[string]string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse K0s config: %w", err)
+	}
+
+	values := make(map[string]string)
+	for _, chart := range helmCharts(doc) {
+		name, _ := chart["name"].(string)
+		chartValues, _ := chart["values"].(string)
+		values[name] = chartValues
+	}
+	return values, nil
+}
+
+// helmCharts returns the helm charts of the generic form of a K0s configuration
+func helmCharts(doc map[string]interface{}) []map[string]interface{} {
+	var charts []map[string]interface{}
+	spec, _ := doc["spec"].(map[string]interface{})
+	extensions, _ := spec["extensions"].(map[string]interface{})
+	helm, _ := extensions["helm"].(map[string]interface{})
+	list, _ := helm["charts"].([]interface{})
+	for _, item := range list {
+		if chart, ok := item.(map[string]interface{}); ok {
+			charts = append(charts, chart)
+		}
+	}
+	return charts
+}
+
+// k0rdentHelmValues returns the values of the k0rdent chart: its values
+// files merged in order, then its inline values
+func k0rdentHelmValues(cfg config.K0rdentHelmConfig) (map[string]interface{}, error) {
+	if len(cfg.ValuesFiles) == 0 {
+		return cfg.Values, nil
+	}
+
+	values := make(map[string]interface{})
+	for _, path := range cfg.ValuesFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read helm values file: %w", err)
+		}
+		var fileValues map[string]interface{}
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return nil, fmt.Errorf("failed to parse helm values file %s: %w", path, err)
+		}
+		values = mergeValues(values, fileValues)
+	}
+	return mergeValues(values, cfg.Values), nil
 }
 
 // formatHelmValues formats helm values as YAML string
@@ -177,6 +430,11 @@ func GenerateAirgapK0sConfig(cfg *config.K0rdentdConfig, registryAddr string, in
 		k0rdentVersion = "1.2.2" // default fallback
 	}
 
+	values, err := k0rdentHelmValues(cfg.K0rdent.Helm)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build OCI chart URL for local registry
 	chartURL := fmt.Sprintf("oci://%s/charts/k0rdent-enterprise", registryAddr)
 
@@ -195,11 +453,11 @@ func GenerateAirgapK0sConfig(cfg *config.K0rdentdConfig, registryAddr string, in
 					Repositories: []K0sHelmRepository{},
 					Charts: []K0sHelmChart{
 						{
-							Name:      defaultK0rdentHelmReleaseName,
+							Name:      K0rdentHelmReleaseName,
 							Chartname: chartURL,
 							Version:   k0rdentVersion,
 							Namespace: cfg.K0rdent.Helm.Namespace,
-							Values:    formatAirgapHelmValues(cfg.K0rdent.Helm.Values, registryAddr),
+							Values:    formatAirgapHelmValues(values, registryAddr),
 						},
 					},
 				},
@@ -207,44 +465,23 @@ func GenerateAirgapK0sConfig(cfg *config.K0rdentdConfig, registryAddr string, in
 		},
 	}
 
+	// Charts are pulled from the local registry, other repositories are unreachable
+	appendAirgapHelmCharts(&k0sConfig.Spec.Extensions.Helm, cfg.K0s.Extensions.Helm, registryAddr)
+
 	// Add registry repository if needed (for OCI with TLS)
 	if !insecure {
 		// For secure registries, we might need to add a repository entry
 		// For now, we skip this as local registries are typically insecure
 	}
 
-	// Only populate API spec if fields are set
-	if cfg.K0s.API.Address != "" || cfg.K0s.API.Port != 0 {
-		k0sConfig.Spec.API = &K0sAPISpec{
-			Address: cfg.K0s.API.Address,
-			Port:    cfg.K0s.API.Port,
-		}
-	}
+	k0sConfig.Spec.API = apiSpec(cfg.K0s)
 
-	// Only populate Network spec if fields are set
-	if cfg.K0s.Network.Provider != "" || cfg.K0s.Network.PodCIDR != "" || cfg.K0s.Network.ServiceCIDR != "" {
-		k0sConfig.Spec.Network = &K0sNetworkSpec{
-			Provider:    cfg.K0s.Network.Provider,
-			PodCIDR:     cfg.K0s.Network.PodCIDR,
-			ServiceCIDR: cfg.K0s.Network.ServiceCIDR,
-		}
-	}
+	k0sConfig.Spec.Network = networkSpec(cfg.K0s.Network)
 
-	// Only populate Storage spec if fields are set
-	if cfg.K0s.Storage.Type != "" || cfg.K0s.Storage.Etcd.PeerAddress != "" {
-		storageSpec := &K0sStorageSpec{
-			Type: cfg.K0s.Storage.Type,
-		}
-		if cfg.K0s.Storage.Etcd.PeerAddress != "" {
-			storageSpec.Etcd = K0sEtcdSpec{
-				PeerAddress: cfg.K0s.Storage.Etcd.PeerAddress,
-			}
-		}
-		k0sConfig.Spec.Storage = storageSpec
-	}
+	k0sConfig.Spec.Storage = storageSpec(cfg.K0s.Storage)
 
-	// Marshal to YAML
-	configBytes, err := yaml.Marshal(k0sConfig)
+	// Marshal to YAML, with the user provided spec overrides
+	configBytes, err := marshalK0sConfig(k0sConfig, cfg.K0s)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal airgap K0s config: %w", err)
 	}
@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// k0sJoinClusterConfig is the K0s configuration of a joining controller.
+// Extensions come from the cluster, so only the settings each controller
+// must share with the others are written. k0s reads the storage from the
+// configuration of each controller, so it is one of them.
+type k0sJoinClusterConfig struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		API     *K0sAPISpec     `yaml:"api,omitempty"`
+		Network *K0sNetworkSpec `yaml:"network,omitempty"`
+		Storage *K0sStorageSpec `yaml:"storage,omitempty"`
+	} `yaml:"spec"`
+}
+
+// GenerateK0sControllerJoinConfig generates the K0s configuration of a
+// controller joining a cluster: the API, network and storage settings,
+// including the control plane and node-local load balancing
+func GenerateK0sControllerJoinConfig(cfg config.K0sConfig) ([]byte, error) {
+	k0sConfig := k0sJoinClusterConfig{
+		APIVersion: "k0s.k0sproject.io/v1beta1",
+		Kind:       "ClusterConfig",
+	}
+	k0sConfig.Metadata.Name = "k0s"
+	k0sConfig.Spec.API = apiSpec(cfg)
+	k0sConfig.Spec.Network = networkSpec(cfg.Network)
+	k0sConfig.Spec.Storage = storageSpec(cfg.Storage)
+
+	data, err := yaml.Marshal(k0sConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal K0s join config: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,209 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// protectedFields are the generated fields the spec overrides must not
+// change: k0rdentd finds, upgrades and removes the k0rdent chart by them
+var protectedFields = map[string]bool{
+	chartField("chartname"): true,
+	chartField("namespace"): true,
+	chartField("version"):   true,
+}
+
+// chartField returns the path of a field of the k0rdent chart
+func chartField(field string) string {
+	return fmt.Sprintf("spec.extensions.helm.charts[name=%s].%s", K0rdentHelmReleaseName, field)
+}
+
+// specConflict is a generated value changed by the spec overrides
+type specConflict struct {
+	path        string
+	description string
+}
+
+// SpecConflictError is returned when k0s.spec or k0s.configOverlay changes
+// settings of the k0rdent chart
+type SpecConflictError struct {
+	// Conflicts describes each conflicting field
+	Conflicts []string
+}
+
+// Error implements the error interface, listing every conflict on its own line
+func (e *SpecConflictError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("k0s spec overrides conflict with the k0rdent chart managed by k0rdentd:")
+	for _, conflict := range e.Conflicts {
+		sb.WriteString("\n  - ")
+		sb.WriteString(conflict)
+	}
+	return sb.String()
+}
+
+// marshalK0sConfig marshals the generated K0s configuration, deep-merging
+// the user provided spec overrides over it. Overridden generated values are
+// logged as warnings, only changes of the k0rdent chart are refused.
+func marshalK0sConfig(k0sConfig K0sClusterConfig, cfg config.K0sConfig) ([]byte, error) {
+	overlay, err := specOverlay(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(overlay) == 0 {
+		return yaml.Marshal(k0sConfig)
+	}
+
+	// Work on the generic form of the generated configuration
+	data, err := yaml.Marshal(k0sConfig)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = make(map[string]interface{})
+		doc["spec"] = spec
+	}
+
+	var conflicts []specConflict
+	mergeSpec(spec, overlay, "spec", &conflicts)
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].path < conflicts[j].path })
+	var refused []string
+	for _, conflict := range conflicts {
+		if protectedFields[conflict.path] {
+			refused = append(refused, conflict.description)
+			continue
+		}
+		utils.GetLogger().Warnf("⚠️  k0s spec override of a generated setting, %s", conflict.description)
+	}
+	if len(refused) > 0 {
+		return nil, &SpecConflictError{Conflicts: refused}
+	}
+
+	return yaml.Marshal(doc)
+}
+
+// specOverlay returns the spec overrides of the configuration: the spec of
+// the k0s.configOverlay file, then k0s.spec merged over it
+func specOverlay(cfg config.K0sConfig) (map[string]interface{}, error) {
+	overlay := make(map[string]interface{})
+
+	if cfg.ConfigOverlay != "" {
+		data, err := os.ReadFile(cfg.ConfigOverlay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read k0s config overlay: %w", err)
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse k0s config overlay %s: %w", cfg.ConfigOverlay, err)
+		}
+		spec, ok := doc["spec"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("k0s config overlay %s has no spec", cfg.ConfigOverlay)
+		}
+		mergeSpec(overlay, spec, "spec", nil)
+	}
+
+	if len(cfg.Spec) > 0 {
+		// Copy so the configuration itself is never modified by merges
+		data, err := yaml.Marshal(cfg.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy k0s.spec: %w", err)
+		}
+		var spec map[string]interface{}
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return nil, fmt.Errorf("failed to copy k0s.spec: %w", err)
+		}
+		mergeSpec(overlay, spec, "spec", nil)
+	}
+
+	return overlay, nil
+}
+
+// mergeSpec deep-merges src into dst. Maps are merged key by key, lists of
+// named objects (such as helm charts) are merged by name, anything else is
+// replaced. If conflicts is not nil, every value of dst changed by src is
+// recorded in it.
+func mergeSpec(dst, src map[string]interface{}, path string, conflicts *[]specConflict) {
+	for key, srcValue := range src {
+		keyPath := path + "." + key
+		dstValue, exists := dst[key]
+		if !exists {
+			dst[key] = srcValue
+			continue
+		}
+
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		if dstIsMap && srcIsMap {
+			mergeSpec(dstMap, srcMap, keyPath, conflicts)
+			continue
+		}
+
+		dstList, dstIsList := namedList(dstValue)
+		srcList, srcIsList := namedList(srcValue)
+		if dstIsList && srcIsList {
+			dst[key] = mergeNamedList(dstList, srcList, keyPath, conflicts)
+			continue
+		}
+
+		if conflicts != nil && !reflect.DeepEqual(dstValue, srcValue) {
+			*conflicts = append(*conflicts, specConflict{
+				path:        keyPath,
+				description: fmt.Sprintf("%s: k0rdentd sets %v, override sets %v", keyPath, dstValue, srcValue),
+			})
+		}
+		dst[key] = srcValue
+	}
+}
+
+// mergeNamedList merges the items of src into dst by name
+func mergeNamedList(dst, src []map[string]interface{}, path string, conflicts *[]specConflict) []interface{} {
+	for _, item := range src {
+		merged := false
+		for _, existing := range dst {
+			if existing["name"] == item["name"] {
+				mergeSpec(existing, item, fmt.Sprintf("%s[name=%v]", path, item["name"]), conflicts)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			dst = append(dst, item)
+		}
+	}
+
+	result := make([]interface{}, len(dst))
+	for idx, item := range dst {
+		result[idx] = item
+	}
+	return result
+}
+
+// namedList returns the items of a list whose items are all objects with a name
+func namedList(value interface{}) ([]map[string]interface{}, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok || itemMap["name"] == nil {
+			return nil, false
+		}
+		items = append(items, itemMap)
+	}
+	return items, true
+}
@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// K0rdentChartVersion returns the version of the k0rdent chart of a K0s
+// configuration
+func K0rdentChartVersion(data []byte) (string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse K0s config: %w", err)
+	}
+
+	for _, chart := range helmCharts(doc) {
+		if chart["name"] != K0rdentHelmReleaseName {
+			continue
+		}
+		if chart["version"] == nil {
+			return "", nil
+		}
+		return fmt.Sprint(chart["version"]), nil
+	}
+	return "", fmt.Errorf("no %s chart in K0s config", K0rdentHelmReleaseName)
+}
+
+// SetK0rdentChartVersion returns a K0s configuration with the version of the
+// k0rdent chart replaced. The rest of the configuration, including manual
+// edits, is kept in order.
+func SetK0rdentChartVersion(data []byte, version string) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse K0s config: %w", err)
+	}
+	charts, err := helmChartsNode(&root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chart := range charts.Content {
+		if name := mappingValue(chart, "name"); name == nil || name.Value != K0rdentHelmReleaseName {
+			continue
+		}
+		versionNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: version}
+		if current := mappingValue(chart, "version"); current != nil {
+			*current = *versionNode
+		} else {
+			chart.Content = append(chart.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}, versionNode)
+		}
+		return yaml.Marshal(&root)
+	}
+	return nil, fmt.Errorf("no %s chart in K0s config", K0rdentHelmReleaseName)
+}
+
+// RemoveK0rdentChart returns a K0s configuration without the k0rdent chart,
+// and whether it had one. The rest of the configuration is kept in order.
+func RemoveK0rdentChart(data []byte) ([]byte, bool, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, false, fmt.Errorf("failed to parse K0s config: %w", err)
+	}
+	charts, err := helmChartsNode(&root)
+	if err != nil {
+		return data, false, nil
+	}
+
+	for idx, chart := range charts.Content {
+		if name := mappingValue(chart, "name"); name == nil || name.Value != K0rdentHelmReleaseName {
+			continue
+		}
+		charts.Content = append(charts.Content[:idx], charts.Content[idx+1:]...)
+		updated, err := yaml.Marshal(&root)
+		if err != nil {
+			return nil, false, err
+		}
+		return updated, true, nil
+	}
+	return data, false, nil
+}
+
+// HasK0rdentChart tells whether a K0s configuration installs the k0rdent chart
+func HasK0rdentChart(data []byte) bool {
+	_, found, err := RemoveK0rdentChart(data)
+	return err == nil && found
+}
+
+// helmChartsNode returns the sequence of helm charts of a K0s configuration
+func helmChartsNode(root *yaml.Node) (*yaml.Node, error) {
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("K0s config is empty")
+	}
+
+	charts := root.Content[0]
+	for _, key := range []string{"spec", "extensions", "helm", "charts"} {
+		charts = mappingValue(charts, key)
+	}
+	if charts == nil || charts.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("no helm charts in K0s config")
+	}
+	return charts, nil
+}
+
+// mappingValue returns the value of key in a YAML mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx+1]
+		}
+	}
+	return nil
+}
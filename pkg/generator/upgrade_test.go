@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func TestSetK0rdentChartVersion(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	cfg := config.DefaultConfig()
+	cfg.K0rdent.Version = "1.1.0"
+	data, err := GenerateK0sConfig(cfg)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	version, err := K0rdentChartVersion(data)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(version).To(gomega.Equal("1.1.0"))
+
+	// Only the chart version changes
+	upgraded, err := SetK0rdentChartVersion(data, "1.2.2")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	cfg.K0rdent.Version = "1.2.2"
+	expected, err := GenerateK0sConfig(cfg)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(upgraded)).To(gomega.Equal(string(expected)))
+
+	version, err = K0rdentChartVersion(upgraded)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(version).To(gomega.Equal("1.2.2"))
+
+	_, err = SetK0rdentChartVersion([]byte("apiVersion: k0s.k0sproject.io/v1beta1\nspec: {}\n"), "1.2.2")
+	g.Expect(err).To(gomega.MatchError("no helm charts in K0s config"))
+	_, err = K0rdentChartVersion([]byte("spec: {}\n"))
+	g.Expect(err).To(gomega.MatchError("no kcm chart in K0s config"))
+}
+
+func TestRemoveK0rdentChart(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	data, err := GenerateK0sConfig(config.DefaultConfig())
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	removed, found, err := RemoveK0rdentChart(data)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(found).To(gomega.BeTrue())
+	_, err = K0rdentChartVersion(removed)
+	g.Expect(err).To(gomega.MatchError("no kcm chart in K0s config"))
+	g.Expect(string(removed)).To(gomega.ContainSubstring("repositories:"))
+
+	// Removing it again changes nothing
+	again, found, err := RemoveK0rdentChart(removed)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(found).To(gomega.BeFalse())
+	g.Expect(again).To(gomega.Equal(removed))
+
+	_, found, err = RemoveK0rdentChart([]byte("spec: {}\n"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(found).To(gomega.BeFalse())
+}
+
+func TestHasK0rdentChart(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	data, err := GenerateK0sConfig(config.DefaultConfig())
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(HasK0rdentChart(data)).To(gomega.BeTrue())
+
+	removed, _, err := RemoveK0rdentChart(data)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(HasK0rdentChart(removed)).To(gomega.BeFalse())
+	g.Expect(HasK0rdentChart([]byte("not: [yaml"))).To(gomega.BeFalse())
+}
@@ -0,0 +1,185 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// k0sReleaseArchitectures are the architectures of the k0s release binaries
+var k0sReleaseArchitectures = []string{"amd64", "arm64", "arm"}
+
+// UpgradeCluster upgrades every node of the cluster: k0s through a k0s
+// autopilot plan targeting all controllers and workers, then k0rdent as
+// Upgrade does
+func (i *Installer) UpgradeCluster(target Versions) error {
+	changes, err := i.prepareUpgrade(target)
+	if err != nil || changes == nil {
+		return err
+	}
+
+	// Without a k0s upgrade, only this controller restarts to read its configuration
+	if !changes.k0s {
+		if err := i.restartK0sForUpgrade(changes); err != nil {
+			return err
+		}
+		return i.finishUpgrade(changes)
+	}
+
+	// Reading the nodes doesn't change the cluster, it also runs in dry-run mode
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	controllers, err := i.k8sClient.ListControlNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(controllers) == 0 {
+		return fmt.Errorf("no controller is registered with k0s autopilot")
+	}
+	nodes, err := i.k8sClient.ListNodeNames(ctx)
+	if err != nil {
+		return err
+	}
+	workers := autopilotWorkers(controllers, nodes)
+
+	platforms, err := i.autopilotPlatforms(changes.target.K0s)
+	if err != nil {
+		return err
+	}
+
+	id := "k0rdentd-upgrade-" + changes.target.K0s
+	plan := k8sclient.NewAutopilotPlan(id, changes.target.K0s, platforms, controllers, workers)
+	description := fmt.Sprintf("Apply the autopilot plan %s upgrading k0s to %s on controllers %s",
+		id, changes.target.K0s, strings.Join(controllers, ", "))
+	if len(workers) > 0 {
+		description += " and workers " + strings.Join(workers, ", ")
+	}
+	if err := i.host.Do(description, func() error { return i.k8sClient.ApplyAutopilotPlan(ctx, plan) }); err != nil {
+		return err
+	}
+
+	if err := i.host.Do("Wait for the autopilot plan to upgrade every node", func() error { return i.watchAutopilotPlan(id) }); err != nil {
+		return err
+	}
+
+	// Autopilot restarted k0s on this controller, with the new k0rdent chart version
+	if err := i.host.Do("Wait for k0s to become ready", i.waitForK0sReady); err != nil {
+		return fmt.Errorf("k0s did not become ready: %w", err)
+	}
+	return i.finishUpgrade(changes)
+}
+
+// autopilotWorkers returns the nodes that are not controllers. Controllers
+// running a worker are upgraded as controllers.
+func autopilotWorkers(controllers, nodes []string) []string {
+	var workers []string
+	for _, node := range nodes {
+		if !slices.Contains(controllers, node) {
+			workers = append(workers, node)
+		}
+	}
+	return workers
+}
+
+// autopilotPlatforms returns the k0s binaries of the autopilot plan: the k0s
+// release binaries, or in airgap mode the embedded binary served by the
+// registry daemon
+func (i *Installer) autopilotPlatforms(version string) (map[string]k8sclient.AutopilotPlatform, error) {
+	platforms := make(map[string]k8sclient.AutopilotPlatform)
+
+	if !airgap.IsAirGap() {
+		for _, arch := range k0sReleaseArchitectures {
+			platforms["linux-"+arch] = k8sclient.AutopilotPlatform{
+				URL: fmt.Sprintf("https://github.com/k0sproject/k0s/releases/download/%s/k0s-%s-%s", version, version, arch),
+			}
+		}
+		return platforms, nil
+	}
+
+	registryAddr := airgap.NewInstaller(i.config, i.debug).GetRegistryAddress()
+	host, _, err := net.SplitHostPort(registryAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry address %s: %w", registryAddr, err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil, fmt.Errorf("the registry address %s is not reachable from the other nodes, set airgap.registry.address to an address they can reach", registryAddr)
+	}
+
+	name, checksum, err := airgap.K0sBinarySHA256()
+	if err != nil {
+		return nil, err
+	}
+	// The embedded k0s binary has the architecture of the airgap build
+	platforms["linux-"+runtime.GOARCH] = k8sclient.AutopilotPlatform{
+		URL:    "http://" + registryAddr + airgap.K0sBinaryURLPath + url.PathEscape(name),
+		SHA256: checksum,
+	}
+	return platforms, nil
+}
+
+// watchAutopilotPlan waits for the autopilot plan to complete, logging the
+// progress of each node
+func (i *Installer) watchAutopilotPlan(id string) error {
+	logger := utils.GetLogger()
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout(config.TimeoutAutopilot))
+	defer cancel()
+
+	planState := ""
+	nodeStates := make(map[string]string)
+	var planErr error
+	// The API is unavailable while controllers restart, errors are retried
+	err := utils.PollWithBackoff(ctx, func() (bool, error) {
+		status, err := i.k8sClient.GetAutopilotPlanStatus(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to read the autopilot plan status: %w", err)
+		}
+		if status.ID != id {
+			logger.Debugf("Autopilot plan %s is not applied yet", id)
+			return false, nil
+		}
+
+		if status.State != planState {
+			planState = status.State
+			logger.Infof("Autopilot plan %s: %s", id, planState)
+		}
+
+		upgraded := 0
+		for _, node := range status.Nodes {
+			if node.State == k8sclient.AutopilotNodeCompleted {
+				upgraded++
+			}
+		}
+		for _, node := range status.Nodes {
+			if nodeStates[node.Name] == node.State {
+				continue
+			}
+			nodeStates[node.Name] = node.State
+			logger.Infof("🔄 %s %s: %s (%d/%d nodes upgraded)", node.Role, node.Name, node.State, upgraded, len(status.Nodes))
+		}
+
+		if status.IsCompleted() {
+			logger.Infof("✅ k0s upgraded on all %d nodes", len(status.Nodes))
+			return true, nil
+		}
+		if status.IsFailed() {
+			planErr = fmt.Errorf("autopilot plan %s failed with state %s, inspect it with 'k0s kubectl get plan autopilot -o yaml'", id, status.State)
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timeout waiting for autopilot plan %s (state: %s): %w", id, planState, err)
+	}
+	return planErr
+}
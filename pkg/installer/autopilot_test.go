@@ -0,0 +1,73 @@
+package installer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// autopilotInstaller returns an installer whose cluster has the given
+// autopilot plan
+func autopilotInstaller(g *gomega.WithT, id, state string) *Installer {
+	plan := k8sclient.NewAutopilotPlan(id, "v1.33.1+k0s.0", nil, []string{"controller-0"}, []string{"worker-1"})
+	g.Expect(unstructured.SetNestedField(plan.Object, state, "status", "state")).To(gomega.Succeed())
+	g.Expect(unstructured.SetNestedSlice(plan.Object, []interface{}{
+		map[string]interface{}{
+			"k0supdate": map[string]interface{}{
+				"controllers": []interface{}{map[string]interface{}{"name": "controller-0", "state": "SignalCompleted"}},
+				"workers":     []interface{}{map[string]interface{}{"name": "worker-1", "state": "SignalCompleted"}},
+			},
+		},
+	}, "status", "commands")).To(gomega.Succeed())
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8sclient.AutopilotPlanGVR: "PlanList"}, plan)
+
+	inst := NewInstaller(false, false)
+	inst.k8sClient = k8sclient.NewFromClientsetAndDynamic(fake.NewSimpleClientset(), dynamicClient)
+	return inst
+}
+
+func TestWatchAutopilotPlan(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(autopilotInstaller(g, "upgrade-1", "Completed").watchAutopilotPlan("upgrade-1")).To(gomega.Succeed())
+
+	err := autopilotInstaller(g, "upgrade-1", "InconsistentTargets").watchAutopilotPlan("upgrade-1")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("autopilot plan upgrade-1 failed with state InconsistentTargets")))
+
+	// A previous plan isn't mistaken for the new one
+	inst := autopilotInstaller(g, "upgrade-0", "Completed")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		plan := k8sclient.NewAutopilotPlan("upgrade-1", "v1.33.1+k0s.0", nil, []string{"controller-0"}, nil)
+		g.Expect(unstructured.SetNestedField(plan.Object, "Completed", "status", "state")).To(gomega.Succeed())
+		g.Expect(inst.k8sClient.ApplyAutopilotPlan(context.Background(), plan)).To(gomega.Succeed())
+	}()
+	g.Expect(inst.watchAutopilotPlan("upgrade-1")).To(gomega.Succeed())
+}
+
+func TestAutopilotWorkers(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	workers := autopilotWorkers([]string{"controller-0", "controller-1"}, []string{"controller-0", "worker-0", "worker-1"})
+	g.Expect(workers).To(gomega.Equal([]string{"worker-0", "worker-1"}))
+	g.Expect(autopilotWorkers([]string{"controller-0"}, []string{"controller-0"})).To(gomega.BeEmpty())
+}
+
+func TestAutopilotPlatforms(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	platforms, err := NewInstaller(false, false).autopilotPlatforms("v1.33.1+k0s.0")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(platforms).To(gomega.HaveLen(3))
+	g.Expect(platforms["linux-amd64"].URL).To(gomega.Equal("https://github.com/k0sproject/k0s/releases/download/v1.33.1+k0s.0/k0s-v1.33.1+k0s.0-amd64"))
+}
@@ -0,0 +1,141 @@
+package installer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/k0s"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// Versions are the k0s and k0rdent versions of a node
+type Versions struct {
+	K0s     string
+	K0rdent string
+}
+
+// K0rdentCompatibility is the range of Kubernetes minor versions supported
+// by a k0rdent minor release
+type K0rdentCompatibility struct {
+	K0rdent       string // k0rdent minor version, e.g. 1.2
+	MinKubernetes string // oldest supported Kubernetes minor version, e.g. 1.31
+	MaxKubernetes string // newest supported Kubernetes minor version
+}
+
+// CompatibilityTable lists the supported k0rdent releases. Upgrades to
+// releases missing from the table are allowed with a warning.
+var CompatibilityTable = []K0rdentCompatibility{
+	{K0rdent: "1.0", MinKubernetes: "1.30", MaxKubernetes: "1.32"},
+	{K0rdent: "1.1", MinKubernetes: "1.30", MaxKubernetes: "1.33"},
+	{K0rdent: "1.2", MinKubernetes: "1.31", MaxKubernetes: "1.33"},
+}
+
+// CheckUpgradeCompatibility checks that upgrading from the current to the
+// target versions is supported: no downgrade, Kubernetes minor versions are
+// not skipped, and the target k0rdent supports the target Kubernetes
+func CheckUpgradeCompatibility(current, target Versions) error {
+	order, err := k0s.CompareVersions(target.K0s, current.K0s)
+	if err != nil {
+		return err
+	}
+	if order < 0 {
+		return fmt.Errorf("downgrading k0s from %s to %s is not supported", current.K0s, target.K0s)
+	}
+
+	currentK8s, err := k0s.ParseVersion(current.K0s)
+	if err != nil {
+		return err
+	}
+	targetK8s, err := k0s.ParseVersion(target.K0s)
+	if err != nil {
+		return err
+	}
+	if targetK8s.Major != currentK8s.Major || targetK8s.Minor > currentK8s.Minor+1 {
+		return fmt.Errorf("upgrading k0s from %s to %s skips Kubernetes minor versions, upgrade to v%d.%d first",
+			current.K0s, target.K0s, currentK8s.Major, currentK8s.Minor+1)
+	}
+
+	order, err = compareSemver(target.K0rdent, current.K0rdent)
+	if err != nil {
+		return err
+	}
+	if order < 0 {
+		return fmt.Errorf("downgrading k0rdent from %s to %s is not supported", current.K0rdent, target.K0rdent)
+	}
+
+	k0rdentMinor, err := minorVersion(target.K0rdent)
+	if err != nil {
+		return err
+	}
+	for _, entry := range CompatibilityTable {
+		if entry.K0rdent != k0rdentMinor {
+			continue
+		}
+		kubernetes := fmt.Sprintf("%d.%d", targetK8s.Major, targetK8s.Minor)
+		if below, _ := compareSemver(kubernetes, entry.MinKubernetes); below < 0 {
+			return fmt.Errorf("k0rdent %s requires Kubernetes %s or newer, k0s %s runs Kubernetes %s",
+				target.K0rdent, entry.MinKubernetes, target.K0s, kubernetes)
+		}
+		if above, _ := compareSemver(kubernetes, entry.MaxKubernetes); above > 0 {
+			return fmt.Errorf("k0rdent %s supports Kubernetes up to %s, k0s %s runs Kubernetes %s",
+				target.K0rdent, entry.MaxKubernetes, target.K0s, kubernetes)
+		}
+		return nil
+	}
+
+	utils.GetLogger().Warnf("⚠️  k0rdent %s is not in the compatibility table, its Kubernetes support is not checked", target.K0rdent)
+	return nil
+}
+
+// minorVersion returns the major.minor part of a version such as 1.2.2
+func minorVersion(version string) (string, error) {
+	parts, err := semverParts(version)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", parts[0], parts[1]), nil
+}
+
+// compareSemver compares two versions such as 1.2.2 or v1.2, ignoring
+// pre-release suffixes. Missing components are 0.
+func compareSemver(v1, v2 string) (int, error) {
+	parts1, err := semverParts(v1)
+	if err != nil {
+		return 0, err
+	}
+	parts2, err := semverParts(v2)
+	if err != nil {
+		return 0, err
+	}
+	for idx := range parts1 {
+		if parts1[idx] != parts2[idx] {
+			if parts1[idx] < parts2[idx] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// semverParts returns the major, minor and patch numbers of a version
+func semverParts(version string) ([3]int, error) {
+	var parts [3]int
+	trimmed := strings.TrimPrefix(version, "v")
+	if idx := strings.IndexAny(trimmed, "-+"); idx >= 0 {
+		trimmed = trimmed[:idx]
+	}
+	fields := strings.Split(trimmed, ".")
+	if trimmed == "" || len(fields) > 3 {
+		return parts, fmt.Errorf("invalid version %q (expected: 1.2.2)", version)
+	}
+	for idx, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, fmt.Errorf("invalid version %q (expected: 1.2.2)", version)
+		}
+		parts[idx] = number
+	}
+	return parts, nil
+}
@@ -0,0 +1,103 @@
+package installer
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestCheckUpgradeCompatibility(t *testing.T) {
+	current := Versions{K0s: "v1.32.4+k0s.0", K0rdent: "1.1.0"}
+
+	tests := []struct {
+		name   string
+		target Versions
+		err    string
+	}{
+		{
+			name:   "same versions",
+			target: current,
+		},
+		{
+			name:   "next Kubernetes minor and k0rdent release",
+			target: Versions{K0s: "v1.33.1+k0s.0", K0rdent: "1.2.2"},
+		},
+		{
+			name:   "k0rdent missing from the table",
+			target: Versions{K0s: "v1.33.1+k0s.0", K0rdent: "9.0.0"},
+		},
+		{
+			name:   "k0s downgrade",
+			target: Versions{K0s: "v1.31.0+k0s.0", K0rdent: "1.1.0"},
+			err:    "downgrading k0s from v1.32.4+k0s.0 to v1.31.0+k0s.0 is not supported",
+		},
+		{
+			name:   "skipped Kubernetes minor",
+			target: Versions{K0s: "v1.34.0+k0s.0", K0rdent: "1.1.0"},
+			err:    "upgrading k0s from v1.32.4+k0s.0 to v1.34.0+k0s.0 skips Kubernetes minor versions, upgrade to v1.33 first",
+		},
+		{
+			name:   "k0rdent downgrade",
+			target: Versions{K0s: "v1.32.4+k0s.0", K0rdent: "1.0.0"},
+			err:    "downgrading k0rdent from 1.1.0 to 1.0.0 is not supported",
+		},
+		{
+			name:   "k0rdent upgrade only",
+			target: Versions{K0s: "v1.32.4+k0s.0", K0rdent: "1.2.0"},
+		},
+		{
+			name:   "invalid k0rdent version",
+			target: Versions{K0s: "v1.32.4+k0s.0", K0rdent: "latest"},
+			err:    `invalid version "latest" (expected: 1.2.2)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			err := CheckUpgradeCompatibility(current, tt.target)
+			if tt.err == "" {
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).To(gomega.MatchError(tt.err))
+		})
+	}
+
+	g := gomega.NewWithT(t)
+	err := CheckUpgradeCompatibility(
+		Versions{K0s: "v1.33.1+k0s.0", K0rdent: "1.2.2"},
+		Versions{K0s: "v1.34.0+k0s.0", K0rdent: "1.2.2"},
+	)
+	g.Expect(err).To(gomega.MatchError("k0rdent 1.2.2 supports Kubernetes up to 1.33, k0s v1.34.0+k0s.0 runs Kubernetes 1.34"))
+
+	err = CheckUpgradeCompatibility(
+		Versions{K0s: "v1.30.2+k0s.0", K0rdent: "1.1.0"},
+		Versions{K0s: "v1.30.2+k0s.0", K0rdent: "1.2.0"},
+	)
+	g.Expect(err).To(gomega.MatchError("k0rdent 1.2.0 requires Kubernetes 1.31 or newer, k0s v1.30.2+k0s.0 runs Kubernetes 1.30"))
+}
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		name     string
+		v1       string
+		v2       string
+		expected int
+	}{
+		{name: "same versions", v1: "1.2.0", v2: "1.2.0", expected: 0},
+		{name: "v prefix", v1: "v1.2.0", v2: "1.2.0", expected: 0},
+		{name: "missing patch", v1: "1.2", v2: "1.2.0", expected: 0},
+		{name: "older", v1: "1.1.0", v2: "1.2.0", expected: -1},
+		{name: "newer", v1: "1.10.0", v2: "1.2.0", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			order, err := compareSemver(tt.v1, tt.v2)
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(order).To(gomega.Equal(tt.expected))
+		})
+	}
+}
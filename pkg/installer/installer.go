@@ -3,39 +3,83 @@ package installer
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/belgaied2/k0rdentd/internal/airgap"
 	"github.com/belgaied2/k0rdentd/pkg/config"
 	"github.com/belgaied2/k0rdentd/pkg/credentials"
+	"github.com/belgaied2/k0rdentd/pkg/events"
+	"github.com/belgaied2/k0rdentd/pkg/generator"
 	"github.com/belgaied2/k0rdentd/pkg/k0s"
 	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/belgaied2/k0rdentd/pkg/system"
 	"github.com/belgaied2/k0rdentd/pkg/utils"
 )
 
+// redactK0sConfig hides the secrets of k0s configurations in dry-run plans
+var redactK0sConfig = system.RedactWith(generator.RedactK0sConfigFile)
+
 // Installer handles the installation and uninstallation of K0s and K0rdent
 type Installer struct {
-	debug      bool
-	dryRun     bool
-	k8sClient  *k8sclient.Client
-	config     *config.K0rdentdConfig // Store full config for airgap support
-	airgapped  bool
-	replaceK0s bool // Replace existing k0s binary without prompting
+	debug        bool
+	dryRun       bool
+	host         system.Host  // Changes the node, records a plan in dry-run mode
+	plan         *system.Plan // Set in dry-run mode
+	k8sClient    *k8sclient.Client
+	config       *config.K0rdentdConfig // Store full config for airgap support
+	airgapped    bool
+	replaceK0s   bool            // Replace existing k0s binary without prompting
+	resume       bool            // Skip the install steps completed by a previous run
+	fromStep     string          // Restart the installation at this step
+	stateFile    string          // Records the progress of install
+	backupDir    string          // Backups made by upgrade
+	forceUpgrade bool            // Upgrade even if the compatibility check fails
+	bundlePath   string          // Airgap bundle pushed to the registry by upgrade
+	rollbackDir  string          // Backups of the files replaced by WithRollback
+	journal      *system.Journal // Records the changes to undo while WithRollback runs
+	// k0rdentChartAdded is set when install adds the kcm chart back to the
+	// k0s configuration, e.g. after uninstall --k0rdent-only. A running k0s
+	// only reads it when it restarts.
+	k0rdentChartAdded bool
+	k0sRestarted      bool             // Set when install restarted a running k0s
+	events            *events.Recorder // Progress events, nil when disabled
 }
 
-// NewInstaller creates a new installer instance
+// NewInstaller creates a new installer instance. In dry-run mode the
+// changes are recorded in a plan instead of being made.
 func NewInstaller(debug, dryRun bool) *Installer {
-	return &Installer{
-		debug:      debug,
-		dryRun:     dryRun,
-		airgapped:  false,
-		replaceK0s: false,
-	}
+	i := &Installer{
+		debug:       debug,
+		dryRun:      dryRun,
+		host:        system.NewHost(debug),
+		airgapped:   false,
+		replaceK0s:  false,
+		stateFile:   DefaultStateFile,
+		backupDir:   DefaultBackupDir,
+		rollbackDir: DefaultRollbackDir,
+	}
+	if dryRun {
+		i.plan = system.NewPlan()
+		i.host = i.plan
+	}
+	return i
+}
+
+// Host returns the host the installer changes, for changes made outside of it
+func (i *Installer) Host() system.Host {
+	return i.host
+}
+
+// Plan returns the changes recorded in dry-run mode, or nil
+func (i *Installer) Plan() *system.Plan {
+	return i.plan
 }
 
 // SetReplaceK0s sets whether to replace existing k0s binary without prompting
@@ -43,46 +87,57 @@ func (i *Installer) SetReplaceK0s(replace bool) {
 	i.replaceK0s = replace
 }
 
+// SetEvents sets the recorder of the progress events
+func (i *Installer) SetEvents(recorder *events.Recorder) {
+	i.events = recorder
+}
+
 // SetConfig sets the full configuration (needed for airgap support)
 func (i *Installer) SetConfig(cfg *config.K0rdentdConfig) {
 	i.config = cfg
 }
 
-// waitForWithSpinner waits for a condition with a spinner animation
+// timeout returns the timeout of a phase of the configuration, its default
+// without configuration
+func (i *Installer) timeout(phase string) time.Duration {
+	if i.config != nil {
+		return i.config.Timeouts.Timeout(phase)
+	}
+	return config.TimeoutsConfig{}.Timeout(phase)
+}
+
+// waitForWithSpinner runs wait with a spinner animation, failing when it
+// takes longer than timeout. resource names what is waited on in the
+// progress events.
 func (i *Installer) waitForWithSpinner(
 	timeout time.Duration,
+	resource string,
 	message string,
-	checkFunc func() (bool, error),
+	wait func(ctx context.Context) error,
 ) error {
-	tickerInterval := 100 * time.Millisecond
-	ticker := time.NewTicker(tickerInterval)
-	defer ticker.Stop()
+	started := time.Now()
+	i.events.Emit(events.Event{Type: events.WaitStarted, Resource: resource, Description: message})
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
 	stopSpinner := make(chan bool)
 	doneCh := make(chan bool)
-	timeoutChan := time.After(timeout)
-
 	go utils.RunWithSpinner(message, stopSpinner, doneCh)
 
-	for {
-		select {
-		case <-timeoutChan:
-			stopSpinner <- true
-			<-doneCh
-			return fmt.Errorf("timeout waiting for %s", message)
-		default:
-			ready, err := checkFunc()
-			if err != nil {
-				utils.GetLogger().Debugf("Check for %s failed: %v", message, err)
-				continue
-			}
-			if ready {
-				stopSpinner <- true
-				<-doneCh
-				return nil
-			}
-		}
+	err := wait(ctx)
+	stopSpinner <- err == nil
+	<-doneCh
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timeout waiting for %s after %v", message, timeout)
+	}
+	if err != nil {
+		i.events.Emit(events.Event{Type: events.WaitFailed, Resource: resource, DurationSeconds: events.Seconds(started), Error: err.Error()})
+		return err
 	}
+	i.events.Emit(events.Event{Type: events.WaitFinished, Resource: resource, DurationSeconds: events.Seconds(started)})
+	return nil
 }
 
 // Install installs K0s and K0rdent using the generated configuration
@@ -93,62 +148,126 @@ func (i *Installer) Install(k0sConfig []byte, k0rdentConfig *config.K0rdentConfi
 	}
 
 	// Online installation
-	if i.dryRun {
-		utils.GetLogger().Infof("📝 Dry run mode - showing what would be done:")
-		utils.GetLogger().Infof("1. Check k0s version conflicts")
-		utils.GetLogger().Infof("2. Write K0s configuration to /etc/k0s/k0s.yaml")
-		utils.GetLogger().Infof("3. Execute: k0s install --config /etc/k0s/k0s.yaml")
-		utils.GetLogger().Infof("4. Start K0s service")
-		utils.GetLogger().Infof("5. Wait for K0rdent to be installed")
-		if k0rdentConfig != nil && k0rdentConfig.Credentials.HasCredentials() {
-			utils.GetLogger().Infof("6. Create cloud provider credentials")
-		}
-		return nil
-	}
+	var steps []installStep
 
 	// Check for k0s version conflicts (online mode only)
 	if i.config != nil {
-		conflict, err := i.CheckK0sVersionConflict(i.config.K0s.Version)
-		if err != nil {
-			return fmt.Errorf("failed to check k0s version: %w", err)
-		}
-		if err := i.HandleVersionConflict(conflict); err != nil {
-			return fmt.Errorf("k0s version conflict: %w", err)
-		}
+		steps = append(steps, installStep{
+			name:        StepCheckVersion,
+			description: "Check k0s version conflicts",
+			inputs:      i.config.K0s.Version,
+			run: func() error {
+				conflict, err := i.CheckK0sVersionConflict(i.config.K0s.Version)
+				if err != nil {
+					return fmt.Errorf("failed to check k0s version: %w", err)
+				}
+				if err := i.HandleVersionConflict(conflict); err != nil {
+					return fmt.Errorf("k0s version conflict: %w", err)
+				}
+				return nil
+			},
+		})
 	}
 
-	// Write K0s configuration
-	if err := i.writeK0sConfig(k0sConfig); err != nil {
-		return fmt.Errorf("failed to write K0s config: %w", err)
+	// As for the credentials, the state file only holds a hash of the
+	// redacted configuration
+	k0sInputs, err := generator.RedactK0sConfig(k0sConfig)
+	if err != nil {
+		return fmt.Errorf("failed to redact the K0s config: %w", err)
 	}
 
-	// Install K0s
-	if err := i.installK0s(); err != nil {
-		return fmt.Errorf("failed to install K0s: %w", err)
-	}
+	steps = append(steps,
+		installStep{
+			name:        StepWriteConfig,
+			description: "Write K0s configuration to /etc/k0s/k0s.yaml",
+			inputs:      k0sInputs,
+			run: func() error {
+				if err := i.writeK0sConfig(k0sConfig); err != nil {
+					return fmt.Errorf("failed to write K0s config: %w", err)
+				}
+				return nil
+			},
+		},
+		installStep{
+			name:        StepInstallK0s,
+			description: "Execute k0s install and start the K0s service",
+			inputs:      k0sInputs,
+			run: func() error {
+				if err := i.installK0s(); err != nil {
+					return fmt.Errorf("failed to install K0s: %w", err)
+				}
+				return nil
+			},
+		},
+		installStep{
+			name:        StepWaitK0rdent,
+			description: "Wait for K0rdent to be installed",
+			inputs:      k0sInputs,
+			run: func() error {
+				if err := i.host.Do("Wait for the k0rdent Helm chart to be installed", i.waitForK0rdentInstalled); err != nil {
+					return fmt.Errorf("k0rdent Helm chart failed to install: %w", err)
+				}
+				return nil
+			},
+		},
+	)
+	steps = append(steps, i.credentialSteps(k0rdentConfig)...)
 
-	// Wait for k0rdent Helm chart to be installed
-	if err := i.waitForK0rdentInstalled(); err != nil {
-		return fmt.Errorf("k0rdent Helm chart failed to install: %w", err)
-	}
+	return i.runSteps(steps)
+}
 
-	// NEW: Wait for CAPI provider Helm releases if credentials are configured
-	if k0rdentConfig != nil && k0rdentConfig.Credentials.HasCredentials() {
-		if err := i.waitForCAPIProviderHelmReleases(&k0rdentConfig.Credentials); err != nil {
-			// Log warning but continue - credential creation will also warn
-			utils.GetLogger().Warnf("⚠️ CAPI infrastructure providers failed to become ready: %v. Will attempt credential creation anyway.", err)
-		}
+// credentialSteps returns the steps creating the cloud provider
+// credentials, if any are configured
+func (i *Installer) credentialSteps(k0rdentConfig *config.K0rdentConfig) []installStep {
+	if k0rdentConfig == nil || !k0rdentConfig.Credentials.HasCredentials() {
+		return nil
 	}
-
-	// Create credentials if configured - log warnings on failure but don't fail the installation
-	if k0rdentConfig != nil && k0rdentConfig.Credentials.HasCredentials() {
-		if err := i.createCredentials(&k0rdentConfig.Credentials); err != nil {
-			// Log warning but continue - user can still access K0rdent UI
-			utils.GetLogger().Warnf("⚠️ Failed to create credentials: %v. You may need to create them manually through the K0rdent UI.", err)
-		}
+	creds := &k0rdentConfig.Credentials
+	providers := i.getRequiredProviders(creds)
+	// The state file must not hold a hash of the secrets, which could be
+	// brute-forced, so rotating a secret doesn't run the step again on resume
+	redactedCreds, err := redactedCredentials(creds)
+	if err != nil {
+		utils.GetLogger().Debugf("Failed to redact the credentials: %v", err)
+		redactedCreds = creds.Names()
+	}
+
+	return []installStep{
+		{
+			name:        StepWaitProviders,
+			description: "Wait for the CAPI infrastructure providers",
+			inputs:      providers,
+			// Credential creation is attempted anyway
+			optional: true,
+			run: func() error {
+				description := "Wait for the CAPI infrastructure provider Helm releases: " + strings.Join(providers, ", ")
+				err := i.host.Do(description, func() error {
+					return i.waitForCAPIProviderHelmReleases(creds)
+				})
+				if err != nil {
+					return fmt.Errorf("CAPI infrastructure providers failed to become ready: %w. Will attempt credential creation anyway", err)
+				}
+				return nil
+			},
+		},
+		{
+			name:        StepCreateCredentials,
+			description: "Create cloud provider credentials",
+			inputs:      redactedCreds,
+			// The K0rdent UI is usable without credentials
+			optional: true,
+			run: func() error {
+				description := "Create cloud provider credentials: " + strings.Join(creds.Names(), ", ")
+				err := i.host.Do(description, func() error {
+					return i.createCredentials(creds)
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create credentials: %w. You may need to create them manually through the K0rdent UI", err)
+				}
+				return nil
+			},
+		},
 	}
-
-	return nil
 }
 
 // InstallJoin installs K0s as a joining node (controller or worker)
@@ -166,16 +285,15 @@ func (i *Installer) InstallJoin(joinConfig *config.JoinConfig) error {
 	logger.Infof("Joining cluster as %s node...", joinConfig.Mode)
 	logger.Infof("Controller server: %s", joinConfig.Server)
 
-	if i.dryRun {
-		logger.Info("📝 Dry run mode - join installation steps:")
-		logger.Infof("1. Extract k0s binary from embedded assets (if airgap)")
-		logger.Infof("2. Configure containerd mirrors (if airgap)")
-		logger.Infof("3. Write K0s configuration")
-		logger.Infof("4. Execute: k0s install %s --token-file <token>", joinConfig.Mode)
-		logger.Infof("5. Start K0s service")
-		logger.Infof("6. Wait for node to be ready")
-		return nil
-	}
+	_, err := i.runStep(StepJoin, fmt.Sprintf("Join the cluster as %s node", joinConfig.Mode), func() error {
+		return i.joinCluster(joinConfig)
+	})
+	return err
+}
+
+// joinCluster installs k0s with the join token and waits for it
+func (i *Installer) joinCluster(joinConfig *config.JoinConfig) error {
+	logger := utils.GetLogger()
 
 	// Handle airgap mode: extract k0s binary and configure containerd
 	if airgap.IsAirGap() {
@@ -186,6 +304,7 @@ func (i *Installer) InstallJoin(joinConfig *config.JoinConfig) error {
 
 		// Create airgap installer
 		agInstaller := airgap.NewInstaller(i.config, i.debug)
+		agInstaller.SetHost(i.host)
 
 		// Check for version mismatch between config and bundled version
 		metadata := airgap.GetBuildMetadata()
@@ -198,20 +317,20 @@ func (i *Installer) InstallJoin(joinConfig *config.JoinConfig) error {
 	}
 
 	// Write k0s config for join mode
-	if err := i.writeK0sJoinConfig(); err != nil {
+	if err := i.writeK0sJoinConfig(joinConfig.Mode); err != nil {
 		return fmt.Errorf("failed to write k0s config: %w", err)
 	}
 
 	// Create token file
 	tokenFile := "/etc/k0s/join-token"
-	if err := os.WriteFile(tokenFile, []byte(joinConfig.Token), 0600); err != nil {
+	if err := i.host.WriteFile(tokenFile, []byte(joinConfig.Token), 0600, system.Redacted()); err != nil {
 		return fmt.Errorf("failed to write token file: %w", err)
 	}
-	defer os.Remove(tokenFile) // Clean up token file after installation
+	// Clean up token file after installation
+	defer i.host.Remove(tokenFile, system.Redacted())
 
 	// Install k0s in join mode
 	// Note: k0s token includes server information, no --server flag needed
-	var stderrBuf bytes.Buffer
 	installArgs := []string{}
 	if joinConfig.Mode == "controller" {
 		installArgs = []string{
@@ -226,63 +345,59 @@ func (i *Installer) InstallJoin(joinConfig *config.JoinConfig) error {
 		}
 	}
 
-	cmd := exec.Command("k0s", installArgs...)
-	cmd.Stderr = &stderrBuf
-
-	if i.debug {
-		logger.Debugf("🔧 Executing: k0s %v", installArgs)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-	}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("k0s install failed: %w. stderr: %s", err, stderrBuf.String())
+	hadData := hasK0sData()
+	if err := i.host.Run("k0s", installArgs...); err != nil {
+		return fmt.Errorf("k0s install failed: %w", err)
 	}
+	i.k0sInstalled(fmt.Sprintf("k0s%s.service", joinConfig.Mode), hadData)
 
 	// Start k0s service
-	startCmd := exec.Command("k0s", "start")
-	var startStderrBuf bytes.Buffer
-	startCmd.Stderr = &startStderrBuf
-
-	if i.debug {
-		logger.Debug("🔧 Executing: k0s start")
-		startCmd.Stdout = os.Stdout
-		startCmd.Stderr = io.MultiWriter(os.Stderr, &startStderrBuf)
-	}
-
-	if err := startCmd.Run(); err != nil {
-		return fmt.Errorf("k0s start failed: %w. stderr: %s", err, startStderrBuf.String())
+	if err := i.host.Run("k0s", "start"); err != nil {
+		return fmt.Errorf("k0s start failed: %w", err)
 	}
 
 	// Wait for k0s to be ready
-	if err := i.waitForK0sReady(); err != nil {
+	if err := i.host.Do("Wait for k0s to become ready", i.waitForK0sReady); err != nil {
 		return fmt.Errorf("k0s did not become ready: %w", err)
 	}
 
-	logger.Infof("✅ Successfully joined cluster as %s", joinConfig.Mode)
+	if !i.dryRun {
+		logger.Infof("✅ Successfully joined cluster as %s", joinConfig.Mode)
+	}
 	return nil
 }
 
-// writeK0sJoinConfig writes a minimal k0s configuration for join mode
-func (i *Installer) writeK0sJoinConfig() error {
+// writeK0sJoinConfig writes the k0s configuration for join mode: minimal
+// for workers, with the shared API and load balancing settings for controllers
+func (i *Installer) writeK0sJoinConfig(mode string) error {
 	configPath := "/etc/k0s/k0s.yaml"
 
 	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+	if err := i.host.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	// Minimal k0s config for join mode
 	// k0s will get most config from the controller via the join token
-	minimalConfig := `apiVersion: k0s.k0sproject.io/v1beta1
+	joinConfig := []byte(`apiVersion: k0s.k0sproject.io/v1beta1
 kind: ClusterConfig
 metadata:
   name: k0s
 spec:
   # Configuration will be merged with controller settings
-`
+`)
+
+	// Controllers each run keepalived and serve the API certificate, so they
+	// need the same API and load balancing settings as the first controller
+	if mode == "controller" && i.config != nil {
+		generated, err := generator.GenerateK0sControllerJoinConfig(i.config.K0s)
+		if err != nil {
+			return err
+		}
+		joinConfig = generated
+	}
 
-	if err := os.WriteFile(configPath, []byte(minimalConfig), 0600); err != nil {
+	if err := i.host.WriteFile(configPath, joinConfig, 0600, redactK0sConfig); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -307,69 +422,84 @@ func (i *Installer) installAirgap(k0rdentConfig *config.K0rdentConfig) error {
 		CheckAirgapVersionMismatch(i.config.K0s.Version, metadata.K0sVersion)
 	}
 
-	if i.dryRun {
-		logger.Infof("📝 Dry run mode - airgap installation steps:")
-		logger.Infof("1. Check k0s version mismatch (config vs bundled)")
-		logger.Infof("2. Extract k0rdent version from bundle")
-		logger.Infof("3. Extract k0s binary from embedded assets")
-		logger.Infof("4. Generate k0s configuration for airgap mode")
-		logger.Infof("5. Install k0s with embedded binary")
-		logger.Infof("6. k0s will automatically install k0rdent from local registry via helm operator")
-		if k0rdentConfig != nil && k0rdentConfig.Credentials.HasCredentials() {
-			logger.Infof("7. Create cloud provider credentials")
-		}
-		return nil
-	}
-
 	// Ensure we have the full config for airgap
 	if i.config == nil {
 		return fmt.Errorf("airgap installation requires full configuration, but config is not set")
 	}
 
-	// Create airgap installer
-	agInstaller := airgap.NewInstaller(i.config, i.debug)
-
-	// Perform airgap-specific preparation (extract k0s, generate config)
-	ctx := context.Background()
-	if err := agInstaller.Install(ctx); err != nil {
-		return fmt.Errorf("airgap preparation failed: %w", err)
-	}
-
-	// Now proceed with standard k0s installation
-	// The k0s binary is now at /usr/local/bin/k0s
-	// The k0s config is at /etc/k0s/k0s.yaml with airgap settings
-	logger.Info("")
-	logger.Info("Installing k0s...")
-	if err := i.installK0s(); err != nil {
-		return fmt.Errorf("failed to install k0s: %w", err)
-	}
-
-	// Wait for k0rdent to be installed via k0s helm operator
-	if err := i.waitForK0rdentInstalled(); err != nil {
-		return fmt.Errorf("k0rdent installation failed: %w", err)
+	// The generated k0s configuration depends on the whole configuration
+	// and on the bundle, the state file only holds a hash of the redacted
+	// configuration
+	redactedConfig, err := i.config.Redacted()
+	if err != nil {
+		return fmt.Errorf("failed to redact the config: %w", err)
+	}
+	inputs := struct {
+		Config   *config.K0rdentdConfig
+		Metadata interface{}
+	}{redactedConfig, metadata}
+
+	steps := []installStep{
+		{
+			name:        StepPrepareAirgap,
+			description: "Extract k0s binary from embedded assets and generate the airgap K0s configuration",
+			inputs:      inputs,
+			run: func() error {
+				agInstaller := airgap.NewInstaller(i.config, i.debug)
+				agInstaller.SetHost(i.host)
+
+				// Perform airgap-specific preparation (extract k0s, generate config)
+				if err := agInstaller.Install(context.Background()); err != nil {
+					return fmt.Errorf("airgap preparation failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			// The k0s binary is now at /usr/local/bin/k0s
+			// The k0s config is at /etc/k0s/k0s.yaml with airgap settings
+			name:        StepInstallK0s,
+			description: "Install k0s with the embedded binary",
+			inputs:      inputs,
+			run: func() error {
+				logger.Info("")
+				logger.Info("Installing k0s...")
+				if err := i.installK0s(); err != nil {
+					return fmt.Errorf("failed to install k0s: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			// k0s installs k0rdent from the local registry via its helm operator
+			name:        StepWaitK0rdent,
+			description: "Wait for k0s to install k0rdent from the local registry",
+			inputs:      inputs,
+			run: func() error {
+				if err := i.host.Do("Wait for the k0rdent Helm chart to be installed", i.waitForK0rdentInstalled); err != nil {
+					return fmt.Errorf("k0rdent installation failed: %w", err)
+				}
+				return nil
+			},
+		},
 	}
+	steps = append(steps, i.credentialSteps(k0rdentConfig)...)
 
-	// Wait for CAPI provider Helm releases if credentials are configured
-	if k0rdentConfig != nil && k0rdentConfig.Credentials.HasCredentials() {
-		if err := i.waitForCAPIProviderHelmReleases(&k0rdentConfig.Credentials); err != nil {
-			logger.Warnf("⚠️ CAPI infrastructure providers failed to become ready: %v. Will attempt credential creation anyway.", err)
-		}
+	if err := i.runSteps(steps); err != nil {
+		return err
 	}
-
-	// Create credentials if configured
-	if k0rdentConfig != nil && k0rdentConfig.Credentials.HasCredentials() {
-		if err := i.createCredentials(&k0rdentConfig.Credentials); err != nil {
-			logger.Warnf("⚠️ Failed to create credentials: %v. You may need to create them manually through the K0rdent UI.", err)
-		}
+	if !i.dryRun {
+		logger.Info("✅ Airgap installation completed successfully")
 	}
-
-	logger.Info("✅ Airgap installation completed successfully")
 	return nil
 }
 
 // createCredentials creates cloud provider credentials in the k0rdent cluster
 func (i *Installer) createCredentials(credsConfig *config.CredentialsConfig) error {
 	utils.GetLogger().Debugf("Creating cloud provider credentials...")
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
 
 	credManager := credentials.NewManager(i.k8sClient)
 	ctx := context.Background()
@@ -385,7 +515,9 @@ func (i *Installer) createCredentials(credsConfig *config.CredentialsConfig) err
 
 // waitForCAPIProviderHelmReleases waits for required CAPI infrastructure provider Helm releases to be deployed
 func (i *Installer) waitForCAPIProviderHelmReleases(credsConfig *config.CredentialsConfig) error {
-	ctx := context.Background()
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
 
 	// Determine which providers are needed
 	providersNeeded := i.getRequiredProviders(credsConfig)
@@ -395,40 +527,38 @@ func (i *Installer) waitForCAPIProviderHelmReleases(credsConfig *config.Credenti
 		return nil
 	}
 
-	return i.waitForWithSpinner(
-		15*time.Minute,
+	releases := make([]string, 0, len(providersNeeded))
+	for _, provider := range providersNeeded {
+		releases = append(releases, "cluster-api-provider-"+provider)
+	}
+	err := i.waitForWithSpinner(
+		i.timeout(config.TimeoutProviders),
+		"helmreleases kcm-system/cluster-api-provider-"+strings.Join(providersNeeded, ","),
 		"Waiting for CAPI infrastructure providers to be deployed",
-		func() (bool, error) {
-			// Check if kcm-system namespace exists
-			exists, err := i.k8sClient.NamespaceExists(ctx, "kcm-system")
-			if err != nil {
-				utils.GetLogger().Debugf("kcm-system namespace check failed: %v", err)
-				return false, nil
-			}
-			if !exists {
-				utils.GetLogger().Debug("kcm-system namespace does not exist yet")
-				return false, nil
-			}
-
-			// Check if all required provider Helm releases are deployed
-			for _, provider := range providersNeeded {
-				releaseName := fmt.Sprintf("cluster-api-provider-%s", provider)
-				ready, err := i.k8sClient.IsHelmReleaseReady(ctx, "kcm-system", releaseName)
-				if err != nil {
-					utils.GetLogger().Debugf("Provider %s Helm release check failed: %v", provider, err)
-					return false, nil
-				}
-				if !ready {
-					utils.GetLogger().Debugf("Provider %s Helm release is not ready yet", provider)
-					return false, nil
-				}
-				utils.GetLogger().Debugf("Provider %s Helm release is deployed", provider)
-			}
-
-			utils.GetLogger().Info("All required CAPI infrastructure provider Helm releases are deployed")
-			return true, nil
+		func(ctx context.Context) error {
+			return i.k8sClient.WaitForHelmReleasesDeployed(ctx, "kcm-system", releases)
 		},
 	)
+	if err != nil {
+		return err
+	}
+
+	utils.GetLogger().Info("All required CAPI infrastructure provider Helm releases are deployed")
+	return nil
+}
+
+// redactedCredentials returns a copy of creds with the secrets redacted
+func redactedCredentials(creds *config.CredentialsConfig) (interface{}, error) {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+	var redacted config.CredentialsConfig
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		return nil, err
+	}
+	config.Redact(&redacted)
+	return redacted, nil
 }
 
 // getRequiredProviders returns a list of provider types that are needed based on credentials config
@@ -454,46 +584,26 @@ func (i *Installer) getRequiredProviders(credsConfig *config.CredentialsConfig)
 	return result
 }
 
-// Uninstall uninstalls K0s and K0rdent
-func (i *Installer) Uninstall() error {
-	if i.dryRun {
-		utils.GetLogger().Infof("📝 Dry run mode - showing what would be done:")
-		utils.GetLogger().Infof("1. Execute: k0s reset")
-		utils.GetLogger().Infof("2. Remove K0s configuration files")
-		return nil
-	}
-
-	// Stop K0s if it started
-	if isK0sStarted() {
-		if err := i.stopK0s(); err != nil {
-			return fmt.Errorf("failed to stop K0s: %w", err)
-		}
-	}
-
-	// Reset K0s
-	if err := i.resetK0s(); err != nil {
-		return fmt.Errorf("failed to reset K0s: %w", err)
-	}
-
-	// Remove configuration files
-	if err := i.cleanupConfig(); err != nil {
-		return fmt.Errorf("failed to cleanup config: %w", err)
-	}
-
-	return nil
-}
-
 // writeK0sConfig writes K0s configuration to file
 func (i *Installer) writeK0sConfig(config []byte) error {
 	configPath := "/etc/k0s/k0s.yaml"
 
 	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+	if err := i.host.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	if existing, err := os.ReadFile(configPath); err == nil && !bytes.Equal(existing, config) {
+		if !generator.HasK0rdentChart(existing) && generator.HasK0rdentChart(config) {
+			i.k0rdentChartAdded = true
+			i.k0sConfigReplaced()
+		} else if k0sServiceRunning() {
+			utils.GetLogger().Warnf("⚠️  %s changed, the running k0s applies it once restarted with 'k0s stop && k0s start'", configPath)
+		}
+	}
+
 	// Write configuration file
-	if err := os.WriteFile(configPath, config, 0600); err != nil {
+	if err := i.host.WriteFile(configPath, config, 0600, redactK0sConfig); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -507,42 +617,52 @@ func (i *Installer) writeK0sConfig(config []byte) error {
 // installK0s installs K0s using the generated configuration
 func (i *Installer) installK0s() error {
 	// Check if k0s is already installed and running
-	if isK0sInstalled() && isK0sRunning() {
-		utils.GetLogger().Info("✅ K0s is already installed and running, skipping installation")
-
-		// Initialize Kubernetes client
-		utils.GetLogger().Debug("Initializing Kubernetes client...")
-		client, err := k8sclient.NewFromK0s()
-		if err != nil {
-			return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	if k0sServiceInstalled() && k0sServiceRunning() {
+		if i.k0rdentChartAdded {
+			// Restarting k0s interrupts its API, which k0rdent doesn't serve yet
+			utils.GetLogger().Info("The kcm chart was added to the K0s configuration, restarting K0s to install k0rdent...")
+			i.k0sRestarted = true
+			if err := i.stopK0s(); err != nil {
+				return err
+			}
+			return i.startK0s()
 		}
-		i.k8sClient = client
-		utils.GetLogger().Debug("Kubernetes client initialized successfully")
-		return nil
+		utils.GetLogger().Info("✅ K0s is already installed and running, skipping installation")
+		return i.connectK8sClient()
 	}
 
 	// Check if k0s is installed but not running
-	if isK0sInstalled() && !isK0sRunning() {
+	if k0sServiceInstalled() && !k0sServiceRunning() {
 		utils.GetLogger().Info("K0s is installed but not running, starting K0s...")
-		startCmd := exec.Command("k0s", "start")
-		var startStderrBuf bytes.Buffer
-		startCmd.Stderr = &startStderrBuf
-
-		if i.debug {
-			utils.GetLogger().Debug("🔧 Executing: k0s start")
-			startCmd.Stdout = os.Stdout
-			startCmd.Stderr = io.MultiWriter(os.Stderr, &startStderrBuf)
-		}
+		i.k0sStarted()
+		return i.startK0s()
+	}
 
-		if err := startCmd.Run(); err != nil {
-			return fmt.Errorf("Command \"k0s start\" failed: %w. stderr: %s", err, startStderrBuf.String())
-		}
-		err := i.waitForK0sReady()
-		if err != nil {
-			return fmt.Errorf("Command \"k0s start\" was successful, but k0s never became ready")
-		}
+	// K0s is not installed, proceed with installation
+	hadData := hasK0sData()
+	if err := i.host.Run("k0s", "install", "controller", "--enable-worker", "--no-taints"); err != nil {
+		return fmt.Errorf("Command \"k0s install\" failed: %w", err)
+	}
+	i.k0sInstalled("k0scontroller.service", hadData)
+	return i.startK0s()
+}
+
+// startK0s starts the K0s service, waits for it and connects to the cluster
+func (i *Installer) startK0s() error {
+	if err := i.host.Run("k0s", "start"); err != nil {
+		return fmt.Errorf("Command \"k0s start\" failed: %w", err)
+	}
+	if err := i.host.Do("Wait for k0s to become ready", i.waitForK0sReady); err != nil {
+		return fmt.Errorf("Command \"k0s start\" was successful, but k0s never became ready")
+	}
+
+	// Initialize Kubernetes client after k0s is ready
+	return i.connectK8sClient()
+}
 
-		// Initialize Kubernetes client after k0s is ready
+// connectK8sClient initializes the Kubernetes client from the k0s kubeconfig
+func (i *Installer) connectK8sClient() error {
+	return i.host.Do("Connect to the Kubernetes API", func() error {
 		utils.GetLogger().Debug("Initializing Kubernetes client...")
 		client, err := k8sclient.NewFromK0s()
 		if err != nil {
@@ -551,53 +671,29 @@ func (i *Installer) installK0s() error {
 		i.k8sClient = client
 		utils.GetLogger().Debug("Kubernetes client initialized successfully")
 		return nil
-	}
-
-	// K0s is not installed, proceed with installation
-	var stderrBuf bytes.Buffer
-	cmd := exec.Command("k0s", "install", "controller", "--enable-worker", "--no-taints")
-	cmd.Stderr = &stderrBuf
-
-	if i.debug {
-		utils.GetLogger().Debug("🔧 Executing: k0s install")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-	}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("Command \"k0s install\" failed: %w. stderr: %s", err, stderrBuf.String())
-	}
-
-	startCmd := exec.Command("k0s", "start")
-	var startStderrBuf bytes.Buffer
-	startCmd.Stderr = &startStderrBuf
-
-	if i.debug {
-		utils.GetLogger().Debug("🔧 Executing: k0s start")
-		startCmd.Stdout = os.Stdout
-		startCmd.Stderr = io.MultiWriter(os.Stderr, &startStderrBuf)
-	}
+	})
+}
 
-	if err := startCmd.Run(); err != nil {
-		return fmt.Errorf("Command \"k0s start\" failed: %w. stderr: %s", err, startStderrBuf.String())
-	}
-	err := i.waitForK0sReady()
-	if err != nil {
-		return fmt.Errorf("Command \"k0s start\" was successful, but k0s never became ready")
+// ensureK8sClient initializes the Kubernetes client when installK0s didn't,
+// e.g. when install resumes after the install-k0s step
+func (i *Installer) ensureK8sClient() error {
+	if i.k8sClient != nil {
+		return nil
 	}
-
-	// Initialize Kubernetes client after k0s is ready
-	utils.GetLogger().Debug("Initializing Kubernetes client...")
 	client, err := k8sclient.NewFromK0s()
 	if err != nil {
 		return fmt.Errorf("failed to create Kubernetes client: %w", err)
 	}
 	i.k8sClient = client
-	utils.GetLogger().Debug("Kubernetes client initialized successfully")
-
 	return nil
 }
 
+// Probes of the k0s service used by installK0s, variables for tests
+var (
+	k0sServiceInstalled = isK0sInstalled
+	k0sServiceRunning   = isK0sRunning
+)
+
 // isK0sInstalled checks if k0s is installed by checking if the config file exists
 func isK0sInstalled() bool {
 
@@ -623,18 +719,8 @@ func isK0sStarted() bool {
 
 // stopK0s stops the K0s service
 func (i *Installer) stopK0s() error {
-	var stderrBuf bytes.Buffer
-	cmd := exec.Command("k0s", "stop")
-	cmd.Stderr = &stderrBuf
-
-	if i.debug {
-		utils.GetLogger().Debug("🔧 Executing: k0s stop")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-	}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("k0s stop failed: %w. stderr: %s", err, stderrBuf.String())
+	if err := i.host.Run("k0s", "stop"); err != nil {
+		return fmt.Errorf("k0s stop failed: %w", err)
 	}
 
 	if i.debug {
@@ -647,10 +733,13 @@ func (i *Installer) stopK0s() error {
 // waitForK0sReady waits for k0s to be ready by checking its status
 func (i *Installer) waitForK0sReady() error {
 	return i.waitForWithSpinner(
-		5*time.Minute,
+		i.timeout(config.TimeoutK0sReady),
+		"k0s",
 		"Waiting for k0s to become ready",
-		func() (bool, error) {
-			return isK0sStarted(), nil
+		func(ctx context.Context) error {
+			return utils.PollWithBackoff(ctx, func() (bool, error) {
+				return isK0sStarted(), nil
+			})
 		},
 	)
 }
@@ -658,6 +747,9 @@ func (i *Installer) waitForK0sReady() error {
 // waitForK0rdentInstalled waits for the k0rdent Helm chart to be installed
 func (i *Installer) waitForK0rdentInstalled() error {
 	ctx := context.Background()
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
 
 	// First check if K0rdent is already ready
 	exists, err := i.k8sClient.NamespaceExists(ctx, "kcm-system")
@@ -670,39 +762,22 @@ func (i *Installer) waitForK0rdentInstalled() error {
 	}
 
 	return i.waitForWithSpinner(
-		15*time.Minute,
+		i.timeout(config.TimeoutK0rdentReady),
+		"deployments kcm-system",
 		"Waiting for K0rdent to become ready",
-		func() (bool, error) {
-			// Check if kcm-system namespace exists
-			exists, err := i.k8sClient.NamespaceExists(ctx, "kcm-system")
-			if err != nil {
-				utils.GetLogger().Debugf("kcm-system namespace check failed: %v", err)
-				return false, nil
-			}
-			if !exists {
-				utils.GetLogger().Debug("kcm-system namespace does not exist yet")
-				return false, nil
-			}
-
-			// Check if all required deployments are ready
-			allReady, err := i.areK0rdentDeploymentsReady()
-			if err != nil {
-				utils.GetLogger().Warnf("deployment readiness check failed: %v", err)
-				return false, nil
-			}
-
-			if allReady {
-				utils.GetLogger().Debug("All K0rdent deployments are ready")
-			}
-
-			return allReady, nil
+		func(ctx context.Context) error {
+			return i.k8sClient.WaitForDeploymentsReady(ctx, "kcm-system", i.k0rdentDeployments())
 		},
 	)
 }
 
 // areK0rdentDeploymentsReady checks if all required K0rdent deployments are ready
 func (i *Installer) areK0rdentDeploymentsReady() (bool, error) {
-	ctx := context.Background()
+	return i.k8sClient.AreAllDeploymentsReady(context.Background(), "kcm-system", i.k0rdentDeployments())
+}
+
+// k0rdentDeployments returns the deployments of a ready k0rdent
+func (i *Installer) k0rdentDeployments() []string {
 	requiredDeployments := []string{
 		"kcm-cert-manager",
 		"kcm-cert-manager-cainjector",
@@ -715,24 +790,13 @@ func (i *Installer) areK0rdentDeploymentsReady() (bool, error) {
 	if !i.airgapped {
 		requiredDeployments = append(requiredDeployments, "kcm-regional-telemetry")
 	}
-
-	return i.k8sClient.AreAllDeploymentsReady(ctx, "kcm-system", requiredDeployments)
+	return requiredDeployments
 }
 
 // resetK0s resets K0s installation
 func (i *Installer) resetK0s() error {
-	var stderrBuf bytes.Buffer
-	cmd := exec.Command("k0s", "reset")
-	cmd.Stderr = &stderrBuf
-
-	if i.debug {
-		utils.GetLogger().Debug("🔧 Executing: k0s reset")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-	}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("k0s reset failed: %v. stderr: %s", err, stderrBuf.String())
+	if err := i.host.Run("k0s", "reset"); err != nil {
+		return fmt.Errorf("k0s reset failed: %v", err)
 	}
 
 	if i.debug {
@@ -742,25 +806,6 @@ func (i *Installer) resetK0s() error {
 	return nil
 }
 
-// cleanupConfig removes configuration files
-func (i *Installer) cleanupConfig() error {
-	configFiles := []string{
-		"/etc/k0s/k0s.yaml",
-		"/etc/k0rdentd/k0rdentd.yaml",
-	}
-
-	for _, file := range configFiles {
-		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to remove %s: %w", file, err)
-		}
-		if i.debug {
-			utils.GetLogger().Debugf("🗑️  Removed %s", file)
-		}
-	}
-
-	return nil
-}
-
 // CheckK0sVersionConflict checks for k0s version conflicts between installed and configured versions
 // Returns a VersionConflict if there's a conflict, nil otherwise
 func (i *Installer) CheckK0sVersionConflict(configVersion string) (*k0s.VersionConflict, error) {
@@ -844,7 +889,7 @@ func (i *Installer) replaceK0sBinary(version string) error {
 	logger := utils.GetLogger()
 
 	logger.Infof("Downloading and installing k0s version %s...", version)
-	if err := k0s.InstallK0sVersion(version); err != nil {
+	if err := i.downloadK0s(version); err != nil {
 		return fmt.Errorf("failed to replace k0s: %w", err)
 	}
 
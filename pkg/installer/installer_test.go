@@ -0,0 +1,94 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/events"
+	"github.com/onsi/gomega"
+)
+
+func TestWaitForWithSpinner(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var out bytes.Buffer
+	inst := NewInstaller(false, false)
+	inst.SetEvents(events.NewRecorder(&out))
+
+	g.Expect(inst.waitForWithSpinner(time.Second, "k0s", "k0s", func(ctx context.Context) error {
+		return nil
+	})).To(gomega.Succeed())
+
+	err := inst.waitForWithSpinner(10*time.Millisecond, "deployments kcm-system", "k0rdent", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Expect(err).To(gomega.MatchError("timeout waiting for k0rdent after 10ms"))
+
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event events.Event
+		g.Expect(json.Unmarshal([]byte(line), &event)).To(gomega.Succeed())
+		types = append(types, string(event.Type)+" "+event.Resource)
+	}
+	g.Expect(types).To(gomega.Equal([]string{
+		"wait_started k0s", "wait_finished k0s",
+		"wait_started deployments kcm-system", "wait_failed deployments kcm-system",
+	}))
+}
+
+func TestTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	inst := NewInstaller(false, false)
+	g.Expect(inst.timeout(config.TimeoutK0rdentReady)).To(gomega.Equal(15 * time.Minute))
+
+	cfg := config.DefaultConfig()
+	cfg.Timeouts.K0rdentReady = "40m"
+	inst.SetConfig(cfg)
+	g.Expect(inst.timeout(config.TimeoutK0rdentReady)).To(gomega.Equal(40 * time.Minute))
+	g.Expect(inst.timeout(config.TimeoutK0sReady)).To(gomega.Equal(5 * time.Minute))
+}
+
+func TestRedactedCredentials(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	creds := &config.CredentialsConfig{AWS: []config.AWSCredential{
+		{Name: "aws", Region: "us-east-1", AccessKeyID: "AKIA", SecretAccessKey: "secret"},
+	}}
+	redacted, err := redactedCredentials(creds)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(redacted).To(gomega.Equal(config.CredentialsConfig{AWS: []config.AWSCredential{
+		{Name: "aws", Region: "us-east-1", AccessKeyID: "AKIA", SecretAccessKey: config.RedactedValue},
+	}}))
+	g.Expect(creds.AWS[0].SecretAccessKey).To(gomega.Equal("secret"))
+}
+
+func TestInstallK0sRunning(t *testing.T) {
+	t.Run("should restart k0s when the kcm chart was added back", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		stubK0sService(t, true, true)
+
+		inst := NewInstaller(false, true)
+		inst.k0rdentChartAdded = true
+		g.Expect(inst.installK0s()).To(gomega.Succeed())
+		g.Expect(planSteps(inst.Plan())).To(gomega.Equal([]string{"k0s stop", "k0s start"}))
+		g.Expect(inst.k0sRestarted).To(gomega.BeTrue())
+	})
+
+	t.Run("should keep k0s running otherwise", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		stubK0sService(t, true, true)
+
+		inst := NewInstaller(false, true)
+		g.Expect(inst.installK0s()).To(gomega.Succeed())
+		g.Expect(planSteps(inst.Plan())).To(gomega.BeEmpty())
+		g.Expect(inst.k0sRestarted).To(gomega.BeFalse())
+	})
+}
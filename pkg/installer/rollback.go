@@ -0,0 +1,160 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/k0s"
+	"github.com/belgaied2/k0rdentd/pkg/system"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// DefaultRollbackDir keeps the files replaced by an install run with
+// --rollback-on-failure, until the run ends
+const DefaultRollbackDir = "/var/lib/k0rdentd/rollback"
+
+// k0sBinaryPath is where get.k0s.sh and the airgap installer put k0s
+const k0sBinaryPath = "/usr/local/bin/k0s"
+
+// k0sDataDir is where k0s keeps the cluster state
+const k0sDataDir = "/var/lib/k0s"
+
+// WithRollback runs fn and, if it fails, undoes the changes it made to the
+// node in reverse order. Files that existed before are restored, and k0s is
+// reset only if it had no data before.
+func (i *Installer) WithRollback(fn func() error) error {
+	// Nothing is changed in dry-run mode
+	if i.dryRun {
+		return fn()
+	}
+	logger := utils.GetLogger()
+
+	dir := filepath.Join(i.rollbackDir, time.Now().UTC().Format("20060102-150405"))
+	journal := system.NewJournal(i.host, dir)
+	previous := i.host
+	i.host, i.journal = journal, journal
+	defer func() {
+		i.host, i.journal = previous, nil
+	}()
+
+	// A rolled back run must not be resumed
+	if err := journal.Preserve(i.stateFile); err != nil {
+		return err
+	}
+
+	err := fn()
+	if err == nil {
+		if err := journal.Discard(); err != nil {
+			logger.Warnf("⚠️  Failed to remove the rollback backups in %s: %v", dir, err)
+		}
+		return nil
+	}
+
+	logger.Warnf("⚠️  Installation failed, rolling back its changes: %v", err)
+	if rollbackErr := journal.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("%w (rollback incomplete, the replaced files are kept in %s: %v)", err, dir, rollbackErr)
+	}
+	if err := journal.Discard(); err != nil {
+		logger.Warnf("⚠️  Failed to remove the rollback backups in %s: %v", dir, err)
+	}
+	logger.Info("↩️  Changes of the installation rolled back")
+	return err
+}
+
+// DownloadK0s installs k0s with get.k0s.sh, the latest version if version
+// is empty
+func (i *Installer) DownloadK0s(version string) error {
+	_, err := i.runStep(StepDownloadK0s, downloadDescription(version), func() error {
+		return i.downloadK0s(version)
+	})
+	return err
+}
+
+// downloadK0s runs get.k0s.sh, within the check-version step when k0s is
+// replaced
+func (i *Installer) downloadK0s(version string) error {
+	// The script replaces the binary outside of the host
+	if err := i.preserve(k0sBinaryPath); err != nil {
+		return err
+	}
+	return i.host.Do(downloadDescription(version), func() error {
+		return k0s.InstallK0sVersion(version)
+	})
+}
+
+// downloadDescription describes the get.k0s.sh run installing version
+func downloadDescription(version string) string {
+	if version == "" {
+		return "Download and install the latest k0s from get.k0s.sh"
+	}
+	return fmt.Sprintf("Download and install k0s %s from get.k0s.sh", version)
+}
+
+// preserve backs up a file changed outside of the host, when rolling back
+func (i *Installer) preserve(path string) error {
+	if i.journal == nil {
+		return nil
+	}
+	return i.journal.Preserve(path)
+}
+
+// k0sInstalled registers how to undo k0s install, when rolling back. Call
+// hasK0sData before k0s install.
+func (i *Installer) k0sInstalled(service string, hadData bool) {
+	if i.journal == nil {
+		return
+	}
+	i.journal.OnUndo("remove the k0s service "+service, func(host system.Host) error {
+		// k0s may not have started
+		if err := host.Run("k0s", "stop"); err != nil {
+			utils.GetLogger().Debugf("k0s stop failed: %v", err)
+		}
+		if !hadData {
+			return host.Run("k0s", "reset")
+		}
+		// k0s reset would delete the data of the previous installation
+		if err := host.Remove(filepath.Join("/etc/systemd/system", service)); err != nil {
+			return err
+		}
+		return host.Run("systemctl", "daemon-reload")
+	})
+}
+
+// k0sStarted registers how to stop again the k0s service install started,
+// when rolling back
+func (i *Installer) k0sStarted() {
+	if i.journal == nil {
+		return
+	}
+	i.journal.OnUndo("stop k0s, it was stopped before the installation", func(host system.Host) error {
+		return host.Run("k0s", "stop")
+	})
+}
+
+// k0sConfigReplaced registers how to restart k0s on the restored k0s
+// configuration if install restarted it on the new one, when rolling back.
+// Call it before writing the configuration, so that it is undone after the
+// configuration is restored.
+func (i *Installer) k0sConfigReplaced() {
+	if i.journal == nil {
+		return
+	}
+	i.journal.OnUndo("restart k0s on the restored configuration, if the installation restarted it", func(host system.Host) error {
+		if !i.k0sRestarted {
+			return nil
+		}
+		// The restart may have failed after k0s stopped
+		if err := host.Run("k0s", "stop"); err != nil {
+			utils.GetLogger().Debugf("k0s stop failed: %v", err)
+		}
+		return host.Run("k0s", "start")
+	})
+}
+
+// hasK0sData tells whether the k0s data dir holds a previous installation
+func hasK0sData() bool {
+	entries, err := os.ReadDir(k0sDataDir)
+	return err == nil && len(entries) > 0
+}
@@ -0,0 +1,150 @@
+package installer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/system"
+	"github.com/onsi/gomega"
+)
+
+func TestWithRollback(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.json")
+	written := filepath.Join(dir, "k0s", "k0s.yaml")
+
+	newInstaller := func() *Installer {
+		inst := NewInstaller(false, false)
+		inst.SetStateFile(stateFile)
+		inst.rollbackDir = filepath.Join(dir, "rollback")
+		return inst
+	}
+	install := func(inst *Installer, fail bool) func() error {
+		return func() error {
+			if err := inst.Host().MkdirAll(filepath.Dir(written), 0755); err != nil {
+				return err
+			}
+			if err := inst.Host().WriteFile(written, []byte("config"), 0600); err != nil {
+				return err
+			}
+			if err := inst.runSteps(recordingSteps(new([]string), "", nil)); err != nil {
+				return err
+			}
+			if fail {
+				return errors.New("timeout waiting for k0rdent")
+			}
+			return nil
+		}
+	}
+
+	// A failed install leaves nothing behind
+	inst := newInstaller()
+	err := inst.WithRollback(install(inst, true))
+	g.Expect(err).To(gomega.MatchError("timeout waiting for k0rdent"))
+	g.Expect(filepath.Dir(written)).ToNot(gomega.BeAnExistingFile())
+	g.Expect(stateFile).ToNot(gomega.BeAnExistingFile())
+	g.Expect(inst.journal).To(gomega.BeNil())
+
+	// A successful install keeps its changes
+	inst = newInstaller()
+	g.Expect(inst.WithRollback(install(inst, false))).To(gomega.Succeed())
+	g.Expect(written).To(gomega.BeAnExistingFile())
+	g.Expect(stateFile).To(gomega.BeAnExistingFile())
+
+	// The files of a previous install are restored
+	previous, err := os.ReadFile(stateFile)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	inst = newInstaller()
+	g.Expect(inst.WithRollback(install(inst, true))).ToNot(gomega.Succeed())
+	restored, err := os.ReadFile(stateFile)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(restored).To(gomega.Equal(previous))
+	g.Expect(written).To(gomega.BeAnExistingFile())
+
+	// The backups are removed once the run ends
+	entries, err := os.ReadDir(filepath.Join(dir, "rollback"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(entries).To(gomega.BeEmpty())
+}
+
+// stubK0sService makes installK0s see a k0s service in the given state
+func stubK0sService(t *testing.T, installed, running bool) {
+	previousInstalled, previousRunning := k0sServiceInstalled, k0sServiceRunning
+	k0sServiceInstalled = func() bool { return installed }
+	k0sServiceRunning = func() bool { return running }
+	t.Cleanup(func() { k0sServiceInstalled, k0sServiceRunning = previousInstalled, previousRunning })
+}
+
+// journaledInstaller returns an installer recording its changes in a plan,
+// through a journal as WithRollback does
+func journaledInstaller(t *testing.T) (*Installer, *system.Plan) {
+	plan := system.NewPlan()
+	journal := system.NewJournal(plan, t.TempDir())
+	inst := NewInstaller(false, false)
+	inst.host, inst.journal = journal, journal
+	return inst, plan
+}
+
+// planSteps returns the commands and written files of a plan
+func planSteps(plan *system.Plan) []string {
+	var steps []string
+	for _, action := range plan.Actions {
+		switch action.Type {
+		case system.ActionCommand:
+			steps = append(steps, strings.Join(action.Command, " "))
+		case system.ActionWrite:
+			steps = append(steps, "write "+filepath.Base(action.Path))
+		}
+	}
+	return steps
+}
+
+func TestRollbackK0sService(t *testing.T) {
+	t.Run("should stop k0s again when install started it", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		stubK0sService(t, true, false)
+
+		inst, plan := journaledInstaller(t)
+		g.Expect(inst.installK0s()).To(gomega.Succeed())
+		g.Expect(inst.journal.Rollback()).To(gomega.Succeed())
+		g.Expect(planSteps(plan)).To(gomega.Equal([]string{"k0s start", "k0s stop"}))
+	})
+
+	t.Run("should restart k0s on the restored configuration", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		stubK0sService(t, true, true)
+
+		config := filepath.Join(t.TempDir(), "k0s.yaml")
+		g.Expect(os.WriteFile(config, []byte("old"), 0600)).To(gomega.Succeed())
+
+		inst, plan := journaledInstaller(t)
+		// As writeK0sConfig does when the configuration changes
+		inst.k0rdentChartAdded = true
+		inst.k0sConfigReplaced()
+		g.Expect(inst.host.WriteFile(config, []byte("new"), 0600)).To(gomega.Succeed())
+
+		g.Expect(inst.installK0s()).To(gomega.Succeed())
+		g.Expect(inst.journal.Rollback()).To(gomega.Succeed())
+		g.Expect(planSteps(plan)).To(gomega.Equal([]string{
+			"write k0s.yaml", "k0s stop", "k0s start",
+			// Rollback
+			"write k0s.yaml", "k0s stop", "k0s start",
+		}))
+	})
+
+	t.Run("should leave a running k0s alone when it wasn't restarted", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		stubK0sService(t, true, true)
+
+		inst, plan := journaledInstaller(t)
+		inst.k0sConfigReplaced()
+		g.Expect(inst.installK0s()).To(gomega.Succeed())
+		g.Expect(inst.journal.Rollback()).To(gomega.Succeed())
+		g.Expect(planSteps(plan)).To(gomega.BeEmpty())
+	})
+}
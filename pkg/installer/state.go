@@ -0,0 +1,104 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultStateFile records the progress of install, for --resume
+const DefaultStateFile = "/var/lib/k0rdentd/state.json"
+
+// StepStatus is the outcome of an install step
+type StepStatus string
+
+const (
+	// StepCompleted marks a step that succeeded
+	StepCompleted StepStatus = "completed"
+	// StepFailed marks a step that returned an error
+	StepFailed StepStatus = "failed"
+)
+
+// StepState records the last run of an install step
+type StepState struct {
+	Status StepStatus `json:"status"`
+	// InputsHash identifies the inputs of the step, a completed step runs
+	// again on resume when they change
+	InputsHash string    `json:"inputsHash,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// State is the persisted progress of install, keyed by step name
+type State struct {
+	Steps     map[string]*StepState `json:"steps"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// NewState returns an empty state
+func NewState() *State {
+	return &State{Steps: make(map[string]*StepState)}
+}
+
+// LoadState reads the state file at path. A missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	state := NewState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Steps == nil {
+		state.Steps = make(map[string]*StepState)
+	}
+	return state, nil
+}
+
+// Save writes the state to path, replacing the previous file atomically
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	s.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// IsCompleted returns true if the step completed with the same inputs
+func (s *State) IsCompleted(name, inputsHash string) bool {
+	step, ok := s.Steps[name]
+	return ok && step.Status == StepCompleted && step.InputsHash == inputsHash
+}
+
+// hashInputs returns a stable hash of the inputs of a step
+func hashInputs(inputs interface{}) (string, error) {
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash step inputs: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,189 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/events"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// Install step names, accepted by --from-step
+const (
+	StepCheckVersion      = "check-version"
+	StepWriteConfig       = "write-config"
+	StepPrepareAirgap     = "prepare-airgap"
+	StepInstallK0s        = "install-k0s"
+	StepWaitK0rdent       = "wait-k0rdent"
+	StepWaitProviders     = "wait-providers"
+	StepCreateCredentials = "create-credentials"
+)
+
+// Steps run outside of the install steps, they are only reported in the
+// progress events
+const (
+	StepDownloadK0s = "download-k0s"
+	StepJoin        = "join"
+)
+
+// installStep is a named step of the installation
+type installStep struct {
+	name        string
+	description string
+	// inputs are hashed to detect changes between runs
+	inputs interface{}
+	// optional steps log failures as warnings instead of stopping install
+	optional bool
+	run      func() error
+}
+
+// SetResume skips the steps completed by a previous install with the same inputs
+func (i *Installer) SetResume(resume bool) {
+	i.resume = resume
+}
+
+// SetFromStep restarts the installation at the given step, earlier steps
+// must have completed in a previous install
+func (i *Installer) SetFromStep(step string) {
+	i.fromStep = step
+}
+
+// SetStateFile sets the file recording the install progress
+func (i *Installer) SetStateFile(path string) {
+	i.stateFile = path
+}
+
+// runSteps runs the install steps in order, recording their outcome in the
+// state file. With --resume, completed steps are skipped until the first
+// step to run; with --from-step, steps before the given one are skipped.
+func (i *Installer) runSteps(steps []installStep) error {
+	logger := utils.GetLogger()
+
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.name)
+	}
+
+	state := NewState()
+	if i.resume || i.fromStep != "" {
+		loaded, err := LoadState(i.stateFile)
+		if err != nil {
+			return err
+		}
+		state = loaded
+	}
+
+	hashes := make([]string, len(steps))
+	for idx, step := range steps {
+		hash, err := hashInputs(step.inputs)
+		if err != nil {
+			return err
+		}
+		hashes[idx] = hash
+	}
+
+	start := 0
+	if i.fromStep != "" {
+		start = -1
+		for idx, name := range names {
+			if name == i.fromStep {
+				start = idx
+				break
+			}
+		}
+		if start < 0 {
+			return fmt.Errorf("unknown step %q (expected one of: %s)", i.fromStep, strings.Join(names, ", "))
+		}
+		for idx, step := range steps[:start] {
+			if status, ok := state.Steps[step.name]; !ok || status.Status != StepCompleted {
+				return fmt.Errorf("step %s has not completed, it must run before %s", step.name, i.fromStep)
+			}
+			// Later steps depend on what the skipped ones did with their inputs
+			if !state.IsCompleted(step.name, hashes[idx]) {
+				return fmt.Errorf("inputs of step %s changed since it completed, restart at it with --from-step %s or use --resume", step.name, step.name)
+			}
+		}
+	}
+
+	// Completed steps are skipped until one has to run, later steps depend on it
+	if i.resume {
+		for start < len(steps) && state.IsCompleted(steps[start].name, hashes[start]) {
+			start++
+		}
+		if start < len(steps) {
+			if previous, ok := state.Steps[steps[start].name]; ok && previous.Status == StepCompleted {
+				logger.Infof("Inputs of step %s changed since the last install, running it again", steps[start].name)
+			}
+		}
+	}
+
+	for idx, step := range steps {
+		if idx < start {
+			logger.Infof("⏭️  Skipping completed step %s", step.name)
+			i.events.Emit(events.Event{Type: events.StepSkipped, Step: step.name, Description: step.description})
+			continue
+		}
+
+		if i.dryRun {
+			// Steps run against the dry-run plan, the progress isn't recorded
+			logger.Infof("📝 [%s] %s", step.name, step.description)
+			if err := step.run(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		logger.Debugf("Running step %s: %s", step.name, step.description)
+		stepState, err := i.runStep(step.name, step.description, step.run)
+		stepState.InputsHash = hashes[idx]
+		state.Steps[step.name] = stepState
+		if saveErr := state.Save(i.stateFile); saveErr != nil {
+			logger.Warnf("⚠️ Failed to record install progress: %v", saveErr)
+		}
+
+		if err == nil {
+			continue
+		}
+		if step.optional {
+			logger.Warnf("⚠️ Step %s failed: %v", step.name, err)
+			continue
+		}
+		// A rolled back install starts over
+		if i.journal == nil {
+			logger.Infof("Fix the problem and run 'k0rdentd install --resume' to continue from step %s", step.name)
+		}
+		return err
+	}
+	return nil
+}
+
+// runStep runs a step, reporting its progress events, and returns its state
+func (i *Installer) runStep(name, description string, run func() error) (*StepState, error) {
+	stepState := &StepState{StartedAt: time.Now().UTC()}
+	i.events.Emit(events.Event{Type: events.StepStarted, Step: name, Description: description})
+	err := run()
+	stepState.FinishedAt = time.Now().UTC()
+	stepState.Status = StepCompleted
+	if err != nil {
+		stepState.Status = StepFailed
+		stepState.Error = err.Error()
+	}
+	i.events.Emit(stepEvent(name, stepState))
+	return stepState, err
+}
+
+// stepEvent returns the progress event of a finished step
+func stepEvent(name string, state *StepState) events.Event {
+	event := events.Event{
+		Time:            state.FinishedAt,
+		Type:            events.StepFinished,
+		Step:            name,
+		DurationSeconds: state.FinishedAt.Sub(state.StartedAt).Round(time.Millisecond).Seconds(),
+	}
+	if state.Status == StepFailed {
+		event.Type = events.StepFailed
+		event.Error = state.Error
+	}
+	return event
+}
@@ -0,0 +1,178 @@
+package installer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/events"
+	"github.com/onsi/gomega"
+)
+
+// recordingSteps returns steps that record their runs, the step named
+// failing returns an error
+func recordingSteps(ran *[]string, failing string, inputs map[string]string) []installStep {
+	var steps []installStep
+	for _, name := range []string{"first", "second", "third"} {
+		steps = append(steps, installStep{
+			name:   name,
+			inputs: inputs[name],
+			run: func() error {
+				*ran = append(*ran, name)
+				if name == failing {
+					return errors.New("boom")
+				}
+				return nil
+			},
+		})
+	}
+	return steps
+}
+
+func TestRunSteps(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	newInstaller := func() *Installer {
+		inst := NewInstaller(false, false)
+		inst.SetStateFile(stateFile)
+		return inst
+	}
+
+	// A failed step stops the installation and is recorded
+	var ran []string
+	err := newInstaller().runSteps(recordingSteps(&ran, "second", nil))
+	g.Expect(err).To(gomega.MatchError("boom"))
+	g.Expect(ran).To(gomega.Equal([]string{"first", "second"}))
+
+	state, err := LoadState(stateFile)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(state.Steps["first"].Status).To(gomega.Equal(StepCompleted))
+	g.Expect(state.Steps["second"].Status).To(gomega.Equal(StepFailed))
+	g.Expect(state.Steps["second"].Error).To(gomega.Equal("boom"))
+
+	// Resuming skips the completed steps
+	ran = nil
+	inst := newInstaller()
+	inst.SetResume(true)
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", nil))).To(gomega.Succeed())
+	g.Expect(ran).To(gomega.Equal([]string{"second", "third"}))
+
+	// Completed steps whose inputs changed run again, with the later ones
+	ran = nil
+	inst = newInstaller()
+	inst.SetResume(true)
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", map[string]string{"second": "changed"}))).To(gomega.Succeed())
+	g.Expect(ran).To(gomega.Equal([]string{"second", "third"}))
+
+	// Restarting at a step runs it even if it completed
+	ran = nil
+	inst = newInstaller()
+	inst.SetFromStep("third")
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", map[string]string{"second": "changed"}))).To(gomega.Succeed())
+	g.Expect(ran).To(gomega.Equal([]string{"third"}))
+
+	// Earlier steps must have completed with the same inputs
+	ran = nil
+	inst = newInstaller()
+	inst.SetFromStep("third")
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", nil))).To(gomega.MatchError(gomega.ContainSubstring("inputs of step second changed")))
+	g.Expect(ran).To(gomega.BeEmpty())
+
+	inst = newInstaller()
+	inst.SetFromStep("unknown")
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", nil))).To(gomega.MatchError(gomega.ContainSubstring("unknown step")))
+
+	// Without resume every step runs and the state starts over
+	ran = nil
+	g.Expect(newInstaller().runSteps(recordingSteps(&ran, "first", nil))).ToNot(gomega.Succeed())
+	inst = newInstaller()
+	inst.SetFromStep("second")
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", nil))).To(gomega.MatchError(gomega.ContainSubstring("step first has not completed")))
+}
+
+func TestRunStepsOptional(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	inst := NewInstaller(false, false)
+	inst.SetStateFile(filepath.Join(t.TempDir(), "state.json"))
+
+	var ran []string
+	steps := recordingSteps(&ran, "first", nil)
+	steps[0].optional = true
+	g.Expect(inst.runSteps(steps)).To(gomega.Succeed())
+	g.Expect(ran).To(gomega.Equal([]string{"first", "second", "third"}))
+
+	// Failed optional steps run again on resume
+	ran = nil
+	inst.SetResume(true)
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", nil))).To(gomega.Succeed())
+	g.Expect(ran).To(gomega.Equal([]string{"first", "second", "third"}))
+}
+
+func TestRunStepsEvents(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	var out bytes.Buffer
+	newInstaller := func() *Installer {
+		inst := NewInstaller(false, false)
+		inst.SetStateFile(stateFile)
+		inst.SetEvents(events.NewRecorder(&out))
+		return inst
+	}
+	decode := func() []events.Event {
+		var decoded []events.Event
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var event events.Event
+			g.Expect(json.Unmarshal([]byte(line), &event)).To(gomega.Succeed())
+			decoded = append(decoded, event)
+		}
+		out.Reset()
+		return decoded
+	}
+
+	var ran []string
+	g.Expect(newInstaller().runSteps(recordingSteps(&ran, "second", nil))).ToNot(gomega.Succeed())
+	decoded := decode()
+	types := make([]string, 0, len(decoded))
+	for _, event := range decoded {
+		types = append(types, string(event.Type)+" "+event.Step)
+	}
+	g.Expect(types).To(gomega.Equal([]string{
+		"step_started first", "step_finished first",
+		"step_started second", "step_failed second",
+	}))
+	g.Expect(decoded[3].Error).To(gomega.Equal("boom"))
+
+	inst := newInstaller()
+	inst.SetResume(true)
+	g.Expect(inst.runSteps(recordingSteps(&ran, "", nil))).To(gomega.Succeed())
+	g.Expect(decode()[0]).To(gomega.HaveField("Type", events.StepSkipped))
+}
+
+func TestStepEventsOutsideOfInstall(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var out bytes.Buffer
+	inst := NewInstaller(false, true)
+	inst.SetEvents(events.NewRecorder(&out))
+
+	g.Expect(inst.DownloadK0s("v1.34.1+k0s.0")).To(gomega.Succeed())
+	g.Expect(inst.InstallJoin(&config.JoinConfig{Mode: "worker", Server: "https://10.0.0.1:6443", Token: "token"})).To(gomega.Succeed())
+
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event events.Event
+		g.Expect(json.Unmarshal([]byte(line), &event)).To(gomega.Succeed())
+		types = append(types, string(event.Type)+" "+event.Step)
+	}
+	g.Expect(types).To(gomega.Equal([]string{
+		"step_started download-k0s", "step_finished download-k0s",
+		"step_started join", "step_finished join",
+	}))
+}
@@ -0,0 +1,278 @@
+package installer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"syscall"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/internal/airgap/containerd"
+	"github.com/belgaied2/k0rdentd/internal/airgap/registry"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/system"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// k0rdentdConfigPath is the system configuration of k0rdentd
+	k0rdentdConfigPath = "/etc/k0rdentd/k0rdentd.yaml"
+	// k0sJoinTokenPath is the token of joining nodes, left by failed joins
+	k0sJoinTokenPath = "/etc/k0s/join-token"
+	// k0sCAPath is the cluster CA, to recognize kubeconfigs of the cluster
+	k0sCAPath = "/var/lib/k0s/pki/ca.crt"
+	// systemdUnitDir holds the units written by k0s install
+	systemdUnitDir = "/etc/systemd/system"
+)
+
+// k0sServices are the systemd units written by k0s install, by role
+var k0sServices = []string{"k0scontroller.service", "k0sworker.service"}
+
+// UninstallOptions selects what Uninstall removes besides k0s
+type UninstallOptions struct {
+	// KeepConfig keeps the k0rdentd configuration and its drop-in files
+	KeepConfig bool
+	// PurgeRegistry removes the storage of the airgap registry
+	PurgeRegistry bool
+	// RemoveBinaries removes the k0s binary, and skopeo in airgap builds
+	RemoveBinaries bool
+}
+
+// InventoryItem is a part of the installation found on the node
+type InventoryItem struct {
+	Path        string
+	Description string
+	// Flag changes whether the item is removed, if any
+	Flag   string
+	remove func() error
+}
+
+// Inventory lists what Uninstall removes from the node and what it keeps
+type Inventory struct {
+	Remove []InventoryItem
+	Keep   []InventoryItem
+}
+
+// IsEmpty tells whether there is nothing to remove
+func (inv *Inventory) IsEmpty() bool {
+	return len(inv.Remove) == 0
+}
+
+// add lists item in Remove or Keep
+func (inv *Inventory) add(remove bool, item InventoryItem) {
+	if remove {
+		inv.Remove = append(inv.Remove, item)
+	} else {
+		inv.Keep = append(inv.Keep, item)
+	}
+}
+
+// UninstallInventory returns what Uninstall removes with opts. Only what
+// exists on the node is listed, in the order it is removed.
+func (i *Installer) UninstallInventory(opts UninstallOptions) (*Inventory, error) {
+	inv := &Inventory{}
+
+	// k0s reset needs the binary, which is removed last
+	service := installedK0sService()
+	if service != "" || hasK0sData() {
+		if _, err := exec.LookPath("k0s"); err != nil {
+			return nil, fmt.Errorf("k0s binary not found, cannot reset k0s")
+		}
+		description := "k0s data, reset with k0s reset"
+		if service != "" {
+			description = fmt.Sprintf("k0s service %s and its data, reset with k0s reset", service)
+		}
+		inv.add(true, InventoryItem{
+			Path:        k0sDataDir,
+			Description: description,
+			remove:      func() error { return i.resetK0sService(service) },
+		})
+	}
+
+	// Read before k0s reset removes the CA
+	for _, path := range clusterKubeconfigs(k0sCAPath, kubeconfigPaths()) {
+		inv.add(true, i.fileItem(path, "kubeconfig of the cluster", nil))
+	}
+
+	for _, path := range containerd.MirrorConfigPaths() {
+		if exists(path) {
+			inv.add(true, i.fileItem(path, "containerd registry mirror configuration", nil))
+		}
+	}
+	if exists(k0sConfigPath) {
+		inv.add(true, i.fileItem(k0sConfigPath, "k0s configuration", redactK0sConfig))
+	}
+	if exists(k0sJoinTokenPath) {
+		inv.add(true, i.fileItem(k0sJoinTokenPath, "k0s join token", system.Redacted()))
+	}
+	if exists(i.stateFile) {
+		inv.add(true, i.fileItem(i.stateFile, "install progress", nil))
+	}
+
+	if exists(k0rdentdConfigPath) {
+		item := i.fileItem(k0rdentdConfigPath, "k0rdentd configuration", system.Redacted())
+		item.Flag = "--keep-config"
+		inv.add(!opts.KeepConfig, item)
+	}
+	if dropIns := filepath.Join(filepath.Dir(k0rdentdConfigPath), config.DropInDirName); exists(dropIns) {
+		item := i.dirItem(dropIns, "k0rdentd configuration drop-in files")
+		item.Flag = "--keep-config"
+		inv.add(!opts.KeepConfig, item)
+	}
+	if exists(registry.DefaultStorageDir) {
+		item := i.dirItem(registry.DefaultStorageDir, "airgap registry storage")
+		item.Flag = "--purge-registry"
+		inv.add(opts.PurgeRegistry, item)
+	}
+
+	binaries := []string{k0sBinaryPath}
+	// Other skopeo binaries may come from a package
+	if airgap.IsAirGap() {
+		binaries = append(binaries, registry.SkopeoBinaryPath)
+	}
+	for _, path := range binaries {
+		if exists(path) {
+			item := i.fileItem(path, filepath.Base(path)+" binary", nil)
+			item.Flag = "--remove-binaries"
+			inv.add(opts.RemoveBinaries, item)
+		}
+	}
+	return inv, nil
+}
+
+// Uninstall removes the items of the inventory, in order
+func (i *Installer) Uninstall(inv *Inventory) error {
+	for _, item := range inv.Remove {
+		if err := item.remove(); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", item.Description, err)
+		}
+	}
+
+	// Directories holding only what was removed
+	for _, dir := range []string{containerd.CertsDir, containerd.ContainerdDropInDir, filepath.Dir(k0sConfigPath)} {
+		if err := i.removeEmptyDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileItem returns an item removing a file
+func (i *Installer) fileItem(path, description string, opt system.FileOption) InventoryItem {
+	return InventoryItem{
+		Path:        path,
+		Description: description,
+		remove: func() error {
+			if opt != nil {
+				return i.host.Remove(path, opt)
+			}
+			return i.host.Remove(path)
+		},
+	}
+}
+
+// dirItem returns an item removing a directory and its content
+func (i *Installer) dirItem(path, description string) InventoryItem {
+	return InventoryItem{
+		Path:        path,
+		Description: description,
+		remove:      func() error { return i.host.RemoveAll(path) },
+	}
+}
+
+// removeEmptyDir removes the directory dir if it is empty, and the empty
+// directories it holds
+func (i *Installer) removeEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := i.removeEmptyDir(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	err = i.host.Remove(dir)
+	if err != nil && (errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST)) {
+		return nil
+	}
+	return err
+}
+
+// resetK0sService stops k0s if its service runs, then resets it
+func (i *Installer) resetK0sService(service string) error {
+	if service != "" && exec.Command("systemctl", "is-active", "--quiet", service).Run() == nil {
+		if err := i.stopK0s(); err != nil {
+			return err
+		}
+	}
+	return i.resetK0s()
+}
+
+// installedK0sService returns the k0s systemd unit of the node, controller
+// or worker, or "" if k0s isn't installed as a service
+func installedK0sService() string {
+	for _, service := range k0sServices {
+		if exists(filepath.Join(systemdUnitDir, service)) {
+			return service
+		}
+	}
+	return ""
+}
+
+// kubeconfigPaths returns the default kubeconfigs of root and of the user
+// running sudo
+func kubeconfigPaths() []string {
+	homes := []string{"/root"}
+	if name := os.Getenv("SUDO_USER"); name != "" && name != "root" {
+		if u, err := user.Lookup(name); err == nil {
+			homes = append(homes, u.HomeDir)
+		}
+	}
+	paths := make([]string, 0, len(homes))
+	for _, home := range homes {
+		paths = append(paths, filepath.Join(home, ".kube", "config"))
+	}
+	return paths
+}
+
+// clusterKubeconfigs returns the kubeconfigs whose clusters all use the CA
+// of the cluster, kubeconfigs also pointing to other clusters are kept
+func clusterKubeconfigs(caPath string, paths []string) []string {
+	ca, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil
+	}
+
+	var matching []string
+	for _, path := range paths {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		if err != nil || len(kubeconfig.Clusters) == 0 {
+			continue
+		}
+		ours := true
+		for _, cluster := range kubeconfig.Clusters {
+			if cluster.CertificateAuthority != caPath &&
+				!bytes.Equal(bytes.TrimSpace(cluster.CertificateAuthorityData), bytes.TrimSpace(ca)) {
+				ours = false
+				break
+			}
+		}
+		if ours {
+			matching = append(matching, path)
+		}
+	}
+	return matching
+}
+
+// exists tells whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,214 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/credentials"
+	"github.com/belgaied2/k0rdentd/pkg/generator"
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// k0sHelmManifestDir holds the Chart manifests k0s writes for the helm
+// extension, named <order>_helm_extension_<release>.yaml
+const k0sHelmManifestDir = "/var/lib/k0s/manifests/helm"
+
+// UninstallK0rdent removes k0rdent from the running cluster: the
+// credentials of creds, the Management object, the kcm chart, the
+// kcm-system namespace and the k0rdent CRDs. k0s and the other workloads
+// keep running, and k0rdent can be installed again with install.
+func (i *Installer) UninstallK0rdent(creds *config.CredentialsConfig) error {
+	if !isK0sRunning() {
+		return fmt.Errorf("k0s is not running, k0rdent can only be removed from a running cluster")
+	}
+	ctx := context.Background()
+
+	if creds != nil && creds.HasCredentials() {
+		err := i.host.Do("Delete the cloud provider credentials", func() error {
+			if err := i.ensureK8sClient(); err != nil {
+				return err
+			}
+			return credentials.NewManager(i.k8sClient).DeleteAll(ctx, *creds)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete credentials: %w", err)
+		}
+	}
+
+	// The kcm controller uninstalls the providers when the Management goes
+	// away, so it must still run
+	description := fmt.Sprintf("Delete the k0rdent Management %s and wait for its providers to be uninstalled", k8sclient.ManagementName)
+	err := i.host.Do(description, func() error {
+		return i.deleteAndWait(ctx, "managements "+k8sclient.ManagementName, "the k0rdent Management", func() error {
+			return i.k8sClient.DeleteResource(ctx, k8sclient.ManagementGVR, "", k8sclient.ManagementName)
+		}, gone(func(ctx context.Context) (bool, error) {
+			return i.k8sClient.ResourceExists(ctx, k8sclient.ManagementGVR, "", k8sclient.ManagementName)
+		}))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete the k0rdent Management: %w", err)
+	}
+
+	if err := i.removeK0rdentChart(); err != nil {
+		return err
+	}
+	chart := k8sclient.K0sChartName(generator.K0rdentHelmReleaseName)
+	description = fmt.Sprintf("Delete the k0s Chart %s/%s and wait for the kcm Helm release to be uninstalled", k8sclient.K0sChartNamespace, chart)
+	err = i.host.Do(description, func() error {
+		return i.deleteAndWait(ctx, "charts "+k8sclient.K0sChartNamespace+"/"+chart, "the kcm chart", func() error {
+			return i.k8sClient.DeleteResource(ctx, k8sclient.HelmChartGVR, k8sclient.K0sChartNamespace, chart)
+		}, gone(func(ctx context.Context) (bool, error) {
+			return i.k8sClient.ResourceExists(ctx, k8sclient.HelmChartGVR, k8sclient.K0sChartNamespace, chart)
+		}))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to uninstall the kcm chart: %w", err)
+	}
+
+	description = fmt.Sprintf("Delete the %s namespace and wait for it to go away", credentials.KCMNamespace)
+	err = i.host.Do(description, func() error {
+		return i.deleteAndWait(ctx, "namespace "+credentials.KCMNamespace, "the "+credentials.KCMNamespace+" namespace", func() error {
+			return i.k8sClient.DeleteNamespace(ctx, credentials.KCMNamespace)
+		}, func(ctx context.Context) error {
+			return i.k8sClient.WaitForNamespaceDeleted(ctx, credentials.KCMNamespace)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete the %s namespace: %w", credentials.KCMNamespace, err)
+	}
+
+	description = fmt.Sprintf("Delete the %s CRDs and wait for them to go away", k8sclient.K0rdentGroup)
+	if err := i.host.Do(description, func() error { return i.deleteK0rdentCRDs(ctx) }); err != nil {
+		return fmt.Errorf("failed to delete the k0rdent CRDs: %w", err)
+	}
+
+	// The recorded progress is about the removed k0rdent
+	if exists(i.stateFile) {
+		if err := i.host.Remove(i.stateFile); err != nil {
+			return fmt.Errorf("failed to remove the install state: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeK0rdentChart removes the kcm chart from the k0s configuration, so
+// that k0s doesn't install it again, and its manifest from the running k0s
+func (i *Installer) removeK0rdentChart() error {
+	data, err := os.ReadFile(k0sConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read k0s config: %w", err)
+	}
+	if err == nil {
+		updated, found, err := generator.RemoveK0rdentChart(data)
+		if err != nil {
+			return fmt.Errorf("failed to remove the kcm chart from the k0s config: %w", err)
+		}
+		if found {
+			if err := i.host.WriteFile(k0sConfigPath, updated, 0600, redactK0sConfig); err != nil {
+				return fmt.Errorf("failed to write k0s config: %w", err)
+			}
+		}
+	}
+
+	manifests, err := filepath.Glob(filepath.Join(k0sHelmManifestDir, "*_helm_extension_"+generator.K0rdentHelmReleaseName+".yaml"))
+	if err != nil {
+		return err
+	}
+	for _, manifest := range manifests {
+		if err := i.host.Remove(manifest); err != nil {
+			return fmt.Errorf("failed to remove the kcm chart manifest: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteAndWait deletes an object with del, then waits for it to go away
+// with wait, which takes as long as its finalizers
+func (i *Installer) deleteAndWait(ctx context.Context, resource, what string, del func() error, wait func(ctx context.Context) error) error {
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
+	if err := del(); err != nil {
+		return err
+	}
+	return i.waitForWithSpinner(
+		i.timeout(config.TimeoutK0rdentUninstall),
+		resource,
+		fmt.Sprintf("Waiting for %s to be deleted", what),
+		wait,
+	)
+}
+
+// gone returns a wait polling exists until it reports the object gone
+func gone(exists func(ctx context.Context) (bool, error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return utils.PollWithBackoff(ctx, func() (bool, error) {
+			found, err := exists(ctx)
+			return !found, err
+		})
+	}
+}
+
+// deleteK0rdentCRDs deletes the k0rdent CRDs and waits for them to go away.
+// On timeout, the objects whose finalizers hold them are reported.
+func (i *Installer) deleteK0rdentCRDs(ctx context.Context) error {
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
+	crds, err := i.k8sClient.ListCRDs(ctx, k8sclient.K0rdentGroup)
+	if err != nil {
+		return err
+	}
+	for _, crd := range crds {
+		if err := i.k8sClient.DeleteResource(ctx, k8sclient.CRDGVR, "", crd.Name); err != nil {
+			return err
+		}
+	}
+
+	err = i.waitForWithSpinner(
+		i.timeout(config.TimeoutK0rdentUninstall),
+		"customresourcedefinitions "+k8sclient.K0rdentGroup,
+		"Waiting for the k0rdent CRDs to be deleted",
+		gone(func(ctx context.Context) (bool, error) {
+			remaining, err := i.k8sClient.ListCRDs(ctx, k8sclient.K0rdentGroup)
+			return len(remaining) > 0, err
+		}),
+	)
+	if err == nil {
+		return nil
+	}
+
+	finalized, listErr := i.finalizedK0rdentObjects(ctx)
+	if listErr != nil {
+		utils.GetLogger().Debugf("Failed to list the k0rdent objects with finalizers: %v", listErr)
+	}
+	if len(finalized) > 0 {
+		return fmt.Errorf("%w, these objects still have finalizers: %s", err, strings.Join(finalized, ", "))
+	}
+	return err
+}
+
+// finalizedK0rdentObjects returns the k0rdent objects holding finalizers,
+// as kind namespace/name
+func (i *Installer) finalizedK0rdentObjects(ctx context.Context) ([]string, error) {
+	crds, err := i.k8sClient.ListCRDs(ctx, k8sclient.K0rdentGroup)
+	if err != nil {
+		return nil, err
+	}
+	var objects []string
+	for _, crd := range crds {
+		names, err := i.k8sClient.ListFinalizedObjects(ctx, crd)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			objects = append(objects, crd.GVR.Resource+" "+name)
+		}
+	}
+	return objects, nil
+}
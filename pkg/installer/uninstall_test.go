@@ -0,0 +1,55 @@
+package installer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestClusterKubeconfigs(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+	ca := "-----BEGIN CERTIFICATE-----\nours\n-----END CERTIFICATE-----\n"
+	g.Expect(os.WriteFile(caPath, []byte(ca), 0600)).To(gomega.Succeed())
+
+	kubeconfig := func(name string, cas ...string) string {
+		content := "apiVersion: v1\nkind: Config\nclusters:\n"
+		for idx, clusterCA := range cas {
+			content += fmt.Sprintf("- name: c%d\n  cluster:\n    server: https://localhost:6443\n    certificate-authority-data: %s\n",
+				idx, base64.StdEncoding.EncodeToString([]byte(clusterCA)))
+		}
+		path := filepath.Join(dir, name)
+		g.Expect(os.WriteFile(path, []byte(content), 0600)).To(gomega.Succeed())
+		return path
+	}
+	ours := kubeconfig("ours", ca)
+	other := kubeconfig("other", "other")
+	mixed := kubeconfig("mixed", ca, "other")
+	missing := filepath.Join(dir, "missing")
+
+	g.Expect(clusterKubeconfigs(caPath, []string{ours, other, mixed, missing})).To(gomega.Equal([]string{ours}))
+	// Without the CA no kubeconfig can be recognized
+	g.Expect(clusterKubeconfigs(filepath.Join(dir, "no-ca"), []string{ours})).To(gomega.BeEmpty())
+}
+
+func TestRemoveEmptyDir(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "certs.d", "quay.io")
+	kept := filepath.Join(dir, "certs.d", "example.com")
+	g.Expect(os.MkdirAll(empty, 0755)).To(gomega.Succeed())
+	g.Expect(os.MkdirAll(kept, 0755)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(filepath.Join(kept, "hosts.toml"), []byte("x"), 0644)).To(gomega.Succeed())
+
+	inst := NewInstaller(false, false)
+	g.Expect(inst.removeEmptyDir(dir)).To(gomega.Succeed())
+	g.Expect(empty).ToNot(gomega.BeAnExistingFile())
+	g.Expect(filepath.Join(kept, "hosts.toml")).To(gomega.BeAnExistingFile())
+}
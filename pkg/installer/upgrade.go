@@ -0,0 +1,289 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/internal/airgap/registry"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/generator"
+	"github.com/belgaied2/k0rdentd/pkg/k0s"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// DefaultBackupDir is where upgrade saves a backup before changing the node
+const DefaultBackupDir = "/var/lib/k0rdentd/backups"
+
+// k0sConfigPath is the k0s configuration read by the k0s service
+const k0sConfigPath = "/etc/k0s/k0s.yaml"
+
+// SetBackupDir sets the directory of the backups made by upgrade
+func (i *Installer) SetBackupDir(dir string) {
+	i.backupDir = dir
+}
+
+// SetForceUpgrade upgrades even if the compatibility check fails
+func (i *Installer) SetForceUpgrade(force bool) {
+	i.forceUpgrade = force
+}
+
+// SetBundlePath sets the airgap bundle whose images upgrade pushes to the
+// local registry
+func (i *Installer) SetBundlePath(path string) {
+	i.bundlePath = path
+}
+
+// CurrentVersions returns the running k0s version and the k0rdent chart
+// version of the k0s configuration
+func CurrentVersions() (Versions, error) {
+	var current Versions
+
+	k0sVersion, err := k0s.GetK0sVersion()
+	if err != nil {
+		return current, err
+	}
+	current.K0s = k0sVersion
+
+	data, err := os.ReadFile(k0sConfigPath)
+	if err != nil {
+		return current, fmt.Errorf("failed to read k0s config: %w", err)
+	}
+	current.K0rdent, err = generator.K0rdentChartVersion(data)
+	if err != nil {
+		return current, err
+	}
+	return current, nil
+}
+
+// upgradeChanges are the versions an upgrade goes from and to
+type upgradeChanges struct {
+	current Versions
+	target  Versions
+	k0s     bool // The k0s version changes
+	k0rdent bool // The k0rdent version changes
+}
+
+// Upgrade upgrades k0s and k0rdent on this controller. Empty target versions
+// keep the current ones. After a backup, the k0rdent chart version is updated
+// in the k0s configuration and k0s is stopped, replaced and started again, so
+// that its helm extension upgrades k0rdent.
+func (i *Installer) Upgrade(target Versions) error {
+	changes, err := i.prepareUpgrade(target)
+	if err != nil || changes == nil {
+		return err
+	}
+
+	if err := i.restartK0sForUpgrade(changes); err != nil {
+		return err
+	}
+	return i.finishUpgrade(changes)
+}
+
+// prepareUpgrade checks the upgrade, backs up and updates the k0s
+// configuration. It returns nil changes when there is nothing to upgrade.
+func (i *Installer) prepareUpgrade(target Versions) (*upgradeChanges, error) {
+	logger := utils.GetLogger()
+
+	current, err := CurrentVersions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the installed versions: %w", err)
+	}
+	if target.K0s == "" {
+		target.K0s = current.K0s
+	}
+	if target.K0rdent == "" {
+		target.K0rdent = current.K0rdent
+	}
+	logger.Infof("Upgrading k0s %s → %s, k0rdent %s → %s", current.K0s, target.K0s, current.K0rdent, target.K0rdent)
+
+	sameK0s, err := k0s.VersionsEqual(current.K0s, target.K0s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare k0s versions: %w", err)
+	}
+	k0rdentOrder, err := compareSemver(current.K0rdent, target.K0rdent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare k0rdent versions: %w", err)
+	}
+	changes := &upgradeChanges{
+		current: current,
+		target:  target,
+		k0s:     !sameK0s,
+		k0rdent: k0rdentOrder != 0,
+	}
+	if !changes.k0s && !changes.k0rdent {
+		logger.Info("✅ k0s and k0rdent are already up to date")
+		return nil, nil
+	}
+
+	if err := CheckUpgradeCompatibility(current, target); err != nil {
+		if !i.forceUpgrade {
+			return nil, fmt.Errorf("unsupported upgrade: %w. Use --force to upgrade anyway", err)
+		}
+		logger.Warnf("⚠️  Unsupported upgrade, continuing because of --force: %v", err)
+	}
+
+	// The backup and the readiness checks need the cluster
+	if !isK0sRunning() {
+		return nil, fmt.Errorf("k0s is not running, start it with 'k0s start' before upgrading")
+	}
+
+	if err := i.backup(current); err != nil {
+		return nil, fmt.Errorf("backup failed: %w", err)
+	}
+
+	if airgap.IsAirGap() && i.bundlePath != "" {
+		registryAddr := airgap.NewInstaller(i.config, i.debug).GetRegistryAddress()
+		description := fmt.Sprintf("Push the images of %s to the registry %s", i.bundlePath, registryAddr)
+		err := i.host.Do(description, func() error {
+			return registry.PushImages(i.bundlePath, registryAddr)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to push the bundle images: %w", err)
+		}
+	}
+
+	if changes.k0rdent {
+		if err := i.updateK0rdentChartVersion(target.K0rdent); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// restartK0sForUpgrade stops k0s, replaces its binary if its version changes
+// and starts it again. k0s reads its configuration at startup, so it
+// restarts even if only k0rdent is upgraded.
+func (i *Installer) restartK0sForUpgrade(changes *upgradeChanges) error {
+	if err := i.stopK0s(); err != nil {
+		return err
+	}
+	if changes.k0s {
+		if err := i.replaceK0sForUpgrade(changes.target.K0s); err != nil {
+			return err
+		}
+	}
+	if err := i.startK0s(); err != nil {
+		return fmt.Errorf("failed to restart k0s: %w", err)
+	}
+	return nil
+}
+
+// finishUpgrade waits for the k0rdent Helm release to be upgraded and for
+// the k0rdent deployments to be ready
+func (i *Installer) finishUpgrade(changes *upgradeChanges) error {
+	target := changes.target
+
+	description := fmt.Sprintf("Wait for the k0rdent Helm release to be upgraded to %s", target.K0rdent)
+	err := i.host.Do(description, func() error {
+		return i.waitForK0rdentVersion(target.K0rdent)
+	})
+	if err != nil {
+		return fmt.Errorf("k0rdent upgrade failed: %w", err)
+	}
+	if err := i.host.Do("Wait for the k0rdent Helm chart to be installed", i.waitForK0rdentInstalled); err != nil {
+		return fmt.Errorf("k0rdent did not become ready: %w", err)
+	}
+
+	if !i.dryRun {
+		utils.GetLogger().Infof("✅ Upgraded to k0s %s and k0rdent %s", target.K0s, target.K0rdent)
+	}
+	return nil
+}
+
+// backup saves the k0s cluster state, the k0s configuration and the k0s
+// binary to a new directory of the backup directory
+func (i *Installer) backup(current Versions) error {
+	logger := utils.GetLogger()
+
+	dir := filepath.Join(i.backupDir, time.Now().UTC().Format("20060102-150405"))
+	if err := i.host.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if err := i.host.Run("k0s", "backup", "--save-path", dir); err != nil {
+		return fmt.Errorf("k0s backup failed: %w", err)
+	}
+
+	config, err := os.ReadFile(k0sConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read k0s config: %w", err)
+	}
+	if err := i.host.WriteFile(filepath.Join(dir, "k0s.yaml"), config, 0600, redactK0sConfig); err != nil {
+		return fmt.Errorf("failed to back up k0s config: %w", err)
+	}
+
+	binaryPath, err := exec.LookPath("k0s")
+	if err != nil {
+		return fmt.Errorf("failed to find the k0s binary: %w", err)
+	}
+	binary, err := os.Open(binaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to open the k0s binary: %w", err)
+	}
+	defer binary.Close()
+	if err := i.host.CopyFile(filepath.Join(dir, "k0s-"+current.K0s), binary, 0755); err != nil {
+		return fmt.Errorf("failed to back up the k0s binary: %w", err)
+	}
+
+	logger.Infof("✅ Backup saved to %s", dir)
+	return nil
+}
+
+// updateK0rdentChartVersion sets the k0rdent chart version in the k0s
+// configuration, keeping the rest of the file
+func (i *Installer) updateK0rdentChartVersion(version string) error {
+	data, err := os.ReadFile(k0sConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read k0s config: %w", err)
+	}
+	updated, err := generator.SetK0rdentChartVersion(data, version)
+	if err != nil {
+		return fmt.Errorf("failed to update the k0rdent chart version: %w", err)
+	}
+	if err := i.host.WriteFile(k0sConfigPath, updated, 0600, redactK0sConfig); err != nil {
+		return fmt.Errorf("failed to write k0s config: %w", err)
+	}
+	return nil
+}
+
+// replaceK0sForUpgrade replaces the stopped k0s binary: extracted from the
+// embedded assets in airgap mode, downloaded otherwise
+func (i *Installer) replaceK0sForUpgrade(version string) error {
+	if !airgap.IsAirGap() {
+		return i.replaceK0sBinary(version)
+	}
+
+	agInstaller := airgap.NewInstaller(i.config, i.debug)
+	agInstaller.SetHost(i.host)
+	if err := agInstaller.ExtractK0sBinary(); err != nil {
+		return fmt.Errorf("failed to extract k0s binary: %w", err)
+	}
+	return nil
+}
+
+// waitForK0rdentVersion waits for the k0s helm extension to deploy the
+// given version of the k0rdent chart
+func (i *Installer) waitForK0rdentVersion(version string) error {
+	if err := i.ensureK8sClient(); err != nil {
+		return err
+	}
+
+	namespace := "kcm-system"
+	if i.config != nil && i.config.K0rdent.Helm.Namespace != "" {
+		namespace = i.config.K0rdent.Helm.Namespace
+	}
+
+	return i.waitForWithSpinner(
+		i.timeout(config.TimeoutK0rdentUpgrade),
+		"helmrelease "+namespace+"/"+generator.K0rdentHelmReleaseName,
+		fmt.Sprintf("Waiting for the k0rdent Helm release to be upgraded to %s", version),
+		func(ctx context.Context) error {
+			return i.k8sClient.WaitForHelmReleaseVersion(ctx, namespace, generator.K0rdentHelmReleaseName, version)
+		},
+	)
+}
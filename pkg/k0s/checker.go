@@ -182,7 +182,7 @@ func runWithSpinner(message string, cmd *exec.Cmd, stdoutBuf, stderrBuf *bytes.B
 	}
 
 	err := cmd.Run()
-	stopSpinner <- true
+	stopSpinner <- err == nil
 	<-doneCh
 
 	return err
@@ -185,7 +185,10 @@ func FormatRunningConflictError(installedVersion, configVersion string) string {
 	sb.WriteString(fmt.Sprintf("   The installed k0s (%s) is currently running as a service.\n", installedVersion))
 	sb.WriteString(fmt.Sprintf("   Config specifies: %s\n", configVersion))
 	sb.WriteString("\n")
-	sb.WriteString("   To proceed, you must manually stop and reset k0s:\n")
+	sb.WriteString("   To upgrade the running cluster, backing it up first, run:\n")
+	sb.WriteString("     sudo k0rdentd upgrade\n")
+	sb.WriteString("\n")
+	sb.WriteString("   To reinstall instead, you must manually stop and reset k0s:\n")
 	sb.WriteString("     sudo k0s stop\n")
 	sb.WriteString("\n")
 	sb.WriteString("   Then run k0rdentd install again.")
@@ -0,0 +1,231 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AutopilotPlanName is the name of the k0s autopilot plan, autopilot only
+// processes the plan with this name
+const AutopilotPlanName = "autopilot"
+
+var (
+	// AutopilotPlanGVR is the resource of k0s autopilot plans
+	AutopilotPlanGVR = schema.GroupVersionResource{
+		Group:    "autopilot.k0sproject.io",
+		Version:  "v1beta2",
+		Resource: "plans",
+	}
+	// ControlNodeGVR is the resource autopilot registers each controller with
+	ControlNodeGVR = schema.GroupVersionResource{
+		Group:    "autopilot.k0sproject.io",
+		Version:  "v1beta2",
+		Resource: "controlnodes",
+	}
+)
+
+// Autopilot plan states, see the k0s autopilot documentation
+const (
+	AutopilotPlanCompleted = "Completed"
+	// AutopilotNodeCompleted is the state of an upgraded node
+	AutopilotNodeCompleted = "SignalCompleted"
+)
+
+// autopilotPlanFailedStates are the plan states autopilot doesn't recover from
+var autopilotPlanFailedStates = map[string]bool{
+	"IncompleteTargets":   true,
+	"InconsistentTargets": true,
+	"Restricted":          true,
+	"MissingPlatform":     true,
+	"MissingSignalNode":   true,
+	"ApplyFailed":         true,
+}
+
+// AutopilotPlatform is the k0s binary of a platform, e.g. linux-amd64
+type AutopilotPlatform struct {
+	URL    string
+	SHA256 string // Optional
+}
+
+// AutopilotNodeStatus is the upgrade progress of a node
+type AutopilotNodeStatus struct {
+	Name  string
+	Role  string // controller or worker
+	State string
+}
+
+// AutopilotPlanStatus is the progress of an autopilot plan
+type AutopilotPlanStatus struct {
+	ID    string
+	State string
+	Nodes []AutopilotNodeStatus
+}
+
+// IsCompleted returns true when every node is upgraded
+func (s *AutopilotPlanStatus) IsCompleted() bool {
+	return s.State == AutopilotPlanCompleted
+}
+
+// IsFailed returns true when autopilot gave up the plan
+func (s *AutopilotPlanStatus) IsFailed() bool {
+	return autopilotPlanFailedStates[s.State]
+}
+
+// NewAutopilotPlan builds a k0s autopilot plan upgrading the given
+// controllers and workers to a k0s version
+func NewAutopilotPlan(id, version string, platforms map[string]AutopilotPlatform, controllers, workers []string) *unstructured.Unstructured {
+	platformSpecs := make(map[string]interface{}, len(platforms))
+	for name, platform := range platforms {
+		spec := map[string]interface{}{"url": platform.URL}
+		if platform.SHA256 != "" {
+			spec["sha256"] = platform.SHA256
+		}
+		platformSpecs[name] = spec
+	}
+
+	update := map[string]interface{}{
+		"version":   version,
+		"platforms": platformSpecs,
+		"targets": map[string]interface{}{
+			"controllers": staticDiscovery(controllers),
+		},
+	}
+	if len(workers) > 0 {
+		update["targets"].(map[string]interface{})["workers"] = staticDiscovery(workers)
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "autopilot.k0sproject.io/v1beta2",
+			"kind":       "Plan",
+			"metadata": map[string]interface{}{
+				"name": AutopilotPlanName,
+			},
+			"spec": map[string]interface{}{
+				"id":        id,
+				"timestamp": "now",
+				"commands": []interface{}{
+					map[string]interface{}{"k0supdate": update},
+				},
+			},
+		},
+	}
+}
+
+// staticDiscovery returns the autopilot targets of the given nodes
+func staticDiscovery(nodes []string) map[string]interface{} {
+	names := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node)
+	}
+	return map[string]interface{}{
+		"discovery": map[string]interface{}{
+			"static": map[string]interface{}{
+				"nodes": names,
+			},
+		},
+	}
+}
+
+// ListControlNodes returns the names of the controllers registered with autopilot
+func (c *Client) ListControlNodes(ctx context.Context) ([]string, error) {
+	list, err := c.dynamicClient.Resource(ControlNodeGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list autopilot control nodes: %w", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListNodeNames returns the names of the Kubernetes nodes
+func (c *Client) ListNodeNames(ctx context.Context) ([]string, error) {
+	list, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, node := range list.Items {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ApplyAutopilotPlan creates the autopilot plan. A previous plan with the
+// same id is kept, so that an interrupted upgrade can be watched again; a
+// finished plan with another id is replaced.
+func (c *Client) ApplyAutopilotPlan(ctx context.Context, plan *unstructured.Unstructured) error {
+	plans := c.dynamicClient.Resource(AutopilotPlanGVR)
+
+	existing, err := plans.Get(ctx, AutopilotPlanName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return fmt.Errorf("failed to get autopilot plan: %w", err)
+	default:
+		id, _, _ := unstructured.NestedString(existing.Object, "spec", "id")
+		newID, _, _ := unstructured.NestedString(plan.Object, "spec", "id")
+		if id == newID {
+			return nil
+		}
+		status := autopilotPlanStatus(existing)
+		if !status.IsCompleted() && !status.IsFailed() {
+			return fmt.Errorf("autopilot plan %s is in progress (state: %s)", id, status.State)
+		}
+		if err := plans.Delete(ctx, AutopilotPlanName, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("failed to delete previous autopilot plan %s: %w", id, err)
+		}
+	}
+
+	if _, err := plans.Create(ctx, plan, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create autopilot plan: %w", err)
+	}
+	return nil
+}
+
+// GetAutopilotPlanStatus returns the progress of the autopilot plan
+func (c *Client) GetAutopilotPlanStatus(ctx context.Context) (*AutopilotPlanStatus, error) {
+	plan, err := c.dynamicClient.Resource(AutopilotPlanGVR).Get(ctx, AutopilotPlanName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get autopilot plan: %w", err)
+	}
+	return autopilotPlanStatus(plan), nil
+}
+
+// autopilotPlanStatus reads the status of a plan with a single k0supdate command
+func autopilotPlanStatus(plan *unstructured.Unstructured) *AutopilotPlanStatus {
+	status := &AutopilotPlanStatus{}
+	status.ID, _, _ = unstructured.NestedString(plan.Object, "spec", "id")
+	status.State, _, _ = unstructured.NestedString(plan.Object, "status", "state")
+
+	commands, _, _ := unstructured.NestedSlice(plan.Object, "status", "commands")
+	for _, command := range commands {
+		commandMap, ok := command.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, role := range []string{"controller", "worker"} {
+			nodes, _, _ := unstructured.NestedSlice(commandMap, "k0supdate", role+"s")
+			for _, node := range nodes {
+				nodeMap, ok := node.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _, _ := unstructured.NestedString(nodeMap, "name")
+				state, _, _ := unstructured.NestedString(nodeMap, "state")
+				status.Nodes = append(status.Nodes, AutopilotNodeStatus{Name: name, Role: role, State: state})
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,102 @@
+package k8sclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newAutopilotClient(objects ...runtime.Object) (*k8sclient.Client, *dynamicfake.FakeDynamicClient) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			k8sclient.AutopilotPlanGVR: "PlanList",
+			k8sclient.ControlNodeGVR:   "ControlNodeList",
+		}, objects...)
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "controller-0"}},
+	)
+	return k8sclient.NewFromClientsetAndDynamic(clientset, dynamicClient), dynamicClient
+}
+
+func TestAutopilotPlan(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+
+	controlNode := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autopilot.k0sproject.io/v1beta2",
+		"kind":       "ControlNode",
+		"metadata":   map[string]interface{}{"name": "controller-0"},
+	}}
+	client, dynamicClient := newAutopilotClient(controlNode)
+
+	controllers, err := client.ListControlNodes(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(controllers).To(gomega.Equal([]string{"controller-0"}))
+	nodes, err := client.ListNodeNames(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(nodes).To(gomega.Equal([]string{"controller-0", "worker-1"}))
+
+	plan := k8sclient.NewAutopilotPlan("upgrade-1", "v1.33.1+k0s.0",
+		map[string]k8sclient.AutopilotPlatform{
+			"linux-amd64": {URL: "http://10.0.0.1:5000/k0s/k0s-v1.33.1+k0s.0-amd64", SHA256: "abc"},
+		},
+		[]string{"controller-0"}, []string{"worker-1"})
+	g.Expect(plan.GetName()).To(gomega.Equal(k8sclient.AutopilotPlanName))
+	commands, _, _ := unstructured.NestedSlice(plan.Object, "spec", "commands")
+	g.Expect(commands).To(gomega.HaveLen(1))
+	workers, _, _ := unstructured.NestedSlice(commands[0].(map[string]interface{}),
+		"k0supdate", "targets", "workers", "discovery", "static", "nodes")
+	g.Expect(workers).To(gomega.Equal([]interface{}{"worker-1"}))
+
+	g.Expect(client.ApplyAutopilotPlan(ctx, plan)).To(gomega.Succeed())
+
+	// Report the progress of each node
+	created, err := dynamicClient.Resource(k8sclient.AutopilotPlanGVR).Get(ctx, k8sclient.AutopilotPlanName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(unstructured.SetNestedField(created.Object, "Schedulable", "status", "state")).To(gomega.Succeed())
+	g.Expect(unstructured.SetNestedSlice(created.Object, []interface{}{
+		map[string]interface{}{
+			"k0supdate": map[string]interface{}{
+				"controllers": []interface{}{map[string]interface{}{"name": "controller-0", "state": "SignalCompleted"}},
+				"workers":     []interface{}{map[string]interface{}{"name": "worker-1", "state": "SignalSent"}},
+			},
+		},
+	}, "status", "commands")).To(gomega.Succeed())
+	_, err = dynamicClient.Resource(k8sclient.AutopilotPlanGVR).Update(ctx, created, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	status, err := client.GetAutopilotPlanStatus(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.ID).To(gomega.Equal("upgrade-1"))
+	g.Expect(status.IsCompleted()).To(gomega.BeFalse())
+	g.Expect(status.IsFailed()).To(gomega.BeFalse())
+	g.Expect(status.Nodes).To(gomega.Equal([]k8sclient.AutopilotNodeStatus{
+		{Name: "controller-0", Role: "controller", State: "SignalCompleted"},
+		{Name: "worker-1", Role: "worker", State: "SignalSent"},
+	}))
+
+	// The plan in progress is kept when applied again, other plans must wait
+	g.Expect(client.ApplyAutopilotPlan(ctx, plan)).To(gomega.Succeed())
+	other := k8sclient.NewAutopilotPlan("upgrade-2", "v1.33.2+k0s.0", nil, []string{"controller-0"}, nil)
+	g.Expect(client.ApplyAutopilotPlan(ctx, other)).To(gomega.MatchError(gomega.ContainSubstring("autopilot plan upgrade-1 is in progress")))
+
+	// A completed plan is replaced
+	g.Expect(unstructured.SetNestedField(created.Object, "Completed", "status", "state")).To(gomega.Succeed())
+	_, err = dynamicClient.Resource(k8sclient.AutopilotPlanGVR).Update(ctx, created, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(client.ApplyAutopilotPlan(ctx, other)).To(gomega.Succeed())
+	status, err = client.GetAutopilotPlanStatus(ctx)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.ID).To(gomega.Equal("upgrade-2"))
+	g.Expect(status.State).To(gomega.BeEmpty())
+}
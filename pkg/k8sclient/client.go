@@ -10,8 +10,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -35,6 +37,11 @@ type HelmRelease struct {
 	Info struct {
 		Status string `json:"status"`
 	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Version string `json:"version"`
+		} `json:"metadata"`
+	} `json:"chart"`
 }
 
 // New creates a new Client from a REST config
@@ -127,11 +134,15 @@ func (c *Client) IsDeploymentReady(ctx context.Context, namespace, name string)
 		return false, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
 	}
 
+	return deploymentReady(deployment), nil
+}
+
+// deploymentReady tells whether every replica of a deployment is ready
+func deploymentReady(deployment *appsv1.Deployment) bool {
 	if deployment.Spec.Replicas == nil {
-		return false, nil
+		return false
 	}
-
-	return deployment.Status.ReadyReplicas == *deployment.Spec.Replicas, nil
+	return deployment.Status.ReadyReplicas == *deployment.Spec.Replicas
 }
 
 // GetPodPhases returns the phases of pods matching the label selector in the given namespace
@@ -286,15 +297,18 @@ func (c *Client) CreateSecret(ctx context.Context, secret *corev1.Secret) error
 	return nil
 }
 
-// CreateAWSClusterStaticIdentity creates an AWSClusterStaticIdentity custom resource
-func (c *Client) CreateAWSClusterStaticIdentity(ctx context.Context, name, secretRef, namespace string) error {
-	gvr := schema.GroupVersionResource{
-		Group:    "infrastructure.cluster.x-k8s.io",
-		Version:  "v1beta2",
-		Resource: "awsclusterstaticidentities",
+// DeleteSecret deletes a Kubernetes Secret, a missing Secret is not an error
+func (c *Client) DeleteSecret(ctx context.Context, namespace, name string) error {
+	err := c.clientset.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret %s/%s: %w", namespace, name, err)
 	}
+	return nil
+}
 
-	identity := &unstructured.Unstructured{
+// NewAWSClusterStaticIdentity builds an AWSClusterStaticIdentity custom resource
+func NewAWSClusterStaticIdentity(name, secretRef, namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta2",
 			"kind":       "AWSClusterStaticIdentity",
@@ -315,6 +329,71 @@ func (c *Client) CreateAWSClusterStaticIdentity(ctx context.Context, name, secre
 			},
 		},
 	}
+}
+
+// NewAzureClusterIdentity builds an AzureClusterIdentity custom resource
+func NewAzureClusterIdentity(name, clientID, tenantID, secretName, namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta1",
+			"kind":       "AzureClusterIdentity",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"clusterctl.cluster.x-k8s.io/move-hierarchy": "true",
+					"k0rdent.mirantis.com/component":             "kcm",
+				},
+			},
+			"spec": map[string]interface{}{
+				"type":              "ServicePrincipal",
+				"clientID":          clientID,
+				"tenantID":          tenantID,
+				"allowedNamespaces": map[string]interface{}{},
+				"clientSecret": map[string]interface{}{
+					"name":      secretName,
+					"namespace": namespace,
+				},
+			},
+		},
+	}
+}
+
+// NewCredential builds a k0rdent Credential custom resource referencing an identity
+func NewCredential(name, description, identityKind, identityName, identityAPIVersion, namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "k0rdent.mirantis.com/v1beta1",
+			"kind":       "Credential",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"k0rdent.mirantis.com/component": "kcm",
+				},
+			},
+			"spec": map[string]interface{}{
+				"description": description,
+				"identityRef": map[string]interface{}{
+					"apiVersion": identityAPIVersion,
+					"kind":       identityKind,
+					"name":       identityName,
+					"namespace":  namespace,
+				},
+			},
+		},
+	}
+}
+
+// CreateAWSClusterStaticIdentity creates an AWSClusterStaticIdentity custom resource
+func (c *Client) CreateAWSClusterStaticIdentity(ctx context.Context, name, secretRef, namespace string) error {
+	gvr := schema.GroupVersionResource{
+		Group:    "infrastructure.cluster.x-k8s.io",
+		Version:  "v1beta2",
+		Resource: "awsclusterstaticidentities",
+	}
+
+	identity := NewAWSClusterStaticIdentity(name, secretRef, namespace)
 
 	_, err := c.dynamicClient.Resource(gvr).Create(ctx, identity, metav1.CreateOptions{})
 	if err != nil {
@@ -344,30 +423,7 @@ func (c *Client) CreateAzureClusterIdentity(ctx context.Context, name, clientID,
 		Resource: "azureclusteridentities",
 	}
 
-	identity := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta1",
-			"kind":       "AzureClusterIdentity",
-			"metadata": map[string]interface{}{
-				"name":      name,
-				"namespace": namespace,
-				"labels": map[string]interface{}{
-					"clusterctl.cluster.x-k8s.io/move-hierarchy": "true",
-					"k0rdent.mirantis.com/component":             "kcm",
-				},
-			},
-			"spec": map[string]interface{}{
-				"type":              "ServicePrincipal",
-				"clientID":          clientID,
-				"tenantID":          tenantID,
-				"allowedNamespaces": map[string]interface{}{},
-				"clientSecret": map[string]interface{}{
-					"name":      secretName,
-					"namespace": namespace,
-				},
-			},
-		},
-	}
+	identity := NewAzureClusterIdentity(name, clientID, tenantID, secretName, namespace)
 
 	_, err := c.dynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, identity, metav1.CreateOptions{})
 	if err != nil {
@@ -397,28 +453,7 @@ func (c *Client) CreateCredential(ctx context.Context, name, description, identi
 		Resource: "credentials",
 	}
 
-	credential := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"apiVersion": "k0rdent.mirantis.com/v1beta1",
-			"kind":       "Credential",
-			"metadata": map[string]interface{}{
-				"name":      name,
-				"namespace": namespace,
-				"labels": map[string]interface{}{
-					"k0rdent.mirantis.com/component": "kcm",
-				},
-			},
-			"spec": map[string]interface{}{
-				"description": description,
-				"identityRef": map[string]interface{}{
-					"apiVersion": identityAPIVersion,
-					"kind":       identityKind,
-					"name":       identityName,
-					"namespace":  namespace,
-				},
-			},
-		},
-	}
+	credential := NewCredential(name, description, identityKind, identityName, identityAPIVersion, namespace)
 
 	_, err := c.dynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, credential, metav1.CreateOptions{})
 	if err != nil {
@@ -466,27 +501,9 @@ func (c *Client) GetHelmReleaseStatus(ctx context.Context, namespace, releaseNam
 
 	// The release status is stored in the secret's data
 	if releaseData, ok := secret.Data["release"]; ok {
-
-		releaseGzipped, err := base64.StdEncoding.DecodeString(string(releaseData))
+		release, err := decodeHelmRelease(releaseData)
 		if err != nil {
-			return HelmReleaseStatusUnknown, fmt.Errorf("issue getting content of release secret: %w", err)
-		}
-
-		gzipReader, err := gzip.NewReader(bytes.NewReader(releaseGzipped))
-		if err != nil {
-			return HelmReleaseStatusUnknown, fmt.Errorf("issue getting content of release secret: %w", err)
-		}
-
-		defer gzipReader.Close()
-
-		releaseJson, err := io.ReadAll(gzipReader)
-		if err != nil {
-			return HelmReleaseStatusUnknown, fmt.Errorf("issue getting content of release secret: %w", err)
-		}
-
-		var release HelmRelease
-		if err := json.Unmarshal(releaseJson, &release); err != nil {
-			return HelmReleaseStatusUnknown, fmt.Errorf("unable to extract status content from release")
+			return HelmReleaseStatusUnknown, err
 		}
 
 		// Parse the status to determine if it's deployed
@@ -503,6 +520,78 @@ func (c *Client) GetHelmReleaseStatus(ctx context.Context, namespace, releaseNam
 	return HelmReleaseStatusUnknown, nil
 }
 
+// GetDeployedHelmReleaseChartVersion returns the chart version of the
+// deployed revision of a Helm release, or "" if no revision is deployed
+func (c *Client) GetDeployedHelmReleaseChartVersion(ctx context.Context, namespace, releaseName string) (string, error) {
+	// Every revision is stored in a secret labeled with the release name and status
+	secrets, err := c.clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s,status=deployed", releaseName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list Helm release secrets of %s/%s: %w", namespace, releaseName, err)
+	}
+
+	candidates := make([]*corev1.Secret, 0, len(secrets.Items))
+	for idx := range secrets.Items {
+		candidates = append(candidates, &secrets.Items[idx])
+	}
+	return deployedChartVersion(candidates, releaseName)
+}
+
+// deployedChartVersion returns the chart version of the latest deployed
+// revision of a release among Helm release secrets, or ""
+func deployedChartVersion(secrets []*corev1.Secret, releaseName string) (string, error) {
+	var latest *corev1.Secret
+	latestRevision := -1
+	for _, secret := range secrets {
+		if secret.Labels["owner"] != "helm" || secret.Labels["name"] != releaseName || secret.Labels["status"] != "deployed" {
+			continue
+		}
+		revision, err := strconv.Atoi(secret.Labels["version"])
+		if err != nil {
+			continue
+		}
+		if revision > latestRevision {
+			latest, latestRevision = secret, revision
+		}
+	}
+	if latest == nil {
+		return "", nil
+	}
+
+	release, err := decodeHelmRelease(latest.Data["release"])
+	if err != nil {
+		return "", err
+	}
+	return release.Chart.Metadata.Version, nil
+}
+
+// decodeHelmRelease decodes the release stored in a Helm release secret:
+// base64 encoded, gzipped JSON
+func decodeHelmRelease(releaseData []byte) (*HelmRelease, error) {
+	releaseGzipped, err := base64.StdEncoding.DecodeString(string(releaseData))
+	if err != nil {
+		return nil, fmt.Errorf("issue getting content of release secret: %w", err)
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(releaseGzipped))
+	if err != nil {
+		return nil, fmt.Errorf("issue getting content of release secret: %w", err)
+	}
+	defer gzipReader.Close()
+
+	releaseJson, err := io.ReadAll(gzipReader)
+	if err != nil {
+		return nil, fmt.Errorf("issue getting content of release secret: %w", err)
+	}
+
+	var release HelmRelease
+	if err := json.Unmarshal(releaseJson, &release); err != nil {
+		return nil, fmt.Errorf("unable to extract status content from release")
+	}
+	return &release, nil
+}
+
 // IsHelmReleaseReady checks if a Helm release is deployed successfully
 func (c *Client) IsHelmReleaseReady(ctx context.Context, namespace, releaseName string) (bool, error) {
 	status, err := c.GetHelmReleaseStatus(ctx, namespace, releaseName)
@@ -583,28 +672,7 @@ func (c *Client) CreateOpenStackCredential(ctx context.Context, name, descriptio
 		Resource: "credentials",
 	}
 
-	credential := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"apiVersion": "k0rdent.mirantis.com/v1beta1",
-			"kind":       "Credential",
-			"metadata": map[string]interface{}{
-				"name":      name,
-				"namespace": namespace,
-				"labels": map[string]interface{}{
-					"k0rdent.mirantis.com/component": "kcm",
-				},
-			},
-			"spec": map[string]interface{}{
-				"description": description,
-				"identityRef": map[string]interface{}{
-					"apiVersion": "v1",
-					"kind":       "Secret",
-					"name":       secretName,
-					"namespace":  namespace,
-				},
-			},
-		},
-	}
+	credential := NewCredential(name, description, "Secret", secretName, "v1", namespace)
 
 	_, err := c.dynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, credential, metav1.CreateOptions{})
 	if err != nil {
@@ -1,7 +1,10 @@
 package k8sclient_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"testing"
 
@@ -700,6 +703,60 @@ func TestAreAllDeploymentsReady(t *testing.T) {
 	})
 }
 
+// helmReleaseSecret returns the secret Helm stores for a release revision
+func helmReleaseSecret(g *gomega.WithT, name string, revision int, status, chartVersion string) *corev1.Secret {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, err := fmt.Fprintf(writer, `{"info":{"status":%q},"chart":{"metadata":{"version":%q}}}`, status, chartVersion)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(writer.Close()).To(gomega.Succeed())
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, revision),
+			Namespace: "kcm-system",
+			Labels: map[string]string{
+				"owner":   "helm",
+				"name":    name,
+				"status":  status,
+				"version": fmt.Sprint(revision),
+			},
+		},
+		Data: map[string][]byte{
+			"release": []byte(base64.StdEncoding.EncodeToString(gzipped.Bytes())),
+		},
+	}
+}
+
+func TestGetDeployedHelmReleaseChartVersion(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	t.Run("should return the chart version of the deployed revision", func(t *testing.T) {
+		ctx := context.Background()
+		fakeClient := fake.NewSimpleClientset(
+			helmReleaseSecret(g, "kcm", 1, "superseded", "1.1.0"),
+			helmReleaseSecret(g, "kcm", 2, "deployed", "1.2.2"),
+			helmReleaseSecret(g, "kcm", 3, "pending-upgrade", "1.3.0"),
+			helmReleaseSecret(g, "other", 1, "deployed", "0.1.0"),
+		)
+		client := k8sclient.NewFromClientset(fakeClient)
+
+		version, err := client.GetDeployedHelmReleaseChartVersion(ctx, "kcm-system", "kcm")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(version).To(gomega.Equal("1.2.2"))
+	})
+
+	t.Run("should return an empty version when no revision is deployed", func(t *testing.T) {
+		ctx := context.Background()
+		fakeClient := fake.NewSimpleClientset(helmReleaseSecret(g, "kcm", 1, "failed", "1.2.2"))
+		client := k8sclient.NewFromClientset(fakeClient)
+
+		version, err := client.GetDeployedHelmReleaseChartVersion(ctx, "kcm-system", "kcm")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(version).To(gomega.BeEmpty())
+	})
+}
+
 func TestErrorHandling(t *testing.T) {
 	g := gomega.NewWithT(t)
 
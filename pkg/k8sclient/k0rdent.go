@@ -0,0 +1,168 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// K0rdentGroup is the API group of the k0rdent CRDs
+	K0rdentGroup = "k0rdent.mirantis.com"
+	// ManagementName is the name of the k0rdent Management object
+	ManagementName = "kcm"
+	// K0sChartNamespace holds the Chart objects of the k0s helm extension
+	K0sChartNamespace = "kube-system"
+)
+
+var (
+	// ManagementGVR is the resource of the k0rdent Management object, whose
+	// finalizer uninstalls the providers
+	ManagementGVR = schema.GroupVersionResource{
+		Group:    K0rdentGroup,
+		Version:  "v1beta1",
+		Resource: "managements",
+	}
+	// HelmChartGVR is the resource of the charts of the k0s helm extension
+	HelmChartGVR = schema.GroupVersionResource{
+		Group:    "helm.k0sproject.io",
+		Version:  "v1beta1",
+		Resource: "charts",
+	}
+	// CRDGVR is the resource of custom resource definitions
+	CRDGVR = schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
+	}
+)
+
+// CRD is a custom resource definition and the resource it serves
+type CRD struct {
+	Name       string
+	GVR        schema.GroupVersionResource
+	Namespaced bool
+}
+
+// K0sChartName returns the name of the Chart object of a release of the k0s
+// helm extension
+func K0sChartName(release string) string {
+	return "k0s-addon-chart-" + release
+}
+
+// DeleteResource deletes an object, cluster-scoped if namespace is empty.
+// A missing object is not an error.
+func (c *Client) DeleteResource(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	err := c.resource(gvr, namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s %s: %w", gvr.Resource, qualifiedName(namespace, name), err)
+	}
+	return nil
+}
+
+// ResourceExists checks if an object exists, cluster-scoped if namespace is
+// empty. A resource the cluster doesn't serve has no objects.
+func (c *Client) ResourceExists(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (bool, error) {
+	_, err := c.resource(gvr, namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get %s %s: %w", gvr.Resource, qualifiedName(namespace, name), err)
+	}
+	return true, nil
+}
+
+// DeleteNamespace deletes a namespace, a missing namespace is not an error
+func (c *Client) DeleteNamespace(ctx context.Context, name string) error {
+	err := c.clientset.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// ListCRDs returns the custom resource definitions of an API group, sorted
+// by name
+func (c *Client) ListCRDs(ctx context.Context, group string) ([]CRD, error) {
+	list, err := c.dynamicClient.Resource(CRDGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom resource definitions: %w", err)
+	}
+
+	var crds []CRD
+	for _, item := range list.Items {
+		if crdGroup, _, _ := unstructured.NestedString(item.Object, "spec", "group"); crdGroup != group {
+			continue
+		}
+		plural, _, _ := unstructured.NestedString(item.Object, "spec", "names", "plural")
+		scope, _, _ := unstructured.NestedString(item.Object, "spec", "scope")
+		crds = append(crds, CRD{
+			Name:       item.GetName(),
+			GVR:        schema.GroupVersionResource{Group: group, Version: storageVersion(&item), Resource: plural},
+			Namespaced: scope == "Namespaced",
+		})
+	}
+	sort.Slice(crds, func(a, b int) bool { return crds[a].Name < crds[b].Name })
+	return crds, nil
+}
+
+// ListFinalizedObjects returns the objects of a custom resource holding
+// finalizers, as namespace/name
+func (c *Client) ListFinalizedObjects(ctx context.Context, crd CRD) ([]string, error) {
+	list, err := c.dynamicClient.Resource(crd.GVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", crd.GVR.Resource, err)
+	}
+
+	var names []string
+	for _, item := range list.Items {
+		if len(item.GetFinalizers()) > 0 {
+			names = append(names, qualifiedName(item.GetNamespace(), item.GetName()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resource returns the dynamic client of a resource, cluster-scoped if
+// namespace is empty
+func (c *Client) resource(gvr schema.GroupVersionResource, namespace string) dynamic.ResourceInterface {
+	if namespace == "" {
+		return c.dynamicClient.Resource(gvr)
+	}
+	return c.dynamicClient.Resource(gvr).Namespace(namespace)
+}
+
+// storageVersion returns the version a CRD stores its objects in
+func storageVersion(crd *unstructured.Unstructured) string {
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, version := range versions {
+		spec, ok := version.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if storage, _ := spec["storage"].(bool); storage {
+			name, _ := spec["name"].(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// qualifiedName returns namespace/name, or name for cluster-scoped objects
+func qualifiedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
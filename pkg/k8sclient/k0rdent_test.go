@@ -0,0 +1,93 @@
+package k8sclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newCRD builds a custom resource definition serving v1beta1
+func newCRD(group, plural, scope string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": plural + "." + group},
+		"spec": map[string]interface{}{
+			"group": group,
+			"scope": scope,
+			"names": map[string]interface{}{"plural": plural},
+			"versions": []interface{}{
+				map[string]interface{}{"name": "v1alpha1", "storage": false},
+				map[string]interface{}{"name": "v1beta1", "storage": true},
+			},
+		},
+	}}
+}
+
+func TestK0rdentResources(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+
+	templatesGVR := schema.GroupVersionResource{Group: k8sclient.K0rdentGroup, Version: "v1beta1", Resource: "clustertemplates"}
+	template := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "k0rdent.mirantis.com/v1beta1",
+		"kind":       "ClusterTemplate",
+		"metadata": map[string]interface{}{
+			"name":       "aws-standalone",
+			"namespace":  "kcm-system",
+			"finalizers": []interface{}{"k0rdent.mirantis.com/cleanup"},
+		},
+	}}
+	management := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "k0rdent.mirantis.com/v1beta1",
+		"kind":       "Management",
+		"metadata":   map[string]interface{}{"name": k8sclient.ManagementName},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			k8sclient.CRDGVR: "CustomResourceDefinitionList",
+			templatesGVR:     "ClusterTemplateList",
+		},
+		newCRD(k8sclient.K0rdentGroup, "clustertemplates", "Namespaced"),
+		newCRD(k8sclient.K0rdentGroup, "managements", "Cluster"),
+		newCRD("cert-manager.io", "certificates", "Namespaced"),
+		template, management)
+	clientset := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kcm-system"}})
+	client := k8sclient.NewFromClientsetAndDynamic(clientset, dynamicClient)
+
+	crds, err := client.ListCRDs(ctx, k8sclient.K0rdentGroup)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(crds).To(gomega.Equal([]k8sclient.CRD{
+		{Name: "clustertemplates.k0rdent.mirantis.com", GVR: templatesGVR, Namespaced: true},
+		{Name: "managements.k0rdent.mirantis.com", GVR: k8sclient.ManagementGVR},
+	}))
+
+	finalized, err := client.ListFinalizedObjects(ctx, crds[0])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(finalized).To(gomega.Equal([]string{"kcm-system/aws-standalone"}))
+
+	exists, err := client.ResourceExists(ctx, k8sclient.ManagementGVR, "", k8sclient.ManagementName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(exists).To(gomega.BeTrue())
+	g.Expect(client.DeleteResource(ctx, k8sclient.ManagementGVR, "", k8sclient.ManagementName)).To(gomega.Succeed())
+	exists, err = client.ResourceExists(ctx, k8sclient.ManagementGVR, "", k8sclient.ManagementName)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(exists).To(gomega.BeFalse())
+
+	// Missing objects are already deleted
+	g.Expect(client.DeleteResource(ctx, k8sclient.HelmChartGVR, k8sclient.K0sChartNamespace, k8sclient.K0sChartName("kcm"))).To(gomega.Succeed())
+	g.Expect(client.DeleteNamespace(ctx, "kcm-system")).To(gomega.Succeed())
+	g.Expect(client.DeleteNamespace(ctx, "kcm-system")).To(gomega.Succeed())
+	exists, err = client.NamespaceExists(ctx, "kcm-system")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(exists).To(gomega.BeFalse())
+}
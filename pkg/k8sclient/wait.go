@@ -0,0 +1,164 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchSyncTimeout bounds the initial listing of a watch, the waits fall back
+// to polling when the watch can't be set up in time
+const watchSyncTimeout = 30 * time.Second
+
+// WaitForDeploymentsReady waits until every replica of the named deployments
+// is ready, or ctx is done
+func (c *Client) WaitForDeploymentsReady(ctx context.Context, namespace string, names []string) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0, informers.WithNamespace(namespace))
+	informer := factory.Apps().V1().Deployments().Informer()
+
+	return waitWithInformer(ctx, informer, func(store cache.Store) (bool, error) {
+		for _, name := range names {
+			obj, found, err := store.GetByKey(namespace + "/" + name)
+			if err != nil || !found {
+				return false, err
+			}
+			if !deploymentReady(obj.(*appsv1.Deployment)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, func() (bool, error) {
+		return c.AreAllDeploymentsReady(ctx, namespace, names)
+	})
+}
+
+// WaitForHelmReleasesDeployed waits until a revision of each release is
+// deployed, or ctx is done
+func (c *Client) WaitForHelmReleasesDeployed(ctx context.Context, namespace string, releases []string) error {
+	return c.waitForHelmReleases(ctx, namespace, func(secrets []*corev1.Secret) (bool, error) {
+		for _, release := range releases {
+			version, err := deployedChartVersion(secrets, release)
+			if err != nil || version == "" {
+				return false, err
+			}
+		}
+		return true, nil
+	}, func() (bool, error) {
+		for _, release := range releases {
+			ready, err := c.IsHelmReleaseReady(ctx, namespace, release)
+			if err != nil || !ready {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// WaitForHelmReleaseVersion waits until the latest deployed revision of a
+// release has the given chart version, or ctx is done
+func (c *Client) WaitForHelmReleaseVersion(ctx context.Context, namespace, release, version string) error {
+	return c.waitForHelmReleases(ctx, namespace, func(secrets []*corev1.Secret) (bool, error) {
+		deployed, err := deployedChartVersion(secrets, release)
+		return deployed == version, err
+	}, func() (bool, error) {
+		deployed, err := c.GetDeployedHelmReleaseChartVersion(ctx, namespace, release)
+		return deployed == version, err
+	})
+}
+
+// waitForHelmReleases watches the Helm release secrets of a namespace until
+// check accepts them
+func (c *Client) waitForHelmReleases(ctx context.Context, namespace string, check func([]*corev1.Secret) (bool, error), poll func() (bool, error)) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = "owner=helm"
+		}),
+	)
+	informer := factory.Core().V1().Secrets().Informer()
+
+	return waitWithInformer(ctx, informer, func(store cache.Store) (bool, error) {
+		var secrets []*corev1.Secret
+		for _, obj := range store.List() {
+			secrets = append(secrets, obj.(*corev1.Secret))
+		}
+		return check(secrets)
+	}, poll)
+}
+
+// WaitForNamespaceDeleted waits until a namespace is gone, or ctx is done
+func (c *Client) WaitForNamespaceDeleted(ctx context.Context, name string) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}),
+	)
+	informer := factory.Core().V1().Namespaces().Informer()
+
+	return waitWithInformer(ctx, informer, func(store cache.Store) (bool, error) {
+		_, found, err := store.GetByKey(name)
+		return !found, err
+	}, func() (bool, error) {
+		found, err := c.NamespaceExists(ctx, name)
+		return !found, err
+	})
+}
+
+// waitWithInformer runs informer and evaluates check against its cache on
+// each change, until check returns true or ctx is done. Check errors are
+// logged and the wait goes on, as with PollWithBackoff. When the cache can't
+// be synced, e.g. because the watch is refused, it polls with poll instead.
+func waitWithInformer(ctx context.Context, informer cache.SharedIndexInformer, check func(cache.Store) (bool, error), poll func() (bool, error)) error {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch: %w", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+
+	syncCtx, cancel := context.WithTimeout(ctx, watchSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		utils.GetLogger().Debug("Watch could not be set up, polling instead")
+		return utils.PollWithBackoff(ctx, poll)
+	}
+
+	for {
+		done, err := check(informer.GetStore())
+		if err != nil {
+			utils.GetLogger().Debugf("Check failed, waiting for the next change: %v", err)
+		}
+		if done && err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package k8sclient_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/k8sclient"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	"github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// watchStarted returns a channel closed once the client watches, changes
+// made before would be missed by the fake clientset
+func watchStarted(fakeClient *fake.Clientset) <-chan struct{} {
+	started := make(chan struct{})
+	var once sync.Once
+	fakeClient.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := fakeClient.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		once.Do(func() { close(started) })
+		return true, w, err
+	})
+	return started
+}
+
+// checkFailed returns a channel closed once a wait logs a failed check
+func checkFailed(t *testing.T) <-chan struct{} {
+	failed := make(chan struct{})
+	var once sync.Once
+	logger := utils.GetLogger()
+	output, level := logger.Out, logger.GetLevel()
+	logger.SetOutput(writerFunc(func(p []byte) (int, error) {
+		if bytes.Contains(p, []byte("Check failed")) {
+			once.Do(func() { close(failed) })
+		}
+		return len(p), nil
+	}))
+	logger.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		logger.SetOutput(output)
+		logger.SetLevel(level)
+	})
+	return failed
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func deployment(name string, replicas, ready int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kcm-system"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: ready},
+	}
+}
+
+func TestWaitForDeploymentsReady(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	t.Run("should return once the deployments become ready", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		fakeClient := fake.NewSimpleClientset(deployment("kcm-controller-manager", 1, 1), deployment("velero", 1, 0))
+		client := k8sclient.NewFromClientset(fakeClient)
+
+		started := watchStarted(fakeClient)
+		updated := make(chan error, 1)
+		go func() {
+			<-started
+			_, err := fakeClient.AppsV1().Deployments("kcm-system").UpdateStatus(ctx, deployment("velero", 1, 1), metav1.UpdateOptions{})
+			updated <- err
+		}()
+
+		err := client.WaitForDeploymentsReady(ctx, "kcm-system", []string{"kcm-controller-manager", "velero"})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(<-updated).To(gomega.Succeed())
+	})
+
+	t.Run("should fail when a deployment is missing at the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		client := k8sclient.NewFromClientset(fake.NewSimpleClientset(deployment("kcm-controller-manager", 1, 1)))
+
+		err := client.WaitForDeploymentsReady(ctx, "kcm-system", []string{"kcm-controller-manager", "velero"})
+		g.Expect(err).To(gomega.MatchError(context.DeadlineExceeded))
+	})
+}
+
+func TestWaitForHelmReleasesDeployed(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	t.Run("should return once every release is deployed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		fakeClient := fake.NewSimpleClientset(helmReleaseSecret(g, "cluster-api", 1, "deployed", "1.0.0"))
+		client := k8sclient.NewFromClientset(fakeClient)
+
+		secret := helmReleaseSecret(g, "cluster-api-provider-aws", 1, "deployed", "2.0.0")
+		started := watchStarted(fakeClient)
+		created := make(chan error, 1)
+		go func() {
+			<-started
+			_, err := fakeClient.CoreV1().Secrets("kcm-system").Create(ctx, secret, metav1.CreateOptions{})
+			created <- err
+		}()
+
+		err := client.WaitForHelmReleasesDeployed(ctx, "kcm-system", []string{"cluster-api", "cluster-api-provider-aws"})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(<-created).To(gomega.Succeed())
+	})
+
+	t.Run("should fail when a release is still pending at the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		client := k8sclient.NewFromClientset(fake.NewSimpleClientset(helmReleaseSecret(g, "cluster-api", 1, "pending-install", "1.0.0")))
+
+		err := client.WaitForHelmReleasesDeployed(ctx, "kcm-system", []string{"cluster-api"})
+		g.Expect(err).To(gomega.MatchError(context.DeadlineExceeded))
+	})
+}
+
+func TestWaitForHelmReleaseVersion(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	t.Run("should return once the version is deployed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		fakeClient := fake.NewSimpleClientset(helmReleaseSecret(g, "kcm", 1, "deployed", "1.1.0"))
+		client := k8sclient.NewFromClientset(fakeClient)
+
+		superseded := helmReleaseSecret(g, "kcm", 1, "superseded", "1.1.0")
+		upgraded := helmReleaseSecret(g, "kcm", 2, "deployed", "1.2.2")
+		started := watchStarted(fakeClient)
+		changed := make(chan error, 1)
+		go func() {
+			<-started
+			_, err := fakeClient.CoreV1().Secrets("kcm-system").Update(ctx, superseded, metav1.UpdateOptions{})
+			if err == nil {
+				_, err = fakeClient.CoreV1().Secrets("kcm-system").Create(ctx, upgraded, metav1.CreateOptions{})
+			}
+			changed <- err
+		}()
+
+		err := client.WaitForHelmReleaseVersion(ctx, "kcm-system", "kcm", "1.2.2")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(<-changed).To(gomega.Succeed())
+	})
+
+	t.Run("should keep waiting when a release can't be decoded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		corrupted := helmReleaseSecret(g, "kcm", 1, "deployed", "1.2.2")
+		corrupted.Data["release"] = []byte("not a release")
+		fakeClient := fake.NewSimpleClientset(corrupted)
+		client := k8sclient.NewFromClientset(fakeClient)
+
+		fixed := helmReleaseSecret(g, "kcm", 1, "deployed", "1.2.2")
+		failed := checkFailed(t)
+		updated := make(chan error, 1)
+		go func() {
+			<-failed
+			_, err := fakeClient.CoreV1().Secrets("kcm-system").Update(ctx, fixed, metav1.UpdateOptions{})
+			updated <- err
+		}()
+
+		err := client.WaitForHelmReleaseVersion(ctx, "kcm-system", "kcm", "1.2.2")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(<-updated).To(gomega.Succeed())
+	})
+}
+
+func TestWaitForNamespaceDeleted(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fakeClient := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kcm-system"}})
+	client := k8sclient.NewFromClientset(fakeClient)
+
+	started := watchStarted(fakeClient)
+	deleted := make(chan error, 1)
+	go func() {
+		<-started
+		deleted <- fakeClient.CoreV1().Namespaces().Delete(ctx, "kcm-system", metav1.DeleteOptions{})
+	}()
+
+	err := client.WaitForNamespaceDeleted(ctx, "kcm-system")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(<-deleted).To(gomega.Succeed())
+}
@@ -3,6 +3,7 @@ package network
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/belgaied2/k0rdentd/pkg/utils"
@@ -57,9 +58,32 @@ func shouldIgnoreInterface(name string) bool {
 	return false
 }
 
-// GetLocalIPs returns all non-local, non-ignored IP addresses from system
+// Family selects the IP addresses of an address family
+type Family string
+
+const (
+	// FamilyAny selects IPv4 addresses first, then IPv6 addresses
+	FamilyAny Family = ""
+	// FamilyIPv4 selects IPv4 addresses only
+	FamilyIPv4 Family = "ipv4"
+	// FamilyIPv6 selects IPv6 addresses only
+	FamilyIPv6 Family = "ipv6"
+)
+
+// FamilyOfCIDR returns FamilyIPv6 for an IPv6 network, so that IPv6-only
+// clusters select IPv6 addresses, and FamilyAny otherwise
+func FamilyOfCIDR(cidr string) Family {
+	ip, _, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() != nil {
+		return FamilyAny
+	}
+	return FamilyIPv6
+}
+
+// GetLocalIPs returns all non-local, non-ignored IP addresses from system,
+// IPv4 addresses first
 func GetLocalIPs() ([]net.IP, error) {
-	var ips []net.IP
+	var ipv4, ipv6 []net.IP
 
 	interfaces, err := net.Interfaces()
 	if err != nil {
@@ -96,27 +120,57 @@ func GetLocalIPs() ([]net.IP, error) {
 				ip = v.IP
 			}
 
-			// Skip IPv6 addresses for now
-			if ip == nil || ip.To4() == nil {
+			// Skip link-local addresses, other nodes can't reliably reach them
+			if ip == nil || !ip.IsGlobalUnicast() {
 				continue
 			}
 
-			ips = append(ips, ip)
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, ip)
+			} else {
+				ipv6 = append(ipv6, ip)
+			}
 		}
 	}
 
-	return ips, nil
+	return append(ipv4, ipv6...), nil
 }
 
-// GetInternalIP returns the first internal IP address (for join config)
-// This is the IP address that other nodes can use to connect to this node
-func GetInternalIP() (string, error) {
+// GetLocalIPsForFamily returns the local IP addresses of the given family
+func GetLocalIPsForFamily(family Family) ([]net.IP, error) {
 	ips, err := GetLocalIPs()
+	if err != nil {
+		return nil, err
+	}
+	return filterFamily(ips, family), nil
+}
+
+// filterFamily returns the addresses of ips that belong to family
+func filterFamily(ips []net.IP, family Family) []net.IP {
+	if family == FamilyAny {
+		return ips
+	}
+	var result []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (family == FamilyIPv4) {
+			result = append(result, ip)
+		}
+	}
+	return result
+}
+
+// GetInternalIP returns the first internal IP address of the given family (for join config)
+// This is the IP address that other nodes can use to connect to this node
+func GetInternalIP(family Family) (string, error) {
+	ips, err := GetLocalIPsForFamily(family)
 	if err != nil {
 		return "", fmt.Errorf("failed to get local IPs: %w", err)
 	}
 
 	if len(ips) == 0 {
+		if family != FamilyAny {
+			return "", fmt.Errorf("no internal %s addresses found", family)
+		}
 		return "", fmt.Errorf("no internal IP addresses found")
 	}
 
@@ -125,22 +179,38 @@ func GetInternalIP() (string, error) {
 }
 
 // GetInternalIPWithOverride returns the override IP if provided, otherwise auto-detects
-func GetInternalIPWithOverride(override string) (string, error) {
+func GetInternalIPWithOverride(override string, family Family) (string, error) {
 	if override != "" {
-		// Validate the override IP
-		ip := net.ParseIP(override)
+		// Validate the override IP, brackets are accepted around IPv6 addresses
+		ip := net.ParseIP(strings.Trim(override, "[]"))
 		if ip == nil {
 			return "", fmt.Errorf("invalid IP address: %s", override)
 		}
-		return override, nil
+		return ip.String(), nil
 	}
 
-	return GetInternalIP()
+	return GetInternalIP(family)
 }
 
 // GetControllerIP is an alias for GetInternalIPWithOverride for clarity
-func GetControllerIP(override string) (string, error) {
-	return GetInternalIPWithOverride(override)
+func GetControllerIP(override string, family Family) (string, error) {
+	return GetInternalIPWithOverride(override, family)
+}
+
+// HostPort joins a host and a port, bracketing IPv6 addresses
+func HostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// URL builds a URL for a host, bracketing IPv6 addresses. The port is
+// left out if it is 0.
+func URL(scheme, host string, port int, path string) string {
+	if port != 0 {
+		host = HostPort(host, port)
+	} else if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, host, path)
 }
 
 // LogDetectedIPs logs all detected IP addresses for debugging
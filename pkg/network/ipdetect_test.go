@@ -1,6 +1,7 @@
 package network
 
 import (
+	"net"
 	"testing"
 
 	"github.com/onsi/gomega"
@@ -33,3 +34,46 @@ func TestShouldIgnoreInterface(t *testing.T) {
 		})
 	}
 }
+
+func TestFamilyOfCIDR(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(FamilyOfCIDR("10.244.0.0/16")).To(gomega.Equal(FamilyAny))
+	g.Expect(FamilyOfCIDR("fd00::/108")).To(gomega.Equal(FamilyIPv6))
+	g.Expect(FamilyOfCIDR("")).To(gomega.Equal(FamilyAny))
+}
+
+func TestFilterFamily(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	ips := []net.IP{net.ParseIP("192.168.1.10"), net.ParseIP("2001:db8::10"), net.ParseIP("10.0.0.1")}
+
+	g.Expect(filterFamily(ips, FamilyAny)).To(gomega.Equal(ips))
+	g.Expect(filterFamily(ips, FamilyIPv4)).To(gomega.Equal([]net.IP{ips[0], ips[2]}))
+	g.Expect(filterFamily(ips, FamilyIPv6)).To(gomega.Equal([]net.IP{ips[1]}))
+}
+
+func TestGetInternalIPWithOverride(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	ip, err := GetInternalIPWithOverride("192.168.1.10", FamilyAny)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(ip).To(gomega.Equal("192.168.1.10"))
+
+	ip, err = GetInternalIPWithOverride("[2001:db8::10]", FamilyIPv6)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(ip).To(gomega.Equal("2001:db8::10"))
+
+	_, err = GetInternalIPWithOverride("not-an-ip", FamilyAny)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestURL(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(URL("http", "192.168.1.10", 0, "/k0rdent-ui")).To(gomega.Equal("http://192.168.1.10/k0rdent-ui"))
+	g.Expect(URL("http", "192.168.1.10", 30080, "")).To(gomega.Equal("http://192.168.1.10:30080"))
+	g.Expect(URL("http", "2001:db8::10", 0, "/k0rdent-ui")).To(gomega.Equal("http://[2001:db8::10]/k0rdent-ui"))
+	g.Expect(URL("http", "2001:db8::10", 30080, "")).To(gomega.Equal("http://[2001:db8::10]:30080"))
+	g.Expect(HostPort("2001:db8::10", 5000)).To(gomega.Equal("[2001:db8::10]:5000"))
+}
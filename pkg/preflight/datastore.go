@@ -0,0 +1,194 @@
+package preflight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// dialTimeout bounds each connection attempt to the datastore
+const dialTimeout = 5 * time.Second
+
+// mysqlAddressPattern matches the address of a mysql DSN, e.g. tcp(host:3306)
+var mysqlAddressPattern = regexp.MustCompile(`tcp\(([^)]+)\)`)
+
+// CheckDatastore checks that the external datastore of the storage
+// configuration is reachable. The embedded etcd and sqlite need no check.
+func CheckDatastore(cfg config.StorageConfig) error {
+	switch {
+	case cfg.Type == "kine":
+		return checkKine(cfg.Kine)
+	case cfg.Etcd.ExternalCluster.IsSet():
+		return checkExternalEtcd(cfg.Etcd.ExternalCluster)
+	}
+	return nil
+}
+
+// checkKine dials the postgres or mysql server of the kine data source
+func checkKine(cfg config.KineConfig) error {
+	var address string
+	switch cfg.Backend {
+	case "postgres":
+		host, port, err := postgresHostPort(cfg.DataSource)
+		if err != nil {
+			// The parse error echoes the data source, which holds credentials
+			return fmt.Errorf("failed to parse postgres data source %s", config.RedactDSN(cfg.DataSource))
+		}
+		if host == "" || strings.HasPrefix(host, "/") {
+			utils.GetLogger().Warnf("⚠️  The postgres data source has no TCP host, skipping the datastore check")
+			return nil
+		}
+		address = net.JoinHostPort(host, port)
+	case "mysql":
+		match := mysqlAddressPattern.FindStringSubmatch(cfg.DataSource)
+		if match == nil {
+			// Let kine handle unix sockets and other transports
+			return nil
+		}
+		address = withDefaultPort(match[1], "3306")
+	default:
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("%s datastore %s is not reachable: %w", cfg.Backend, address, err)
+	}
+	conn.Close()
+	utils.GetLogger().Infof("✅ %s datastore %s is reachable", cfg.Backend, address)
+	return nil
+}
+
+// postgresHostPort returns the host and port of a postgres data source, in
+// the URL form (postgres://user@host:port/db) or the key=value form
+// (host=db port=5432 user=k0s). The host is empty or a directory for unix
+// sockets.
+func postgresHostPort(dataSource string) (string, string, error) {
+	host, port := "", "5432"
+	if strings.Contains(dataSource, "://") {
+		u, err := url.Parse(dataSource)
+		if err != nil {
+			return "", "", err
+		}
+		host = u.Hostname()
+		if u.Port() != "" {
+			port = u.Port()
+		}
+		// Sockets are set with a host query parameter
+		if query := u.Query(); host == "" && query.Has("host") {
+			host = query.Get("host")
+		}
+		return host, port, nil
+	}
+
+	for _, field := range strings.Fields(dataSource) {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		value = strings.Trim(value, "'")
+		switch key {
+		case "host":
+			host = value
+		case "port":
+			port = value
+		}
+	}
+	return host, port, nil
+}
+
+// checkExternalEtcd dials each etcd endpoint, with a TLS handshake for
+// https endpoints. It fails only if no endpoint is reachable.
+func checkExternalEtcd(cfg config.ExternalEtcdConfig) error {
+	logger := utils.GetLogger()
+
+	tlsConfig, err := etcdTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, endpoint := range cfg.Endpoints {
+		if err := dialEtcd(endpoint, tlsConfig); err != nil {
+			logger.Warnf("⚠️  etcd endpoint %s is not reachable: %v", endpoint, err)
+			failures = append(failures, endpoint)
+			continue
+		}
+		logger.Debugf("etcd endpoint %s is reachable", endpoint)
+	}
+
+	if len(failures) == len(cfg.Endpoints) {
+		return fmt.Errorf("no etcd endpoint is reachable: %s", strings.Join(failures, ", "))
+	}
+	logger.Infof("✅ %d of %d etcd endpoints are reachable", len(cfg.Endpoints)-len(failures), len(cfg.Endpoints))
+	return nil
+}
+
+// etcdTLSConfig builds the client TLS configuration of the external etcd
+func etcdTLSConfig(cfg config.ExternalEtcdConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		caData, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read etcd CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in etcd CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load etcd client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// dialEtcd opens a connection to an etcd endpoint
+func dialEtcd(endpoint string, tlsConfig *tls.Config) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	address := withDefaultPort(u.Host, "2379")
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if u.Scheme != "https" {
+		conn, err := dialer.Dial("tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	endpointTLS := tlsConfig.Clone()
+	endpointTLS.ServerName = u.Hostname()
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, endpointTLS)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// withDefaultPort adds port to host when it has none
+func withDefaultPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
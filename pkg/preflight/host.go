@@ -0,0 +1,454 @@
+package preflight
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/k0s"
+)
+
+// k0sDataDir is where k0s keeps the cluster state
+const k0sDataDir = "/var/lib/k0s"
+
+// maxClockSkew is the clock difference tolerated with the chart registry,
+// certificates and tokens are rejected when clocks drift further apart
+const maxClockSkew = 30 * time.Second
+
+// memoryTolerance accounts for the memory reserved by the kernel and the
+// firmware, which MemTotal doesn't include
+const memoryTolerance = 0.9
+
+// requirements are the resources a node needs
+type requirements struct {
+	cpus      int
+	memoryGiB int
+	diskGiB   int
+}
+
+var (
+	// Controllers run the k0rdent management components
+	controllerMinimum     = requirements{cpus: 4, memoryGiB: 8, diskGiB: 20}
+	controllerRecommended = requirements{cpus: 8, memoryGiB: 16, diskGiB: 50}
+	workerMinimum         = requirements{cpus: 1, memoryGiB: 1, diskGiB: 10}
+	workerRecommended     = requirements{cpus: 2, memoryGiB: 4, diskGiB: 20}
+)
+
+// requiredModules are the kernel modules used by containerd and kube-router
+var requiredModules = []string{"overlay", "br_netfilter", "nf_conntrack"}
+
+// requiredSysctls are the kernel parameters pod networking relies on
+var requiredSysctls = []struct{ name, value string }{
+	{"net.ipv4.ip_forward", "1"},
+	{"net.bridge.bridge-nf-call-iptables", "1"},
+}
+
+// defaultAPIPort is the port of the Kubernetes API server unless
+// k0s.api.port is set
+const defaultAPIPort = 6443
+
+// Host accessors, replaced in tests
+var (
+	// hostRoot prefixes the /proc, /sys and /var paths read by the checks
+	hostRoot   = "/"
+	numCPU     = runtime.NumCPU
+	geteuid    = os.Geteuid
+	k0sRunning = k0s.IsK0sRunning
+	httpClient = &http.Client{Timeout: dialTimeout}
+)
+
+// env holds what the checks share, the chart registry is probed once for
+// both its reachability and the clock skew
+type env struct {
+	opts  Options
+	chart string
+	// apiPort is the port of the Kubernetes API server
+	apiPort int
+
+	probeOnce    sync.Once
+	registryURL  string
+	registryDate time.Time
+	registryErr  error
+}
+
+func newEnv(cfg *config.K0rdentdConfig, opts Options) *env {
+	apiPort := defaultAPIPort
+	if cfg.K0s.API.Port != 0 {
+		apiPort = cfg.K0s.API.Port
+	}
+	return &env{opts: opts, chart: cfg.K0rdent.Helm.Chart, apiPort: apiPort}
+}
+
+// requirements returns the minimum and recommended resources of the node
+func (e *env) requirements() (requirements, requirements) {
+	if e.opts.Mode == "worker" {
+		return workerMinimum, workerRecommended
+	}
+	return controllerMinimum, controllerRecommended
+}
+
+// probeRegistry sends a request to the registry of the k0rdent chart
+func (e *env) probeRegistry() {
+	e.probeOnce.Do(func() {
+		e.registryURL = chartRegistryURL(e.chart)
+		if e.registryURL == "" {
+			e.registryErr = fmt.Errorf("cannot determine the registry of chart %q", e.chart)
+			return
+		}
+		resp, err := httpClient.Get(e.registryURL)
+		if err != nil {
+			// The URL error repeats the URL reported by the check
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			e.registryErr = err
+			return
+		}
+		resp.Body.Close()
+		// Any answer, even 401, proves the registry is reachable
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			e.registryDate = date
+		}
+	})
+}
+
+// hostPath returns path below hostRoot
+func hostPath(path string) string {
+	return filepath.Join(hostRoot, path)
+}
+
+func checkRoot(e *env) (Status, string) {
+	if geteuid() != 0 {
+		if e.opts.DryRun {
+			return StatusWarn, "not running as root, the installation itself must run with sudo"
+		}
+		return StatusFail, "k0rdentd must run as root, use sudo"
+	}
+	return StatusPass, "running as root"
+}
+
+func checkCPU(e *env) (Status, string) {
+	minimum, recommended := e.requirements()
+	cpus := numCPU()
+	switch {
+	case cpus < minimum.cpus:
+		return StatusFail, fmt.Sprintf("%d CPUs, at least %d are required", cpus, minimum.cpus)
+	case cpus < recommended.cpus:
+		return StatusWarn, fmt.Sprintf("%d CPUs, %d are recommended", cpus, recommended.cpus)
+	}
+	return StatusPass, fmt.Sprintf("%d CPUs", cpus)
+}
+
+func checkMemory(e *env) (Status, string) {
+	data, err := os.ReadFile(hostPath("/proc/meminfo"))
+	if err != nil {
+		return StatusWarn, fmt.Sprintf("failed to read memory size: %v", err)
+	}
+	total, err := memTotal(data)
+	if err != nil {
+		return StatusWarn, err.Error()
+	}
+
+	minimum, recommended := e.requirements()
+	size := formatGiB(total)
+	switch {
+	case float64(total) < float64(minimum.memoryGiB<<30)*memoryTolerance:
+		return StatusFail, fmt.Sprintf("%s of memory, at least %d GiB is required", size, minimum.memoryGiB)
+	case float64(total) < float64(recommended.memoryGiB<<30)*memoryTolerance:
+		return StatusWarn, fmt.Sprintf("%s of memory, %d GiB is recommended", size, recommended.memoryGiB)
+	}
+	return StatusPass, fmt.Sprintf("%s of memory", size)
+}
+
+// memTotal returns the MemTotal of /proc/meminfo in bytes
+func memTotal(meminfo []byte) (uint64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(meminfo))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal %q", fields[1])
+		}
+		return kb << 10, nil
+	}
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
+
+func checkDisk(e *env) (Status, string) {
+	// The data dir may not exist yet, check the filesystem it will be on
+	path := hostPath(k0sDataDir)
+	for {
+		if _, err := os.Stat(path); err == nil || path == filepath.Dir(path) {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return StatusWarn, fmt.Sprintf("failed to read free space of %s: %v", path, err)
+	}
+	free := stat.Bavail * uint64(stat.Bsize)
+
+	minimum, recommended := e.requirements()
+	size := formatGiB(free)
+	switch {
+	case free < uint64(minimum.diskGiB)<<30:
+		return StatusFail, fmt.Sprintf("%s free for %s, at least %d GiB is required", size, k0sDataDir, minimum.diskGiB)
+	case free < uint64(recommended.diskGiB)<<30:
+		return StatusWarn, fmt.Sprintf("%s free for %s, %d GiB is recommended", size, k0sDataDir, recommended.diskGiB)
+	}
+	return StatusPass, fmt.Sprintf("%s free for %s", size, k0sDataDir)
+}
+
+// formatGiB formats a size in bytes as GiB
+func formatGiB(size uint64) string {
+	return fmt.Sprintf("%.1f GiB", float64(size)/(1<<30))
+}
+
+func checkPorts(e *env) (Status, string) {
+	// api server, konnectivity, k0s join api and kubelet
+	ports := []int{e.apiPort, 8132, 9443, 10250}
+	if e.opts.Mode == "worker" {
+		ports = []int{10250}
+	}
+	busy := busyPorts(ports)
+
+	var notes []string
+	if e.opts.Airgap {
+		host, port, err := net.SplitHostPort(e.opts.RegistryAddress)
+		if p, _ := strconv.Atoi(port); err == nil && isLocalHost(host) && len(busyPorts([]int{p})) > 0 {
+			// The registry is started before the installation
+			if isRegistry(e.opts.RegistryAddress) {
+				notes = append(notes, fmt.Sprintf("%d is used by the registry", p))
+			} else {
+				busy = append(busy, p)
+			}
+		}
+	}
+
+	switch {
+	case len(busy) > 0 && k0sRunning():
+		return StatusWarn, fmt.Sprintf("ports %s are in use, k0s is already running", joinInts(busy))
+	case len(busy) > 0:
+		return StatusFail, fmt.Sprintf("ports %s are in use by another process", joinInts(busy))
+	}
+	message := fmt.Sprintf("ports %s are free", joinInts(ports))
+	if len(notes) > 0 {
+		message += ", " + strings.Join(notes, ", ")
+	}
+	return StatusPass, message
+}
+
+// busyPorts returns the TCP ports that cannot be listened on
+func busyPorts(ports []int) []int {
+	var busy []int
+	for _, port := range ports {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			busy = append(busy, port)
+			continue
+		}
+		listener.Close()
+	}
+	return busy
+}
+
+// isLocalHost tells whether host is this machine
+func isLocalHost(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		hostname, err := os.Hostname()
+		return err == nil && strings.EqualFold(host, hostname)
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRegistry tells whether an OCI registry answers at address
+func isRegistry(address string) bool {
+	resp, err := httpClient.Get(fmt.Sprintf("http://%s/v2/", address))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusUnauthorized
+}
+
+// joinInts joins numbers with commas
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func checkSwap(*env) (Status, string) {
+	data, err := os.ReadFile(hostPath("/proc/swaps"))
+	if err != nil {
+		return StatusWarn, fmt.Sprintf("failed to read swap devices: %v", err)
+	}
+	if devices := swapDevices(data); len(devices) > 0 {
+		return StatusWarn, fmt.Sprintf("swap is enabled on %s, the kubelet may refuse to start; disable it with swapoff -a", strings.Join(devices, ", "))
+	}
+	return StatusPass, "swap is disabled"
+}
+
+// swapDevices returns the devices listed in /proc/swaps, below its header
+func swapDevices(swaps []byte) []string {
+	var devices []string
+	lines := strings.Split(strings.TrimSpace(string(swaps)), "\n")
+	for _, line := range lines[1:] {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			devices = append(devices, fields[0])
+		}
+	}
+	return devices
+}
+
+func checkCgroupV2(*env) (Status, string) {
+	if _, err := os.Stat(hostPath("/sys/fs/cgroup/cgroup.controllers")); err != nil {
+		return StatusWarn, "cgroup v1 is in use, Kubernetes has deprecated it in favor of cgroup v2"
+	}
+	return StatusPass, "cgroup v2 is in use"
+}
+
+func checkKernelModules(*env) (Status, string) {
+	builtin := builtinModules()
+	var missing []string
+	for _, module := range requiredModules {
+		if _, err := os.Stat(hostPath("/sys/module/" + module)); err == nil || builtin[module] {
+			continue
+		}
+		missing = append(missing, module)
+	}
+	if len(missing) > 0 {
+		return StatusWarn, fmt.Sprintf("modules %s are not loaded, load them with modprobe", strings.Join(missing, ", "))
+	}
+	return StatusPass, fmt.Sprintf("modules %s are loaded", strings.Join(requiredModules, ", "))
+}
+
+// builtinModules returns the modules compiled into the running kernel
+func builtinModules() map[string]bool {
+	modules := make(map[string]bool)
+	release, err := os.ReadFile(hostPath("/proc/sys/kernel/osrelease"))
+	if err != nil {
+		return modules
+	}
+	data, err := os.ReadFile(hostPath(filepath.Join("/lib/modules", strings.TrimSpace(string(release)), "modules.builtin")))
+	if err != nil {
+		return modules
+	}
+	for _, line := range strings.Fields(string(data)) {
+		// e.g. kernel/net/bridge/br_netfilter.ko
+		name := strings.TrimSuffix(filepath.Base(line), ".ko")
+		modules[strings.ReplaceAll(name, "-", "_")] = true
+	}
+	return modules
+}
+
+func checkSysctls(*env) (Status, string) {
+	var wrong []string
+	for _, sysctl := range requiredSysctls {
+		path := hostPath(filepath.Join("/proc/sys", strings.ReplaceAll(sysctl.name, ".", "/")))
+		data, err := os.ReadFile(path)
+		if err != nil || strings.TrimSpace(string(data)) != sysctl.value {
+			wrong = append(wrong, fmt.Sprintf("%s=%s", sysctl.name, sysctl.value))
+		}
+	}
+	if len(wrong) > 0 {
+		return StatusWarn, fmt.Sprintf("%s not set, set them with sysctl -w", strings.Join(wrong, ", "))
+	}
+	return StatusPass, "required sysctls are set"
+}
+
+func checkK0sDataDir(*env) (Status, string) {
+	entries, err := os.ReadDir(hostPath(k0sDataDir))
+	if err != nil || len(entries) == 0 {
+		return StatusPass, fmt.Sprintf("%s is empty", k0sDataDir)
+	}
+	return StatusWarn, fmt.Sprintf("%s holds data of a previous installation, run k0rdentd uninstall for a fresh start", k0sDataDir)
+}
+
+func checkClock(e *env) (Status, string) {
+	if !e.opts.Airgap {
+		e.probeRegistry()
+		if !e.registryDate.IsZero() {
+			skew := time.Since(e.registryDate).Round(time.Second)
+			if skew.Abs() > maxClockSkew {
+				return StatusWarn, fmt.Sprintf("clock is %s off the chart registry time, synchronize it with NTP", skew)
+			}
+			return StatusPass, "clock is in sync with the chart registry"
+		}
+	}
+
+	// Without a reference time, rely on the NTP status
+	output, err := exec.Command("timedatectl", "show", "--property=NTPSynchronized", "--value").Output()
+	if err != nil {
+		return StatusWarn, "clock synchronization could not be checked"
+	}
+	if strings.TrimSpace(string(output)) != "yes" {
+		return StatusWarn, "clock is not synchronized with NTP"
+	}
+	return StatusPass, "clock is synchronized with NTP"
+}
+
+func checkChartRegistry(e *env) (Status, string) {
+	e.probeRegistry()
+	if e.registryErr != nil {
+		if e.registryURL == "" {
+			return StatusWarn, e.registryErr.Error()
+		}
+		return StatusFail, fmt.Sprintf("%s is not reachable: %v", e.registryURL, e.registryErr)
+	}
+	return StatusPass, fmt.Sprintf("%s is reachable", e.registryURL)
+}
+
+// chartRegistryURL returns the URL to probe for a chart reference, the
+// registry API of oci:// charts or the repository of https:// ones
+func chartRegistryURL(chart string) string {
+	u, err := url.Parse(chart)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	switch u.Scheme {
+	case "oci":
+		return fmt.Sprintf("https://%s/v2/", u.Host)
+	case "http", "https":
+		return fmt.Sprintf("%s://%s/", u.Scheme, u.Host)
+	}
+	return ""
+}
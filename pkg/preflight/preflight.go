@@ -0,0 +1,181 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// StatusPass means the host meets the requirement
+	StatusPass Status = "pass"
+	// StatusWarn means the installation can proceed, but may misbehave
+	StatusWarn Status = "warn"
+	// StatusFail means the installation would fail
+	StatusFail Status = "fail"
+	// StatusIgnored means the check was skipped with --ignore
+	StatusIgnored Status = "ignored"
+)
+
+// Names of the checks, as accepted by --ignore
+const (
+	CheckRoot          = "root"
+	CheckCPU           = "cpu"
+	CheckMemory        = "memory"
+	CheckDisk          = "disk"
+	CheckPorts         = "ports"
+	CheckSwap          = "swap"
+	CheckCgroupV2      = "cgroup-v2"
+	CheckKernelModules = "kernel-modules"
+	CheckSysctls       = "sysctls"
+	CheckK0sDataDir    = "k0s-data-dir"
+	CheckClock         = "clock"
+	CheckChartRegistry = "chart-registry"
+	CheckDatastoreName = "datastore"
+)
+
+// CheckNames lists every check in the order they run
+var CheckNames = []string{
+	CheckRoot, CheckCPU, CheckMemory, CheckDisk, CheckPorts, CheckSwap,
+	CheckCgroupV2, CheckKernelModules, CheckSysctls, CheckK0sDataDir,
+	CheckClock, CheckChartRegistry, CheckDatastoreName,
+}
+
+// Options describes the node being checked
+type Options struct {
+	// Mode is controller or worker when joining a cluster, empty when
+	// initializing a new one
+	Mode string
+	// Airgap is set for airgap builds, the chart registry isn't contacted
+	Airgap bool
+	// RegistryAddress is the address of the local registry in airgap mode
+	RegistryAddress string
+	// Ignore lists the checks to skip
+	Ignore []string
+	// DryRun is set when the installation only plans its changes, which
+	// doesn't need root
+	DryRun bool
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of all the checks
+type Report struct {
+	Results []Result `json:"results"`
+	// Passed is false when a check failed
+	Passed bool `json:"passed"`
+}
+
+// check is a host requirement, run returns its status and a message
+type check struct {
+	name string
+	run  func(e *env) (Status, string)
+}
+
+// ValidateIgnore checks that every ignored check exists
+func ValidateIgnore(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(CheckNames, name) {
+			return fmt.Errorf("unknown preflight check %q, must be one of: %s", name, strings.Join(CheckNames, ", "))
+		}
+	}
+	return nil
+}
+
+// Run runs the checks that apply to the node. Nothing on the host is changed.
+func Run(cfg *config.K0rdentdConfig, opts Options) (*Report, error) {
+	if err := ValidateIgnore(opts.Ignore); err != nil {
+		return nil, err
+	}
+
+	e := newEnv(cfg, opts)
+	report := &Report{Results: []Result{}, Passed: true}
+	for _, c := range checks(cfg, opts) {
+		result := Result{Name: c.name, Status: StatusIgnored, Message: "ignored with --ignore"}
+		if !slices.Contains(opts.Ignore, c.name) {
+			result.Status, result.Message = c.run(e)
+		}
+		if result.Status == StatusFail {
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// checks returns the checks that apply to the node
+func checks(cfg *config.K0rdentdConfig, opts Options) []check {
+	list := []check{
+		{CheckRoot, checkRoot},
+		{CheckCPU, checkCPU},
+		{CheckMemory, checkMemory},
+		{CheckDisk, checkDisk},
+		{CheckPorts, checkPorts},
+		{CheckSwap, checkSwap},
+		{CheckCgroupV2, checkCgroupV2},
+		{CheckKernelModules, checkKernelModules},
+		{CheckSysctls, checkSysctls},
+		{CheckK0sDataDir, checkK0sDataDir},
+		{CheckClock, checkClock},
+	}
+	if !opts.Airgap {
+		list = append(list, check{CheckChartRegistry, checkChartRegistry})
+	}
+	// Each controller connects to the datastore, workers don't
+	if opts.Mode != "worker" && usesExternalDatastore(cfg.K0s.Storage) {
+		list = append(list, check{CheckDatastoreName, func(*env) (Status, string) {
+			if err := CheckDatastore(cfg.K0s.Storage); err != nil {
+				return StatusFail, err.Error()
+			}
+			return StatusPass, "external datastore is reachable"
+		}})
+	}
+	return list
+}
+
+// usesExternalDatastore tells whether CheckDatastore has anything to dial
+func usesExternalDatastore(cfg config.StorageConfig) bool {
+	return (cfg.Type == "kine" && (cfg.Kine.Backend == "postgres" || cfg.Kine.Backend == "mysql")) ||
+		cfg.Etcd.ExternalCluster.IsSet()
+}
+
+// Failed returns the names of the failed checks
+func (r *Report) Failed() []string {
+	var names []string
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			names = append(names, result.Name)
+		}
+	}
+	return names
+}
+
+// WriteTable writes the results as a table
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+	for _, result := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Name, strings.ToUpper(string(result.Status)), result.Message)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON, for automation
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,225 @@
+package preflight
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+// fakeHost points the checks to a temporary root holding files
+func fakeHost(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := hostRoot
+	hostRoot = root
+	t.Cleanup(func() { hostRoot = previous })
+}
+
+func TestValidateIgnore(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(ValidateIgnore([]string{CheckSwap, CheckPorts})).To(gomega.Succeed())
+	err := ValidateIgnore([]string{"swapp"})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown preflight check "swapp"`)))
+}
+
+func TestChecksDatastore(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	cfg := &config.K0rdentdConfig{}
+	cfg.K0s.Storage = config.StorageConfig{Type: "kine", Kine: config.KineConfig{
+		Backend: "postgres", DataSource: "postgres://k0s:pass@db:5432/k0s",
+	}}
+	names := func(mode string) []string {
+		var names []string
+		for _, check := range checks(cfg, Options{Mode: mode}) {
+			names = append(names, check.name)
+		}
+		return names
+	}
+
+	// Every controller connects to the datastore
+	g.Expect(names("")).To(gomega.ContainElement(CheckDatastoreName))
+	g.Expect(names("controller")).To(gomega.ContainElement(CheckDatastoreName))
+	g.Expect(names("worker")).ToNot(gomega.ContainElement(CheckDatastoreName))
+}
+
+func TestRunIgnore(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	fakeHost(t, map[string]string{"/proc/swaps": "Filename Type Size Used Priority\n"})
+	previous := geteuid
+	geteuid = func() int { return 1000 }
+	t.Cleanup(func() { geteuid = previous })
+
+	// Only the root and swap checks run
+	var ignore []string
+	for _, name := range CheckNames {
+		if name != CheckRoot && name != CheckSwap {
+			ignore = append(ignore, name)
+		}
+	}
+	report, err := Run(&config.K0rdentdConfig{}, Options{Airgap: true, Ignore: ignore})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	statuses := map[string]Status{}
+	for _, result := range report.Results {
+		statuses[result.Name] = result.Status
+	}
+	g.Expect(statuses).To(gomega.HaveKeyWithValue(CheckRoot, StatusFail))
+	g.Expect(statuses).To(gomega.HaveKeyWithValue(CheckSwap, StatusPass))
+	g.Expect(statuses).To(gomega.HaveKeyWithValue(CheckCPU, StatusIgnored))
+	// Not applicable in airgap mode or with the embedded datastore
+	g.Expect(statuses).ToNot(gomega.HaveKey(CheckChartRegistry))
+	g.Expect(statuses).ToNot(gomega.HaveKey(CheckDatastoreName))
+	g.Expect(report.Passed).To(gomega.BeFalse())
+	g.Expect(report.Failed()).To(gomega.Equal([]string{CheckRoot}))
+
+	// Planning an installation doesn't need root
+	report, err = Run(&config.K0rdentdConfig{}, Options{Airgap: true, Ignore: ignore, DryRun: true})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(report.Results[0]).To(gomega.HaveField("Status", StatusWarn))
+	g.Expect(report.Passed).To(gomega.BeTrue())
+
+	_, err = Run(&config.K0rdentdConfig{}, Options{Ignore: []string{"unknown"}})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestCheckMemory(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  string
+		memKB string
+		want  Status
+	}{
+		{name: "recommended", memKB: "16318412", want: StatusPass},
+		{name: "below recommended", memKB: "8000000", want: StatusWarn},
+		{name: "below minimum", memKB: "4000000", want: StatusFail},
+		{name: "worker minimum", mode: "worker", memKB: "4000000", want: StatusPass},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			fakeHost(t, map[string]string{
+				"/proc/meminfo": "MemTotal:       " + tt.memKB + " kB\nMemFree:         1000 kB\n",
+			})
+			status, _ := checkMemory(&env{opts: Options{Mode: tt.mode}})
+			g.Expect(status).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func TestSwapDevices(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	swaps := "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n/swap.img\tfile\t\t4194300\t\t0\t\t-2\n"
+	g.Expect(swapDevices([]byte(swaps))).To(gomega.Equal([]string{"/swap.img"}))
+	g.Expect(swapDevices([]byte("Filename Type Size Used Priority\n"))).To(gomega.BeEmpty())
+}
+
+func TestCheckKernelModulesAndSysctls(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	fakeHost(t, map[string]string{
+		"/sys/module/overlay/refcnt":                   "1",
+		"/sys/module/nf_conntrack/refcnt":              "1",
+		"/proc/sys/kernel/osrelease":                   "6.8.0-generic\n",
+		"/lib/modules/6.8.0-generic/modules.builtin":   "kernel/net/bridge/br_netfilter.ko\n",
+		"/proc/sys/net/ipv4/ip_forward":                "1\n",
+		"/proc/sys/net/bridge/bridge-nf-call-iptables": "0\n",
+	})
+
+	status, _ := checkKernelModules(&env{})
+	g.Expect(status).To(gomega.Equal(StatusPass))
+
+	status, message := checkSysctls(&env{})
+	g.Expect(status).To(gomega.Equal(StatusWarn))
+	g.Expect(message).To(gomega.ContainSubstring("net.bridge.bridge-nf-call-iptables=1"))
+	g.Expect(message).ToNot(gomega.ContainSubstring("ip_forward"))
+}
+
+func TestCheckK0sDataDir(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	fakeHost(t, nil)
+	status, _ := checkK0sDataDir(&env{})
+	g.Expect(status).To(gomega.Equal(StatusPass))
+
+	fakeHost(t, map[string]string{"/var/lib/k0s/pki/ca.crt": "cert"})
+	status, _ = checkK0sDataDir(&env{})
+	g.Expect(status).To(gomega.Equal(StatusWarn))
+}
+
+func TestBusyPorts(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	g.Expect(busyPorts([]int{port})).To(gomega.Equal([]int{port}))
+}
+
+func TestCheckPortsAPIPort(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	listener, err := net.Listen("tcp", ":0")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+	previous := k0sRunning
+	k0sRunning = func() bool { return false }
+	t.Cleanup(func() { k0sRunning = previous })
+
+	cfg := &config.K0rdentdConfig{}
+	cfg.K0s.API.Port = port
+	status, message := checkPorts(newEnv(cfg, Options{}))
+	g.Expect(status).To(gomega.Equal(StatusFail))
+	g.Expect(message).To(gomega.ContainSubstring(strconv.Itoa(port)))
+	g.Expect(newEnv(&config.K0rdentdConfig{}, Options{}).apiPort).To(gomega.Equal(defaultAPIPort))
+}
+
+func TestChartRegistryURL(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(chartRegistryURL("oci://registry.mirantis.com/k0rdent-enterprise/charts/k0rdent-enterprise")).
+		To(gomega.Equal("https://registry.mirantis.com/v2/"))
+	g.Expect(chartRegistryURL("https://charts.example.com/stable")).To(gomega.Equal("https://charts.example.com/"))
+	g.Expect(chartRegistryURL("k0rdent/kcm")).To(gomega.BeEmpty())
+}
+
+func TestReportOutput(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	report := &Report{Results: []Result{
+		{Name: CheckSwap, Status: StatusWarn, Message: "swap is enabled"},
+	}, Passed: true}
+
+	var table bytes.Buffer
+	g.Expect(report.WriteTable(&table)).To(gomega.Succeed())
+	g.Expect(table.String()).To(gomega.Equal("CHECK  STATUS  MESSAGE\nswap   WARN    swap is enabled\n"))
+
+	var out bytes.Buffer
+	g.Expect(report.WriteJSON(&out)).To(gomega.Succeed())
+	var decoded Report
+	g.Expect(json.Unmarshal(out.Bytes(), &decoded)).To(gomega.Succeed())
+	g.Expect(decoded).To(gomega.Equal(*report))
+}
@@ -0,0 +1,186 @@
+// Package render computes the files an installation would produce without
+// changing the host, so they can be reviewed before running install.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap"
+	"github.com/belgaied2/k0rdentd/internal/airgap/bundle"
+	"github.com/belgaied2/k0rdentd/internal/airgap/containerd"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/belgaied2/k0rdentd/pkg/credentials"
+	"github.com/belgaied2/k0rdentd/pkg/generator"
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// K0sConfigPath is where install writes the k0s configuration
+	K0sConfigPath = "/etc/k0s/k0s.yaml"
+	// HelmValuesDir holds the merged values of each helm chart
+	HelmValuesDir = "helm"
+	// CredentialsManifestPath holds the credential objects created in the cluster
+	CredentialsManifestPath = "manifests/credentials.yaml"
+)
+
+// Artifact is a file produced by an installation
+type Artifact struct {
+	// Path is the absolute path install writes the file to on the host,
+	// or a relative path for content that is applied to the cluster
+	Path    string
+	Content []byte
+	Mode    os.FileMode
+}
+
+// Options control how artifacts are rendered
+type Options struct {
+	// Airgap renders the artifacts of an airgap installation
+	Airgap bool
+	// ShowSecrets keeps sensitive values instead of redacting them
+	ShowSecrets bool
+}
+
+// Render returns the artifacts an installation of the configuration would produce
+func Render(cfg *config.K0rdentdConfig, opts Options) ([]Artifact, error) {
+	if cfg.Join.Mode != "" {
+		return nil, fmt.Errorf("rendering is not supported for join configurations (join.mode: %s)", cfg.Join.Mode)
+	}
+
+	k0sConfig, err := k0sConfig(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []Artifact
+	if opts.ShowSecrets {
+		artifacts = append(artifacts, Artifact{Path: K0sConfigPath, Content: k0sConfig, Mode: 0600})
+	} else {
+		redacted, err := generator.RedactK0sConfig(k0sConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact k0s config: %w", err)
+		}
+		artifacts = append(artifacts, Artifact{Path: K0sConfigPath, Content: redacted, Mode: 0600})
+	}
+
+	if opts.Airgap {
+		registryAddr := airgap.NewInstaller(cfg, false).GetRegistryAddress()
+		artifacts = append(artifacts, Artifact{
+			Path:    containerd.CRIRegistryConfigPath,
+			Content: []byte(containerd.CRIRegistryConfig()),
+			Mode:    0644,
+		})
+		for _, registry := range containerd.MirroredRegistries {
+			artifacts = append(artifacts, Artifact{
+				Path:    containerd.HostsConfigPath(registry),
+				Content: []byte(containerd.HostsConfig(registry, registryAddr)),
+				Mode:    0644,
+			})
+		}
+	}
+
+	values, err := generator.HelmChartValues(k0sConfig)
+	if err != nil {
+		return nil, err
+	}
+	charts := make([]string, 0, len(values))
+	for chart := range values {
+		charts = append(charts, chart)
+	}
+	sort.Strings(charts)
+	for _, chart := range charts {
+		chartValues := values[chart]
+		if !opts.ShowSecrets {
+			if chartValues, err = config.RedactYAML(chartValues); err != nil {
+				return nil, fmt.Errorf("failed to redact values of chart %s: %w", chart, err)
+			}
+		}
+		artifacts = append(artifacts, Artifact{
+			Path:    filepath.Join(HelmValuesDir, chart+"-values.yaml"),
+			Content: []byte(chartValues),
+			Mode:    0600,
+		})
+	}
+
+	if cfg.K0rdent.Credentials.HasCredentials() {
+		manifest, err := credentialsManifest(cfg.K0rdent.Credentials, opts.ShowSecrets)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, Artifact{Path: CredentialsManifestPath, Content: manifest, Mode: 0600})
+	}
+
+	return artifacts, nil
+}
+
+// WriteArtifacts writes artifacts under dir, absolute host paths being
+// mirrored below it
+func WriteArtifacts(dir string, artifacts []Artifact) error {
+	for _, artifact := range artifacts {
+		path := filepath.Join(dir, artifact.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", artifact.Path, err)
+		}
+		if err := os.WriteFile(path, artifact.Content, artifact.Mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// k0sConfig generates the k0s configuration install would write
+func k0sConfig(cfg *config.K0rdentdConfig, opts Options) ([]byte, error) {
+	if !opts.Airgap {
+		data, err := generator.GenerateK0sConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate K0s config: %w", err)
+		}
+		return data, nil
+	}
+
+	// Like the airgap installer, prefer the k0rdent version of the bundle
+	airgapConfig := *cfg
+	if cfg.Airgap.BundlePath != "" {
+		version, err := bundle.ExtractK0rdentVersion(cfg.Airgap.BundlePath)
+		if err != nil {
+			utils.GetLogger().Warnf("⚠️ Failed to extract version from bundle: %v. Using config version.", err)
+		} else {
+			airgapConfig.K0rdent.Version = version
+		}
+	}
+
+	installer := airgap.NewInstaller(&airgapConfig, false)
+	data, err := generator.GenerateAirgapK0sConfig(&airgapConfig, installer.GetRegistryAddress(), installer.IsRegistryInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate airgap k0s config: %w", err)
+	}
+	return data, nil
+}
+
+// credentialsManifest returns the credential objects as a multi-document
+// YAML manifest, with Secret data redacted unless showSecrets is set
+func credentialsManifest(creds config.CredentialsConfig, showSecrets bool) ([]byte, error) {
+	var buf bytes.Buffer
+	for idx, object := range credentials.Resources(creds) {
+		if secret, ok := object.(*corev1.Secret); ok && !showSecrets {
+			for key := range secret.StringData {
+				secret.StringData[key] = config.RedactedValue
+			}
+		}
+
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal credential manifest: %w", err)
+		}
+		if idx > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/belgaied2/k0rdentd/internal/airgap/containerd"
+	"github.com/belgaied2/k0rdentd/pkg/config"
+	"github.com/onsi/gomega"
+)
+
+func testConfig() *config.K0rdentdConfig {
+	cfg := config.DefaultConfig()
+	cfg.K0rdent.Helm.Values["registry"] = map[string]interface{}{
+		"url":      "registry.local",
+		"password": "hunter2",
+	}
+	cfg.K0rdent.Credentials.AWS = []config.AWSCredential{
+		{Name: "prod", Region: "us-east-1", AccessKeyID: "AKIA", SecretAccessKey: "supersecret"},
+	}
+	return cfg
+}
+
+// paths returns the paths of the artifacts
+func paths(artifacts []Artifact) []string {
+	var result []string
+	for _, artifact := range artifacts {
+		result = append(result, artifact.Path)
+	}
+	return result
+}
+
+// content returns the content of the artifact at path
+func content(artifacts []Artifact, path string) string {
+	for _, artifact := range artifacts {
+		if artifact.Path == path {
+			return string(artifact.Content)
+		}
+	}
+	return ""
+}
+
+func TestRender(t *testing.T) {
+	t.Run("online install artifacts are redacted", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		artifacts, err := Render(testConfig(), Options{})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(paths(artifacts)).To(gomega.Equal([]string{
+			K0sConfigPath,
+			filepath.Join(HelmValuesDir, "kcm-values.yaml"),
+			CredentialsManifestPath,
+		}))
+
+		for _, artifact := range artifacts {
+			g.Expect(string(artifact.Content)).ToNot(gomega.ContainSubstring("hunter2"))
+			g.Expect(string(artifact.Content)).ToNot(gomega.ContainSubstring("supersecret"))
+		}
+		g.Expect(content(artifacts, K0sConfigPath)).To(gomega.ContainSubstring("registry.local"))
+
+		manifest := content(artifacts, CredentialsManifestPath)
+		g.Expect(manifest).To(gomega.ContainSubstring("kind: Secret"))
+		g.Expect(manifest).To(gomega.ContainSubstring("kind: AWSClusterStaticIdentity"))
+		g.Expect(manifest).To(gomega.ContainSubstring("kind: Credential"))
+		g.Expect(manifest).To(gomega.ContainSubstring("name: prod-identity"))
+	})
+
+	t.Run("secrets are kept on request", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		artifacts, err := Render(testConfig(), Options{ShowSecrets: true})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(content(artifacts, K0sConfigPath)).To(gomega.ContainSubstring("hunter2"))
+		g.Expect(content(artifacts, CredentialsManifestPath)).To(gomega.ContainSubstring("supersecret"))
+	})
+
+	t.Run("airgap install artifacts include containerd mirrors", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		cfg := testConfig()
+		cfg.Airgap.Registry.Address = "10.0.0.1:5000"
+
+		artifacts, err := Render(cfg, Options{Airgap: true})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(paths(artifacts)).To(gomega.ContainElements(
+			containerd.CRIRegistryConfigPath,
+			containerd.HostsConfigPath("quay.io"),
+			containerd.HostsConfigPath("registry.k8s.io"),
+		))
+		g.Expect(content(artifacts, containerd.HostsConfigPath("quay.io"))).To(gomega.ContainSubstring("http://10.0.0.1:5000"))
+		g.Expect(content(artifacts, K0sConfigPath)).To(gomega.ContainSubstring("oci://10.0.0.1:5000"))
+	})
+
+	t.Run("join configurations are rejected", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		cfg := testConfig()
+		cfg.Join = config.JoinConfig{Mode: "worker", Server: "10.0.0.1", Token: "token"}
+
+		_, err := Render(cfg, Options{})
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("join configurations")))
+	})
+}
+
+func TestWriteArtifacts(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	artifacts := []Artifact{
+		{Path: K0sConfigPath, Content: []byte("k0s"), Mode: 0600},
+		{Path: CredentialsManifestPath, Content: []byte("creds"), Mode: 0600},
+	}
+	g.Expect(WriteArtifacts(dir, artifacts)).To(gomega.Succeed())
+
+	data, err := os.ReadFile(filepath.Join(dir, "etc", "k0s", "k0s.yaml"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.Equal("k0s"))
+
+	data, err = os.ReadFile(filepath.Join(dir, "manifests", "credentials.yaml"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.Equal("creds"))
+}
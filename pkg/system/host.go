@@ -0,0 +1,144 @@
+// Package system runs commands and changes files on the node. The dry-run
+// implementation records them in a plan instead, so that dry-run follows the
+// same code paths as a real run.
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// Host changes the node: it runs commands, writes and removes files and
+// performs other actions such as waits and Kubernetes API calls
+type Host interface {
+	// Run runs a command. The error includes the command's stderr.
+	Run(name string, args ...string) error
+	// WriteFile writes data to path, like os.WriteFile
+	WriteFile(path string, data []byte, perm os.FileMode, opts ...FileOption) error
+	// CopyFile writes the content of src to path, for large or binary files
+	CopyFile(path string, src io.Reader, perm os.FileMode) error
+	// MkdirAll creates a directory and its parents, like os.MkdirAll
+	MkdirAll(path string, perm os.FileMode) error
+	// Remove removes a file, a missing file is not an error
+	Remove(path string, opts ...FileOption) error
+	// RemoveAll removes a directory and its content, like os.RemoveAll
+	RemoveAll(path string) error
+	// Do performs an action that is neither a command nor a file change,
+	// such as waiting for k0s or calling the Kubernetes API
+	Do(description string, fn func() error) error
+}
+
+// FileOption configures how a file change is shown in a dry-run plan
+type FileOption func(*fileOptions)
+
+type fileOptions struct {
+	redact func([]byte) []byte
+}
+
+// RedactWith shows the file content through redact in dry-run plans, e.g.
+// to hide secrets. redact is applied to the current and the new content.
+func RedactWith(redact func([]byte) []byte) FileOption {
+	return func(o *fileOptions) {
+		o.redact = redact
+	}
+}
+
+// Redacted hides the whole file content in dry-run plans
+func Redacted() FileOption {
+	return RedactWith(func(data []byte) []byte {
+		if len(data) == 0 {
+			return nil
+		}
+		return []byte("<redacted>\n")
+	})
+}
+
+// CommandLine formats a command for display
+func CommandLine(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
+
+// localHost changes the node for real
+type localHost struct {
+	debug bool
+}
+
+// NewHost returns a Host changing the node. In debug mode commands output
+// is streamed to the terminal.
+func NewHost(debug bool) Host {
+	return &localHost{debug: debug}
+}
+
+// Run implements Host
+func (h *localHost) Run(name string, args ...string) error {
+	var stderrBuf bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderrBuf
+
+	if h.debug {
+		utils.GetLogger().Debugf("🔧 Executing: %s", CommandLine(name, args...))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w. stderr: %s", err, stderrBuf.String())
+	}
+	return nil
+}
+
+// WriteFile implements Host
+func (h *localHost) WriteFile(path string, data []byte, perm os.FileMode, _ ...FileOption) error {
+	return os.WriteFile(path, data, perm)
+}
+
+// CopyFile implements Host
+func (h *localHost) CopyFile(path string, src io.Reader, perm os.FileMode) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// MkdirAll implements Host
+func (h *localHost) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Remove implements Host
+func (h *localHost) Remove(path string, _ ...FileOption) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if h.debug {
+		utils.GetLogger().Debugf("🗑️  Removed %s", path)
+	}
+	return nil
+}
+
+// RemoveAll implements Host
+func (h *localHost) RemoveAll(path string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if h.debug {
+		utils.GetLogger().Debugf("🗑️  Removed %s", path)
+	}
+	return nil
+}
+
+// Do implements Host
+func (h *localHost) Do(_ string, fn func() error) error {
+	return fn()
+}
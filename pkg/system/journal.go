@@ -0,0 +1,225 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/belgaied2/k0rdentd/pkg/utils"
+)
+
+// Journal is a Host recording how to undo the changes made through it, so
+// that a failed run can be rolled back. Files are backed up before their
+// first change: files that existed are restored, new files are removed.
+type Journal struct {
+	host      Host
+	backupDir string
+	preserved map[string]bool
+	undo      []undoAction
+}
+
+// undoAction reverts one change, through the wrapped host
+type undoAction struct {
+	description string
+	fn          func(host Host) error
+}
+
+// NewJournal returns a Journal making the changes with host and keeping the
+// backups of changed files in backupDir
+func NewJournal(host Host, backupDir string) *Journal {
+	return &Journal{
+		host:      host,
+		backupDir: backupDir,
+		preserved: make(map[string]bool),
+	}
+}
+
+// BackupDir returns the directory of the backups
+func (j *Journal) BackupDir() string {
+	return j.backupDir
+}
+
+// Run implements Host. Commands are not undone, unless OnUndo registers how.
+func (j *Journal) Run(name string, args ...string) error {
+	return j.host.Run(name, args...)
+}
+
+// WriteFile implements Host
+func (j *Journal) WriteFile(path string, data []byte, perm os.FileMode, opts ...FileOption) error {
+	if err := j.Preserve(path); err != nil {
+		return err
+	}
+	return j.host.WriteFile(path, data, perm, opts...)
+}
+
+// CopyFile implements Host
+func (j *Journal) CopyFile(path string, src io.Reader, perm os.FileMode) error {
+	if err := j.Preserve(path); err != nil {
+		return err
+	}
+	return j.host.CopyFile(path, src, perm)
+}
+
+// MkdirAll implements Host, the directories it creates are removed on
+// rollback if they are empty
+func (j *Journal) MkdirAll(path string, perm os.FileMode) error {
+	var missing []string
+	for dir := filepath.Clean(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if err := j.host.MkdirAll(path, perm); err != nil {
+		return err
+	}
+
+	// Parents are registered first, so children are removed before them
+	for idx := len(missing) - 1; idx >= 0; idx-- {
+		dir := missing[idx]
+		j.OnUndo("remove directory "+dir, func(Host) error {
+			err := os.Remove(dir)
+			if err != nil && (os.IsNotExist(err) || errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST)) {
+				// Files not created by the run are kept
+				return nil
+			}
+			return err
+		})
+	}
+	return nil
+}
+
+// Remove implements Host
+func (j *Journal) Remove(path string, opts ...FileOption) error {
+	if err := j.Preserve(path); err != nil {
+		return err
+	}
+	return j.host.Remove(path, opts...)
+}
+
+// RemoveAll implements Host, the files of the directory are restored on
+// rollback
+func (j *Journal) RemoveAll(path string) error {
+	err := filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		return j.Preserve(file)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return j.host.RemoveAll(path)
+}
+
+// Do implements Host. Actions are not undone, unless OnUndo registers how.
+func (j *Journal) Do(description string, fn func() error) error {
+	return j.host.Do(description, fn)
+}
+
+// Preserve backs up path before it changes, for changes made outside of
+// the journal such as by an install script. Only the first call for a path
+// has an effect: rollback restores the content it had at that point.
+func (j *Journal) Preserve(path string) error {
+	path = filepath.Clean(path)
+	if j.preserved[path] {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		j.preserved[path] = true
+		j.OnUndo("remove "+path, func(host Host) error {
+			return host.Remove(path)
+		})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("failed to back up %s: is a directory", path)
+	}
+
+	backup := filepath.Join(j.backupDir, fmt.Sprintf("%03d-%s", len(j.preserved), filepath.Base(path)))
+	if err := copyFile(backup, path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	j.preserved[path] = true
+
+	perm := info.Mode().Perm()
+	j.OnUndo("restore "+path, func(host Host) error {
+		src, err := os.Open(backup)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		// The directory may have been removed with RemoveAll
+		if err := host.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := host.CopyFile(path, src, perm); err != nil {
+			return err
+		}
+		// The permissions of an existing file are kept by CopyFile
+		return os.Chmod(path, perm)
+	})
+	return nil
+}
+
+// OnUndo registers how to undo a change, for changes the journal cannot
+// see such as installed services
+func (j *Journal) OnUndo(description string, fn func(host Host) error) {
+	j.undo = append(j.undo, undoAction{description: description, fn: fn})
+}
+
+// Rollback undoes the recorded changes in reverse order. It goes on after
+// a failure to undo as much as possible, and returns all the failures.
+func (j *Journal) Rollback() error {
+	logger := utils.GetLogger()
+
+	var errs []error
+	for idx := len(j.undo) - 1; idx >= 0; idx-- {
+		action := j.undo[idx]
+		logger.Infof("↩️  Rollback: %s", action.description)
+		if err := action.fn(j.host); err != nil {
+			logger.Warnf("⚠️  Failed to %s: %v", action.description, err)
+			errs = append(errs, fmt.Errorf("failed to %s: %w", action.description, err))
+		}
+	}
+	j.undo = nil
+	return errors.Join(errs...)
+}
+
+// Discard forgets the recorded changes and removes the backups, once they
+// are no longer needed
+func (j *Journal) Discard() error {
+	j.undo = nil
+	j.preserved = make(map[string]bool)
+	return os.RemoveAll(j.backupDir)
+}
+
+// copyFile copies src to dst, creating the directory of dst
+func copyFile(dst, src string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
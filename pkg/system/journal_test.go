@@ -0,0 +1,78 @@
+package system
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestJournalRollback(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.yaml")
+	removed := filepath.Join(dir, "removed.yaml")
+	g.Expect(os.WriteFile(existing, []byte("before\n"), 0640)).To(gomega.Succeed())
+	g.Expect(os.WriteFile(removed, []byte("kept\n"), 0600)).To(gomega.Succeed())
+
+	journal := NewJournal(NewHost(false), filepath.Join(dir, "backups"))
+
+	newDir := filepath.Join(dir, "new", "nested")
+	created := filepath.Join(newDir, "created.yaml")
+	g.Expect(journal.MkdirAll(newDir, 0755)).To(gomega.Succeed())
+	g.Expect(journal.WriteFile(created, []byte("new\n"), 0600)).To(gomega.Succeed())
+	g.Expect(journal.WriteFile(existing, []byte("first\n"), 0600)).To(gomega.Succeed())
+	// Rollback restores the content from before the first change
+	g.Expect(journal.WriteFile(existing, []byte("second\n"), 0600)).To(gomega.Succeed())
+	g.Expect(journal.Remove(removed)).To(gomega.Succeed())
+
+	var undone []string
+	journal.OnUndo("first", func(Host) error {
+		undone = append(undone, "first")
+		return nil
+	})
+	journal.OnUndo("second", func(Host) error {
+		undone = append(undone, "second")
+		return errors.New("boom")
+	})
+
+	err := journal.Rollback()
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to second: boom")))
+	// Undo actions run in reverse order, after a failure too
+	g.Expect(undone).To(gomega.Equal([]string{"second", "first"}))
+
+	data, err := os.ReadFile(existing)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.Equal("before\n"))
+	info, err := os.Stat(existing)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(gomega.Equal(os.FileMode(0640)))
+
+	data, err = os.ReadFile(removed)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.Equal("kept\n"))
+
+	g.Expect(filepath.Join(dir, "new")).ToNot(gomega.BeAnExistingFile())
+
+	g.Expect(journal.Discard()).To(gomega.Succeed())
+	g.Expect(filepath.Join(dir, "backups")).ToNot(gomega.BeAnExistingFile())
+}
+
+func TestJournalKeepsForeignFiles(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	journal := NewJournal(NewHost(false), filepath.Join(dir, "backups"))
+
+	newDir := filepath.Join(dir, "new")
+	g.Expect(journal.MkdirAll(newDir, 0755)).To(gomega.Succeed())
+	// Written by another process, e.g. k0s itself
+	foreign := filepath.Join(newDir, "foreign")
+	g.Expect(os.WriteFile(foreign, []byte("x"), 0600)).To(gomega.Succeed())
+
+	g.Expect(journal.Rollback()).To(gomega.Succeed())
+	g.Expect(foreign).To(gomega.BeAnExistingFile())
+}
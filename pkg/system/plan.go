@@ -0,0 +1,288 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ActionType is the kind of change recorded in a plan
+type ActionType string
+
+const (
+	// ActionCommand runs a command
+	ActionCommand ActionType = "command"
+	// ActionMkdir creates a directory
+	ActionMkdir ActionType = "mkdir"
+	// ActionWrite writes a file
+	ActionWrite ActionType = "write"
+	// ActionRemove removes a file
+	ActionRemove ActionType = "remove"
+	// ActionDo performs another action, such as a wait or an API call
+	ActionDo ActionType = "action"
+)
+
+// Action is a change of the node recorded by a dry run
+type Action struct {
+	Type ActionType `json:"type"`
+	// Command is the command and its arguments, for commands
+	Command []string `json:"command,omitempty"`
+	// Path is the changed file or directory
+	Path string `json:"path,omitempty"`
+	// Mode is the permission of written files, e.g. 0600
+	Mode string `json:"mode,omitempty"`
+	// Diff is the unified diff of a written or removed text file
+	Diff string `json:"diff,omitempty"`
+	// Unchanged is set when a write keeps the current content
+	Unchanged bool `json:"unchanged,omitempty"`
+	// Description describes other actions and binary writes
+	Description string `json:"description,omitempty"`
+}
+
+// Plan is a Host recording the changes a run would make, without making
+// them. Files written earlier in the plan are the base of later diffs.
+type Plan struct {
+	Actions []Action `json:"actions"`
+
+	// files are the planned contents, nil for removed files
+	files map[string][]byte
+	dirs  map[string]bool
+	// removedDirs are the directories removed with their content
+	removedDirs map[string]bool
+}
+
+// NewPlan returns an empty plan
+func NewPlan() *Plan {
+	return &Plan{
+		Actions:     []Action{},
+		files:       make(map[string][]byte),
+		dirs:        make(map[string]bool),
+		removedDirs: make(map[string]bool),
+	}
+}
+
+// IsDryRun tells whether host only records its changes in a plan
+func IsDryRun(host Host) bool {
+	_, ok := host.(*Plan)
+	return ok
+}
+
+// Run implements Host
+func (p *Plan) Run(name string, args ...string) error {
+	p.Actions = append(p.Actions, Action{
+		Type:    ActionCommand,
+		Command: append([]string{name}, args...),
+	})
+	return nil
+}
+
+// unreadableDescription describes the changes of files the dry run can't
+// read, e.g. /etc/k0s/k0s.yaml when not run as root
+const unreadableDescription = "current content unreadable (run as root for a diff)"
+
+// WriteFile implements Host
+func (p *Plan) WriteFile(path string, data []byte, perm os.FileMode, opts ...FileOption) error {
+	current, exists, err := p.current(path)
+	p.files[path] = data
+	if err != nil {
+		p.Actions = append(p.Actions, Action{
+			Type:        ActionWrite,
+			Path:        path,
+			Mode:        fmt.Sprintf("%04o", perm.Perm()),
+			Description: unreadableDescription,
+		})
+		return nil
+	}
+
+	options := applyFileOptions(opts)
+	diff := unifiedDiff(path, options.redact(current), options.redact(data), exists, true)
+	p.Actions = append(p.Actions, Action{
+		Type:      ActionWrite,
+		Path:      path,
+		Mode:      fmt.Sprintf("%04o", perm.Perm()),
+		Diff:      diff,
+		Unchanged: exists && diff == "",
+	})
+	return nil
+}
+
+// CopyFile implements Host
+func (p *Plan) CopyFile(path string, _ io.Reader, perm os.FileMode) error {
+	// The content isn't read, large files would be loaded for nothing
+	p.files[path] = []byte{}
+	p.Actions = append(p.Actions, Action{
+		Type:        ActionWrite,
+		Path:        path,
+		Mode:        fmt.Sprintf("%04o", perm.Perm()),
+		Description: "binary content not shown",
+	})
+	return nil
+}
+
+// MkdirAll implements Host
+func (p *Plan) MkdirAll(path string, _ os.FileMode) error {
+	path = filepath.Clean(path)
+	if p.dirs[path] {
+		return nil
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	p.dirs[path] = true
+	p.Actions = append(p.Actions, Action{Type: ActionMkdir, Path: path})
+	return nil
+}
+
+// Remove implements Host
+func (p *Plan) Remove(path string, opts ...FileOption) error {
+	current, exists, err := p.current(path)
+	if err != nil {
+		p.files[path] = nil
+		p.Actions = append(p.Actions, Action{Type: ActionRemove, Path: path, Description: unreadableDescription})
+		return nil
+	}
+	if !exists {
+		return nil
+	}
+	p.files[path] = nil
+
+	options := applyFileOptions(opts)
+	p.Actions = append(p.Actions, Action{
+		Type: ActionRemove,
+		Path: path,
+		Diff: unifiedDiff(path, options.redact(current), nil, true, false),
+	})
+	return nil
+}
+
+// RemoveAll implements Host
+func (p *Plan) RemoveAll(path string) error {
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); err != nil || p.removedDirs[path] {
+		return nil
+	}
+	p.removedDirs[path] = true
+	p.Actions = append(p.Actions, Action{
+		Type:        ActionRemove,
+		Path:        path,
+		Description: "with its content",
+	})
+	return nil
+}
+
+// Do implements Host, fn is not called
+func (p *Plan) Do(description string, _ func() error) error {
+	p.Actions = append(p.Actions, Action{Type: ActionDo, Description: description})
+	return nil
+}
+
+// WriteText writes the plan in a human readable form: commands prefixed
+// with $, file changes followed by their diff
+func (p *Plan) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	for _, action := range p.Actions {
+		switch action.Type {
+		case ActionCommand:
+			fmt.Fprintf(&sb, "$ %s\n", strings.Join(action.Command, " "))
+		case ActionMkdir:
+			fmt.Fprintf(&sb, "+ mkdir %s\n", action.Path)
+		case ActionWrite:
+			fmt.Fprintf(&sb, "~ write %s (%s)", action.Path, action.Mode)
+			switch {
+			case action.Unchanged:
+				sb.WriteString(" unchanged")
+			case action.Description != "":
+				fmt.Fprintf(&sb, " %s", action.Description)
+			}
+			sb.WriteString("\n")
+			sb.WriteString(action.Diff)
+		case ActionRemove:
+			fmt.Fprintf(&sb, "- remove %s", action.Path)
+			if action.Description != "" {
+				fmt.Fprintf(&sb, " %s", action.Description)
+			}
+			sb.WriteString("\n")
+			sb.WriteString(action.Diff)
+		case ActionDo:
+			fmt.Fprintf(&sb, "• %s\n", action.Description)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the plan as JSON, for automation
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// current returns the content path would have at this point of the plan,
+// and whether it exists. It fails when path exists but can't be read.
+func (p *Plan) current(path string) ([]byte, bool, error) {
+	if data, ok := p.files[path]; ok {
+		return data, data != nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// applyFileOptions returns the options of a file change, by default the
+// content is shown as is
+func applyFileOptions(opts []FileOption) fileOptions {
+	options := fileOptions{redact: func(data []byte) []byte { return data }}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// unifiedDiff returns the unified diff between the content of path before and
+// after the change. Missing files are shown as /dev/null.
+func unifiedDiff(path string, before, after []byte, beforeExists, afterExists bool) string {
+	fromFile, toFile := path, path
+	if !beforeExists {
+		fromFile = "/dev/null"
+	}
+	if !afterExists {
+		toFile = "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// splitLines splits content into lines, each ending with a newline
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	content := string(data)
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	// The content ends with a newline, the last element is empty
+	lines := strings.SplitAfter(content, "\n")
+	return lines[:len(lines)-1]
+}
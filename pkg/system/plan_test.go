@@ -0,0 +1,120 @@
+package system
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestPlanRecordsChanges(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.yaml")
+	g.Expect(os.WriteFile(existing, []byte("a: 1\nb: 2\n"), 0600)).To(gomega.Succeed())
+	created := filepath.Join(dir, "new", "created.yaml")
+
+	plan := NewPlan()
+	g.Expect(plan.Run("k0s", "install", "controller")).To(gomega.Succeed())
+	g.Expect(plan.MkdirAll(dir, 0755)).To(gomega.Succeed())
+	g.Expect(plan.MkdirAll(filepath.Dir(created), 0755)).To(gomega.Succeed())
+	g.Expect(plan.WriteFile(created, []byte("c: 3\n"), 0644)).To(gomega.Succeed())
+	g.Expect(plan.WriteFile(existing, []byte("a: 1\nb: 3\n"), 0600)).To(gomega.Succeed())
+	g.Expect(plan.WriteFile(existing, []byte("a: 1\nb: 3\n"), 0600)).To(gomega.Succeed())
+	g.Expect(plan.Remove(filepath.Join(dir, "missing"))).To(gomega.Succeed())
+	g.Expect(plan.Remove(created)).To(gomega.Succeed())
+	called := false
+	g.Expect(plan.Do("Wait for k0s", func() error {
+		called = true
+		return errors.New("not called")
+	})).To(gomega.Succeed())
+	g.Expect(called).To(gomega.BeFalse())
+
+	// Existing directories and missing files aren't changes
+	g.Expect(plan.Actions).To(gomega.HaveLen(7))
+	g.Expect(plan.Actions[0].Command).To(gomega.Equal([]string{"k0s", "install", "controller"}))
+	g.Expect(plan.Actions[1]).To(gomega.Equal(Action{Type: ActionMkdir, Path: filepath.Dir(created)}))
+
+	g.Expect(plan.Actions[2].Mode).To(gomega.Equal("0644"))
+	g.Expect(plan.Actions[2].Diff).To(gomega.ContainSubstring("--- /dev/null\n+++ " + created))
+	g.Expect(plan.Actions[2].Diff).To(gomega.ContainSubstring("+c: 3\n"))
+
+	g.Expect(plan.Actions[3].Diff).To(gomega.ContainSubstring("-b: 2\n+b: 3\n"))
+	g.Expect(plan.Actions[4].Unchanged).To(gomega.BeTrue())
+
+	// Removing a planned file diffs against the planned content
+	g.Expect(plan.Actions[5].Type).To(gomega.Equal(ActionRemove))
+	g.Expect(plan.Actions[5].Diff).To(gomega.ContainSubstring("+++ /dev/null"))
+	g.Expect(plan.Actions[5].Diff).To(gomega.ContainSubstring("-c: 3\n"))
+
+	// Nothing changed on disk
+	content, err := os.ReadFile(existing)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.Equal("a: 1\nb: 2\n"))
+	g.Expect(filepath.Dir(created)).ToNot(gomega.BeADirectory())
+
+	var text bytes.Buffer
+	g.Expect(plan.WriteText(&text)).To(gomega.Succeed())
+	g.Expect(text.String()).To(gomega.HavePrefix("$ k0s install controller\n+ mkdir " + filepath.Dir(created) + "\n"))
+	g.Expect(text.String()).To(gomega.ContainSubstring("~ write " + existing + " (0600) unchanged\n"))
+	g.Expect(text.String()).To(gomega.HaveSuffix("• Wait for k0s\n"))
+
+	var decoded Plan
+	var js bytes.Buffer
+	g.Expect(plan.WriteJSON(&js)).To(gomega.Succeed())
+	g.Expect(json.Unmarshal(js.Bytes(), &decoded)).To(gomega.Succeed())
+	g.Expect(decoded.Actions).To(gomega.Equal(plan.Actions))
+}
+
+func TestPlanRedaction(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "join-token")
+	plan := NewPlan()
+	g.Expect(plan.WriteFile(path, []byte("secret-token"), 0600, Redacted())).To(gomega.Succeed())
+	g.Expect(plan.Remove(path, Redacted())).To(gomega.Succeed())
+
+	upper := RedactWith(func(data []byte) []byte { return bytes.ToUpper(data) })
+	g.Expect(plan.WriteFile(path, []byte("token: abc\n"), 0600, upper)).To(gomega.Succeed())
+
+	for _, action := range plan.Actions {
+		g.Expect(action.Diff).ToNot(gomega.ContainSubstring("secret-token"))
+	}
+	g.Expect(plan.Actions[0].Diff).To(gomega.ContainSubstring("+<redacted>\n"))
+	g.Expect(plan.Actions[1].Diff).To(gomega.ContainSubstring("-<redacted>\n"))
+	g.Expect(strings.Contains(plan.Actions[2].Diff, "+TOKEN: ABC\n")).To(gomega.BeTrue())
+}
+
+func TestIsDryRun(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(IsDryRun(NewPlan())).To(gomega.BeTrue())
+	g.Expect(IsDryRun(NewHost(false))).To(gomega.BeFalse())
+}
+
+func TestPlanUnreadableFile(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// Reading a directory fails like reading a root-only file without root
+	path := t.TempDir()
+	plan := NewPlan()
+	g.Expect(plan.WriteFile(path, []byte("a: 1\n"), 0600)).To(gomega.Succeed())
+	plan.files = make(map[string][]byte)
+	g.Expect(plan.Remove(path)).To(gomega.Succeed())
+
+	g.Expect(plan.Actions).To(gomega.Equal([]Action{
+		{Type: ActionWrite, Path: path, Mode: "0600", Description: unreadableDescription},
+		{Type: ActionRemove, Path: path, Description: unreadableDescription},
+	}))
+
+	var text bytes.Buffer
+	g.Expect(plan.WriteText(&text)).To(gomega.Succeed())
+	g.Expect(text.String()).ToNot(gomega.ContainSubstring("/dev/null"))
+	g.Expect(text.String()).To(gomega.ContainSubstring("~ write " + path + " (0600) current content unreadable (run as root for a diff)\n"))
+}
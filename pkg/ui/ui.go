@@ -27,6 +27,10 @@ const (
 	k0rdentUIIngressName = "k0rdent-ui"
 	// k0rdentUIIngressPath is the path where k0rdent UI will be accessible
 	k0rdentUIIngressPath = "/k0rdent-ui"
+	// k0rdentUIUsername is the default Basic Auth user of k0rdent UI
+	k0rdentUIUsername = "admin"
+	// PasswordSource tells where k0rdent UI reads its Basic Auth password
+	PasswordSource = "env BASIC_AUTH_PASSWORD of deployment " + k0rdentUINamespace + "/" + k0rdentUIDeploymentName
 )
 
 // getK8sClient creates a Kubernetes client from k0s kubeconfig
@@ -140,7 +144,7 @@ func buildIngressObject(ips []string) *networkingv1.Ingress {
 
 // TestUIAccess tests if k0rdent UI is accessible on the given IP
 func TestUIAccess(ip string) bool {
-	url := fmt.Sprintf("http://%s%s", ip, k0rdentUIIngressPath)
+	url := network.URL("http", ip, 0, k0rdentUIIngressPath)
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
@@ -163,8 +167,23 @@ func TestUIAccess(ip string) bool {
 	return false
 }
 
+// Access tells how to reach the exposed k0rdent UI
+type Access struct {
+	// URL goes through the ingress on the primary IP
+	URL         string
+	NodePortURL string
+	Username    string
+}
+
 // ExposeUI exposes k0rdent UI by creating an ingress and printing access URLs
 func ExposeUI() error {
+	_, err := Expose()
+	return err
+}
+
+// Expose works like ExposeUI and also returns how to reach the UI, nil if
+// no IP address was found
+func Expose() (*Access, error) {
 	utils.GetLogger().Info("Checking k0rdent UI deployment status...")
 
 	// Wait for deployment to be ready with timeout (default 5 minutes)
@@ -175,7 +194,7 @@ func ExposeUI() error {
 	for {
 		ready, err := DeploymentReady()
 		if err != nil {
-			return fmt.Errorf("failed to check k0rdent UI deployment readiness: %w", err)
+			return nil, fmt.Errorf("failed to check k0rdent UI deployment readiness: %w", err)
 		}
 		if ready {
 			utils.GetLogger().Info("k0rdent UI deployment is ready")
@@ -183,7 +202,7 @@ func ExposeUI() error {
 		}
 
 		if time.Since(startTime) > timeout {
-			return fmt.Errorf("timeout waiting for k0rdent UI deployment to be ready after %v", timeout)
+			return nil, fmt.Errorf("timeout waiting for k0rdent UI deployment to be ready after %v", timeout)
 		}
 
 		utils.GetLogger().Debug("k0rdent UI deployment not ready yet, retrying...")
@@ -193,10 +212,10 @@ func ExposeUI() error {
 	// Check if service exists
 	exists, err := ServiceExists()
 	if err != nil {
-		return fmt.Errorf("failed to check k0rdent UI service existence: %w", err)
+		return nil, fmt.Errorf("failed to check k0rdent UI service existence: %w", err)
 	}
 	if !exists {
-		return fmt.Errorf("k0rdent UI service not found")
+		return nil, fmt.Errorf("k0rdent UI service not found")
 	}
 
 	utils.GetLogger().Info("k0rdent UI deployment and service are ready")
@@ -254,20 +273,27 @@ func ExposeUI() error {
 		utils.GetLogger().Info("   You can use the following command to port-forward to k0rdent UI:")
 		utils.GetLogger().Info("   k0s kubectl port-forward -n kcm-system svc/k0rdent-k0rdent-ui 8080:80")
 		utils.GetLogger().Info("   Then access at: http://localhost:8080/k0rdent-ui")
-		return nil
+		return nil, nil
 	}
 
 	// Create ingress
 	if err := CreateIngress(uniqueIPs); err != nil {
-		return fmt.Errorf("failed to create ingress: %w", err)
+		return nil, fmt.Errorf("failed to create ingress: %w", err)
 	}
 
 	// Test UI access on primary IP
 	primaryIP := uniqueIPs[0]
+	access := &Access{
+		URL:      network.URL("http", primaryIP, 0, k0rdentUIIngressPath),
+		Username: k0rdentUIUsername,
+	}
+	if nodePort > 0 {
+		access.NodePortURL = network.URL("http", primaryIP, int(nodePort), "")
+	}
 	if TestUIAccess(primaryIP) {
-		utils.GetLogger().Infof("\n✅ Successfully tested k0rdent UI access on http://%s%s", primaryIP, k0rdentUIIngressPath)
+		utils.GetLogger().Infof("\n✅ Successfully tested k0rdent UI access on %s", network.URL("http", primaryIP, 0, k0rdentUIIngressPath))
 	} else {
-		utils.GetLogger().Infof("\n⚠️  Warning: Could not access k0rdent UI on http://%s%s", primaryIP, k0rdentUIIngressPath)
+		utils.GetLogger().Infof("\n⚠️  Warning: Could not access k0rdent UI on %s", network.URL("http", primaryIP, 0, k0rdentUIIngressPath))
 		utils.GetLogger().Infof("   The ingress has been created, but the UI is not accessible,")
 		utils.GetLogger().Infof("   this usually means that no Ingress Controller is installed. Try out the NodePort Address")
 	}
@@ -275,7 +301,7 @@ func ExposeUI() error {
 	// Print all possible access URLs
 	utils.GetLogger().Info("\n🌐 If an Ingress Controller is installed, K0rdent UI is accessible at:")
 	for _, ip := range uniqueIPs {
-		url := fmt.Sprintf("http://%s%s", ip, k0rdentUIIngressPath)
+		url := network.URL("http", ip, 0, k0rdentUIIngressPath)
 		utils.GetLogger().Infof("   %s", url)
 	}
 
@@ -283,7 +309,7 @@ func ExposeUI() error {
 	if nodePort > 0 {
 		utils.GetLogger().Info("\n🔌 NodePort access (requires firewall rules):")
 		for _, ip := range uniqueIPs {
-			url := fmt.Sprintf("http://%s:%d", ip, nodePort)
+			url := network.URL("http", ip, int(nodePort), "")
 			utils.GetLogger().Infof("   %s", url)
 		}
 		utils.GetLogger().Info("\n⚠️  Note: Firewall rules may need to be configured to allow access to the NodePort")
@@ -300,14 +326,12 @@ func ExposeUI() error {
 		utils.GetLogger().Warnf("Failed to get Basic Auth password: %v", err)
 		utils.GetLogger().Info("\n🔐 Basic Auth credentials: Not available")
 	} else {
-		// Default username is typically "admin" for k0rdent
-		username := "admin"
 		utils.GetLogger().Infof("\n🔐 Basic Auth credentials:")
-		utils.GetLogger().Infof("   Username: %s", username)
+		utils.GetLogger().Infof("   Username: %s", k0rdentUIUsername)
 		utils.GetLogger().Infof("   Password: %s", password)
 	}
 
-	return nil
+	return access, nil
 }
 
 // removeDuplicateIPs removes duplicate IPs from a slice
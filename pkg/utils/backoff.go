@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// Delays between the checks of PollWithBackoff, variables for tests
+var (
+	pollInitialDelay = time.Second
+	pollMaxDelay     = 30 * time.Second
+)
+
+// PollWithBackoff calls check until it returns true or ctx is done, waiting
+// twice as long after each call, from 1s up to 30s. Errors of check are
+// logged and retried, ctx's error is returned when it is done.
+func PollWithBackoff(ctx context.Context, check func() (bool, error)) error {
+	delay := pollInitialDelay
+	for {
+		done, err := check()
+		if err != nil {
+			GetLogger().Debugf("Check failed, retrying in %v: %v", delay, err)
+		} else if done {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, pollMaxDelay)
+	}
+}
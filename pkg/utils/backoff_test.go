@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestPollWithBackoff(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	initial, maxDelay := pollInitialDelay, pollMaxDelay
+	pollInitialDelay, pollMaxDelay = time.Millisecond, 4*time.Millisecond
+	defer func() { pollInitialDelay, pollMaxDelay = initial, maxDelay }()
+
+	t.Run("should retry failed checks until done", func(t *testing.T) {
+		calls := 0
+		err := PollWithBackoff(context.Background(), func() (bool, error) {
+			calls++
+			if calls == 1 {
+				return false, errors.New("unavailable")
+			}
+			return calls == 5, nil
+		})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(calls).To(gomega.Equal(5))
+	})
+
+	t.Run("should return the error of ctx when it is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := PollWithBackoff(ctx, func() (bool, error) {
+			return false, nil
+		})
+		g.Expect(err).To(gomega.MatchError(context.DeadlineExceeded))
+	})
+}
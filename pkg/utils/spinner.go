@@ -2,23 +2,49 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"time"
 )
 
 var (
 	spinner       = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	tickerSpinner = time.NewTicker(time.Second / 10)
+	// spinnerEnabled is false when stdout isn't a terminal, e.g. when run by
+	// automation, the animation would only clutter the output
+	spinnerEnabled = isTerminal(os.Stdout)
 )
 
+// DisableSpinner replaces the spinner animation with log lines, e.g. when
+// stdout carries JSON events
+func DisableSpinner() {
+	spinnerEnabled = false
+}
+
 // RunWithSpinner runs a command with a spinner animation until it completes
-// stop: channel to signal the spinner to stop
+// stop: channel to signal the spinner to stop, with true when the command
+// succeeded and false when it failed
 // finished: channel that will be closed when the spinner has finished cleaning up
 func RunWithSpinner(message string, stop chan bool, finished chan bool) {
+	if !spinnerEnabled {
+		GetLogger().Infof("⏳ %s...", message)
+		if <-stop {
+			GetLogger().Infof("✅ %s: DONE.", message)
+		} else {
+			GetLogger().Errorf("❌ %s: FAILED.", message)
+		}
+		close(finished)
+		return
+	}
+
 	tickerCounter := 0
 	for {
 		select {
-		case <-stop:
-			fmt.Println("\r✅", message, ": DONE.")
+		case succeeded := <-stop:
+			if succeeded {
+				fmt.Println("\r✅", message, ": DONE.")
+			} else {
+				fmt.Println("\r❌", message, ": FAILED.")
+			}
 			close(finished)
 			return
 		case <-tickerSpinner.C:
@@ -27,3 +53,9 @@ func RunWithSpinner(message string, stop chan bool, finished chan bool) {
 		}
 	}
 }
+
+// isTerminal tells whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
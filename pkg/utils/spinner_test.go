@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestRunWithSpinner(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	enabled := spinnerEnabled
+	DisableSpinner()
+	defer func() { spinnerEnabled = enabled }()
+
+	var out bytes.Buffer
+	logger := GetLogger()
+	output := logger.Out
+	logger.SetOutput(&out)
+	defer logger.SetOutput(output)
+
+	tests := []struct {
+		name      string
+		succeeded bool
+		expected  string
+	}{
+		{name: "should log DONE when the command succeeded", succeeded: true, expected: "✅ Waiting: DONE."},
+		{name: "should log FAILED when the command failed", succeeded: false, expected: "❌ Waiting: FAILED."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			stop := make(chan bool)
+			finished := make(chan bool)
+			go RunWithSpinner("Waiting", stop, finished)
+			stop <- tt.succeeded
+			<-finished
+			g.Expect(out.String()).To(gomega.ContainSubstring(tt.expected))
+			if !tt.succeeded {
+				g.Expect(out.String()).NotTo(gomega.ContainSubstring("DONE"))
+			}
+		})
+	}
+}